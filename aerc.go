@@ -18,6 +18,7 @@ import (
 	"git.sr.ht/~rjarry/aerc/commands"
 	"git.sr.ht/~rjarry/aerc/commands/account"
 	"git.sr.ht/~rjarry/aerc/commands/compose"
+	"git.sr.ht/~rjarry/aerc/commands/layout"
 	"git.sr.ht/~rjarry/aerc/commands/msg"
 	"git.sr.ht/~rjarry/aerc/commands/msgview"
 	"git.sr.ht/~rjarry/aerc/commands/terminal"
@@ -39,6 +40,13 @@ func getCommands(selected libui.Drawable) []*commands.Commands {
 			msg.MessageCommands,
 			commands.GlobalCommands,
 		}
+	case *widgets.Layout:
+		return []*commands.Commands{
+			layout.LayoutCommands,
+			account.AccountCommands,
+			msg.MessageCommands,
+			commands.GlobalCommands,
+		}
 	case *widgets.Composer:
 		return []*commands.Commands{
 			compose.ComposeCommands,
@@ -60,6 +68,18 @@ func getCommands(selected libui.Drawable) []*commands.Commands {
 	}
 }
 
+func allCommands() []*commands.Commands {
+	return []*commands.Commands{
+		account.AccountCommands,
+		compose.ComposeCommands,
+		layout.LayoutCommands,
+		msg.MessageCommands,
+		msgview.MessageViewCommands,
+		terminal.TerminalCommands,
+		commands.GlobalCommands,
+	}
+}
+
 func execCommand(aerc *widgets.Aerc, ui *libui.UI, cmd []string) error {
 	cmds := getCommands(aerc.SelectedTabContent())
 	for i, set := range cmds {
@@ -172,6 +192,11 @@ func main() {
 		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
 		os.Exit(1) //nolint:gocritic // PanicHandler does not need to run as it's not a panic
 	}
+	err = commands.RegisterAliases(config.Aliases, allCommands())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		os.Exit(1) //nolint:gocritic // PanicHandler does not need to run as it's not a panic
+	}
 
 	log.Infof("Starting up version %s", log.BuildInfo)
 
@@ -193,7 +218,13 @@ func main() {
 		return execCommand(aerc, ui, cmd)
 	}, func(cmd string) []string {
 		return getCompletions(aerc, cmd)
-	}, &commands.CmdHistory, deferLoop)
+	}, commands.CmdHistory.For, deferLoop)
+
+	err = commands.LoadScripts(aerc, allCommands())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load scripts: %v\n", err)
+		os.Exit(1) //nolint:gocritic // PanicHandler does not need to run as it's not a panic
+	}
 
 	ui, err = libui.Initialize(aerc)
 	if err != nil {
@@ -221,6 +252,9 @@ func main() {
 	// set the aerc version so that we can use it in the template funcs
 	templates.SetVersion(Version)
 
+	aerc.InitSession()
+	aerc.InitRecovery()
+
 	if retryExec {
 		// retry execution
 		arg := args[0]
@@ -254,6 +288,12 @@ func main() {
 		}
 		ui.Render()
 	}
+	if config.General.RestoreSession {
+		err = aerc.CloseSession(widgets.SessionFile())
+		if err != nil {
+			log.Errorf("failed to save session: %v", err)
+		}
+	}
 	err = aerc.CloseBackends()
 	if err != nil {
 		log.Warnf("failed to close backends: %v", err)
@@ -3,13 +3,13 @@ package account
 import (
 	"errors"
 	"fmt"
-	"os"
 	"path/filepath"
 	"sync"
 	"time"
 
 	"git.sr.ht/~rjarry/aerc/log"
 	"git.sr.ht/~rjarry/aerc/widgets"
+	wlib "git.sr.ht/~rjarry/aerc/worker/lib"
 	mboxer "git.sr.ht/~rjarry/aerc/worker/mbox"
 	"git.sr.ht/~rjarry/aerc/worker/types"
 )
@@ -54,13 +54,12 @@ func (ExportMbox) Execute(aerc *widgets.Aerc, args []string) error {
 
 	go func() {
 		defer log.PanicHandler()
-		file, err := os.Create(filename)
+		file, err := wlib.CreateFile(filename)
 		if err != nil {
 			log.Errorf("failed to create file: %v", err)
 			aerc.PushError(err.Error())
 			return
 		}
-		defer file.Close()
 
 		var mu sync.Mutex
 		var ctr uint32
@@ -86,7 +85,8 @@ func (ExportMbox) Execute(aerc *widgets.Aerc, args []string) error {
 
 			log.Debugf("fetching %d for export", len(uids))
 			acct.Worker().PostAction(&types.FetchFullMessages{
-				Uids: uids,
+				Uids:       uids,
+				Background: true,
 			}, func(msg types.WorkerMessage) {
 				switch msg := msg.(type) {
 				case *types.Done:
@@ -116,6 +116,12 @@ func (ExportMbox) Execute(aerc *widgets.Aerc, args []string) error {
 			}
 			retries++
 		}
+		// flush compressed data
+		if err := file.Close(); err != nil {
+			log.Errorf("failed to close file: %v", err)
+			aerc.PushError(err.Error())
+			return
+		}
 		statusInfo := fmt.Sprintf("Exported %d of %d messages to %s.", ctr, len(store.Uids()), filename)
 		aerc.PushStatus(statusInfo, 10*time.Second)
 		log.Debugf(statusInfo)
@@ -5,7 +5,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"os"
 	"path/filepath"
 	"sync/atomic"
 	"time"
@@ -14,6 +13,7 @@ import (
 	"git.sr.ht/~rjarry/aerc/log"
 	"git.sr.ht/~rjarry/aerc/models"
 	"git.sr.ht/~rjarry/aerc/widgets"
+	wlib "git.sr.ht/~rjarry/aerc/worker/lib"
 	mboxer "git.sr.ht/~rjarry/aerc/worker/mbox"
 	"git.sr.ht/~rjarry/aerc/worker/types"
 )
@@ -57,7 +57,7 @@ func (ImportMbox) Execute(aerc *widgets.Aerc, args []string) error {
 		statusInfo := fmt.Sprintln("Importing", filename, "to folder", folder)
 		aerc.PushStatus(statusInfo, 10*time.Second)
 		log.Debugf(statusInfo)
-		f, err := os.Open(filename)
+		f, err := wlib.OpenFile(filename)
 		if err != nil {
 			aerc.PushError(err.Error())
 			return
@@ -57,6 +57,9 @@ func (Recover) Complete(aerc *widgets.Aerc, args []string) []string {
 }
 
 func (Recover) Execute(aerc *widgets.Aerc, args []string) error {
+	if len(args) == 1 {
+		return aerc.RecoverDrafts()
+	}
 	// Complete() expects to be passed only the arguments, not including the command name
 	if len(Recover{}.Complete(aerc, args[1:])) == 0 {
 		return errors.New("No messages to recover.")
@@ -75,7 +78,7 @@ func (Recover) Execute(aerc *widgets.Aerc, args []string) error {
 	}
 
 	if len(args) <= optind {
-		return errors.New("Usage: recover [-f] <file>")
+		return errors.New("Usage: recover [[-f] <file>]")
 	}
 
 	acct := aerc.SelectedAccount()
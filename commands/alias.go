@@ -0,0 +1,206 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"git.sr.ht/~rjarry/aerc/config"
+	"git.sr.ht/~rjarry/aerc/lib/parse"
+	"git.sr.ht/~rjarry/aerc/lib/templates"
+	"git.sr.ht/~rjarry/aerc/widgets"
+)
+
+// maximum number of nested alias invocations, to avoid infinite recursion
+// when an alias refers to itself
+const aliasMaxDepth = 16
+
+var aliasDepth int
+
+// Alias is a user defined command made of a sequence of other commands.
+type Alias struct {
+	name string
+	def  string
+	cmds parse.CommandChain
+}
+
+var paramRe = regexp.MustCompile(`\$(\d+|@|\$)`)
+
+func NewAlias(name, def string) (*Alias, error) {
+	chain, err := parse.CommandLine(def)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("%s: empty command", name)
+	}
+	params := false
+	for _, cmd := range chain {
+		for _, arg := range cmd.Args {
+			if hasParam(arg) {
+				params = true
+			}
+			if _, err := templates.ParseTemplate(name, arg); err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+	if !params {
+		// behave like a shell alias and pass any extra arguments to
+		// the last command
+		last := chain[len(chain)-1]
+		last.Args = append(last.Args, "$@")
+	}
+	return &Alias{name: name, def: def, cmds: chain}, nil
+}
+
+// RegisterAliases adds the user defined aliases to the global commands. The
+// alias names must not shadow any of the given built-in command sets.
+func RegisterAliases(aliases []*config.AliasConfig, builtins []*Commands) error {
+	for _, a := range aliases {
+		for _, cmds := range builtins {
+			if cmds.ByName(a.Name) != nil {
+				return fmt.Errorf("alias %q conflicts with built-in command",
+					a.Name)
+			}
+		}
+		alias, err := NewAlias(a.Name, a.Command)
+		if err != nil {
+			return err
+		}
+		register(alias)
+	}
+	return nil
+}
+
+// AliasDefinitions returns a human readable list of all user defined aliases.
+func AliasDefinitions() []string {
+	var defs []string
+	for _, name := range GlobalCommands.Names() {
+		if alias, ok := GlobalCommands.ByName(name).(*Alias); ok {
+			defs = append(defs, fmt.Sprintf("%s = %s", alias.name, alias.def))
+		}
+	}
+	return defs
+}
+
+func (a *Alias) Aliases() []string {
+	return []string{a.name}
+}
+
+func (a *Alias) Complete(aerc *widgets.Aerc, args []string) []string {
+	// Complete using the first command which refers to the positional
+	// parameters. Only the arguments preceding the first parameter are
+	// kept as a prefix.
+	var prefix []string
+	for _, cmd := range a.cmds {
+		for i, arg := range cmd.Args {
+			if hasParam(arg) {
+				prefix = cmd.Args[:i]
+				break
+			}
+		}
+		if prefix != nil {
+			break
+		}
+	}
+	if len(prefix) == 0 {
+		return nil
+	}
+	head := strings.Join(prefix, " ") + " "
+	var completions []string
+	for _, c := range aerc.CompleteCommand(head + strings.Join(args, " ")) {
+		if strings.HasPrefix(c, head) {
+			completions = append(completions, strings.TrimPrefix(c, head))
+		}
+	}
+	return completions
+}
+
+func (a *Alias) Execute(aerc *widgets.Aerc, args []string) error {
+	if aliasDepth >= aliasMaxDepth {
+		return errors.New("alias recursion limit reached")
+	}
+	aliasDepth++
+	defer func() { aliasDepth-- }()
+
+	data := aliasTemplateData(aerc)
+	return a.cmds.Run(func(cmd []string) error {
+		expanded, err := a.expand(cmd, args[1:], data)
+		if err != nil {
+			return err
+		}
+		return aerc.RunCommand(expanded)
+	})
+}
+
+// expand executes the templates of each argument and replaces the positional
+// parameters ($1, $2, ..., $@) with the alias arguments.
+func (a *Alias) expand(
+	cmd []string, params []string, data *templates.TemplateData,
+) ([]string, error) {
+	var expanded []string
+	for _, arg := range cmd {
+		t, err := templates.ParseTemplate(a.name, arg)
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if err := t.Execute(&buf, data); err != nil {
+			return nil, err
+		}
+		arg = templates.Unstyle(buf.String())
+		if arg == "$@" {
+			expanded = append(expanded, params...)
+			continue
+		}
+		var paramErr error
+		arg = paramRe.ReplaceAllStringFunc(arg, func(p string) string {
+			switch p {
+			case "$$":
+				return "$"
+			case "$@":
+				return strings.Join(params, " ")
+			}
+			n, _ := strconv.Atoi(p[1:])
+			if n < 1 || n > len(params) {
+				paramErr = fmt.Errorf("%s: missing argument %s", a.name, p)
+				return ""
+			}
+			return params[n-1]
+		})
+		if paramErr != nil {
+			return nil, paramErr
+		}
+		expanded = append(expanded, arg)
+	}
+	return expanded, nil
+}
+
+// aliasTemplateData returns the template data for the currently selected
+// message, if any.
+func aliasTemplateData(aerc *widgets.Aerc) *templates.TemplateData {
+	acct := aerc.SelectedAccount()
+	if acct == nil {
+		return new(templates.TemplateData)
+	}
+	data := accountTemplateData(acct)
+	if pm, ok := aerc.SelectedTabContent().(widgets.ProvidesMessage); ok {
+		if msg, err := pm.SelectedMessage(); err == nil && msg != nil {
+			data.SetInfo(msg, 0, false)
+		}
+	}
+	return data
+}
+
+func hasParam(arg string) bool {
+	for _, p := range paramRe.FindAllString(arg, -1) {
+		if p != "$$" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+
+	"git.sr.ht/~rjarry/aerc/lib/templates"
+)
+
+func TestAliasExpand(t *testing.T) {
+	type tc struct {
+		def      string
+		args     []string
+		expected [][]string
+	}
+	cases := []*tc{
+		{
+			"read; archive flat",
+			nil,
+			[][]string{{"read"}, {"archive", "flat"}},
+		},
+		{
+			"read && archive",
+			[]string{"flat"},
+			[][]string{{"read"}, {"archive", "flat"}},
+		},
+		{
+			"move -p $1",
+			[]string{"Archive/2023"},
+			[][]string{{"move", "-p", "Archive/2023"}},
+		},
+		{
+			`exec echo "$2 $1"; cf $@`,
+			[]string{"a", "b"},
+			[][]string{{"exec", "echo", "b a"}, {"cf", "a", "b"}},
+		},
+		{
+			`exec echo 'a;b' "c;d" $$1`,
+			[]string{"x"},
+			[][]string{{"exec", "echo", "a;b", "c;d", "$1", "x"}},
+		},
+		{
+			`exec echo {{.Subject}}`,
+			nil,
+			[][]string{{"exec", "echo", "[PATCH aerc 2/3] foo: baz bar buz"}},
+		},
+	}
+	for _, c := range cases {
+		alias, err := NewAlias("test", c.def)
+		if err != nil {
+			t.Errorf("%q: %v", c.def, err)
+			continue
+		}
+		var result [][]string
+		for _, cmd := range alias.cmds {
+			expanded, err := alias.expand(cmd.Args, c.args, templates.DummyData())
+			if err != nil {
+				t.Errorf("%q: %v", c.def, err)
+				continue
+			}
+			result = append(result, expanded)
+		}
+		if !reflect.DeepEqual(result, c.expected) {
+			t.Errorf("%q: expected %q but got %q", c.def, c.expected, result)
+		}
+	}
+}
+
+func TestAliasMissingArgument(t *testing.T) {
+	alias, err := NewAlias("test", "move $2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = alias.expand(alias.cmds[0].Args, []string{"one"}, templates.DummyData())
+	if err == nil {
+		t.Errorf("expected missing argument error")
+	}
+}
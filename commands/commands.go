@@ -8,6 +8,7 @@ import (
 
 	"github.com/google/shlex"
 
+	"git.sr.ht/~rjarry/aerc/lib/parse"
 	"git.sr.ht/~rjarry/aerc/widgets"
 )
 
@@ -74,7 +75,18 @@ func (cmds *Commands) ExecuteCommand(aerc *widgets.Aerc, args []string) error {
 	return NoSuchCommand(args[0])
 }
 
-func (cmds *Commands) GetCompletions(aerc *widgets.Aerc, cmd string) []string {
+// GetCompletions completes the last command of a command line chained with
+// ;, && or ||. The previous commands are kept as is.
+func (cmds *Commands) GetCompletions(aerc *widgets.Aerc, line string) []string {
+	prefix, cmd := parse.LastCommand(line)
+	var completions []string
+	for _, c := range cmds.complete(aerc, cmd) {
+		completions = append(completions, prefix+c)
+	}
+	return completions
+}
+
+func (cmds *Commands) complete(aerc *widgets.Aerc, cmd string) []string {
 	args, err := shlex.Split(cmd)
 	if err != nil {
 		return nil
@@ -18,6 +18,8 @@ import (
 
 	"git.sr.ht/~rjarry/aerc/commands/mode"
 	"git.sr.ht/~rjarry/aerc/lib"
+	"git.sr.ht/~rjarry/aerc/lib/statusline"
+	"git.sr.ht/~rjarry/aerc/lib/ui"
 	"git.sr.ht/~rjarry/aerc/log"
 	"git.sr.ht/~rjarry/aerc/models"
 	"git.sr.ht/~rjarry/aerc/widgets"
@@ -140,6 +142,8 @@ func send(aerc *widgets.Aerc, composer *widgets.Composer, ctx sendCtx,
 	// so we do everything in a goroutine and hide the composer from the user
 	aerc.RemoveTab(composer)
 	aerc.PushStatus("Sending...", 10*time.Second)
+	acct := composer.Account()
+	acct.SetStatus(statusline.Outbox(1))
 
 	// enter no-quit mode
 	mode.NoQuit()
@@ -190,6 +194,7 @@ func send(aerc *widgets.Aerc, composer *widgets.Composer, ctx sendCtx,
 		defer mode.NoQuitDone()
 
 		err := <-failCh
+		ui.QueueFunc(func() { acct.SetStatus(statusline.Outbox(-1)) })
 		if err != nil {
 			aerc.PushError(strings.ReplaceAll(err.Error(), "\n", " "))
 			aerc.NewTab(composer, tabName)
@@ -37,6 +37,9 @@ func (ExecCmd) Execute(aerc *widgets.Aerc, args []string) error {
 	case *widgets.AccountView:
 		env = append(env, fmt.Sprintf("account=%s", view.AccountConfig().Name))
 		env = append(env, fmt.Sprintf("folder=%s", view.Directories().Selected()))
+	case *widgets.Layout:
+		env = append(env, fmt.Sprintf("account=%s", view.SelectedAccount().Name()))
+		env = append(env, fmt.Sprintf("folder=%s", view.SelectedDirectory()))
 	case *widgets.MessageViewer:
 		acct := view.SelectedAccount()
 		env = append(env, fmt.Sprintf("account=%s", acct.AccountConfig().Name))
@@ -22,6 +22,8 @@ var pages = []string{
 	"templates",
 	"tutorial",
 	"keys",
+	"aliases",
+	"lua",
 }
 
 func init() {
@@ -44,6 +46,27 @@ func (Help) Execute(aerc *widgets.Aerc, args []string) error {
 		return errors.New("Usage: help [topic]")
 	}
 
+	if page == "aerc-aliases" {
+		aliases := AliasDefinitions()
+		if len(aliases) == 0 {
+			return errors.New("No aliases defined in aerc.conf")
+		}
+		aerc.AddDialog(widgets.NewDialog(
+			widgets.NewListBox(
+				"Aliases: Press <Esc> or <Enter> to close. "+
+					"Start typing to filter aliases.",
+				aliases,
+				aerc.SelectedAccountUiConfig(),
+				func(_ string) {
+					aerc.CloseDialog()
+				},
+			),
+			func(h int) int { return h / 4 },
+			func(h int) int { return h / 2 },
+		))
+		return nil
+	}
+
 	if page == "aerc-keys" {
 		aerc.AddDialog(widgets.NewDialog(
 			widgets.NewListBox(
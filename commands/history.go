@@ -7,91 +7,172 @@ import (
 	"io"
 	"os"
 	"path"
+	"sort"
+	"strings"
 	"sync"
 
+	"git.sr.ht/~rjarry/aerc/lib"
 	"git.sr.ht/~rjarry/aerc/log"
 	"github.com/kyoh86/xdg"
 )
 
 type cmdHistory struct {
-	// rolling buffer of prior commands
+	// rolling buffers of prior commands, indexed by history name
 	//
-	// most recent command is at the end of the list,
+	// most recent command is at the end of each list,
 	// least recent is index 0
-	cmdList []string
-
-	// current placement in list
-	current int
+	lists map[string][]string
 
 	// initialize history storage
 	initHistfile sync.Once
 	histfile     io.ReadWriter
 }
 
-// number of commands to keep in history
+// number of commands to keep in each history
 const cmdLimit = 1000
 
+// histories which are kept in memory only, answers to prompts may be
+// sensitive
+var volatileHistories = map[string]bool{
+	lib.HistoryPrompt: true,
+}
+
+// commands which are also recorded in the search history
+var searchCommands = map[string]bool{
+	"search": true,
+	"filter": true,
+}
+
 // CmdHistory is the history of executed commands
 var CmdHistory = cmdHistory{}
 
-func (h *cmdHistory) Add(cmd string) {
+// For returns a cursor over the named history. Each cursor keeps track of
+// its own location in history.
+func (h *cmdHistory) For(name string) lib.History {
 	h.initHistfile.Do(h.initialize)
+	c := &historyCursor{history: h, context: name}
+	c.Reset()
+	return c
+}
 
-	// if we're at cap, cut off the first element
-	if len(h.cmdList) >= cmdLimit {
-		h.cmdList = h.cmdList[1:]
+// add appends cmd to the named history. An earlier occurrence of the same
+// command is removed so that each command appears only once.
+func (h *cmdHistory) add(name string, cmd string) bool {
+	list := h.lists[name]
+	if len(list) > 0 && list[len(list)-1] == cmd {
+		return false
+	}
+	for i, c := range list {
+		if c == cmd {
+			list = append(list[:i], list[i+1:]...)
+			break
+		}
 	}
+	list = append(list, cmd)
+	// if we're over cap, cut off the first elements
+	if len(list) > cmdLimit {
+		list = list[len(list)-cmdLimit:]
+	}
+	h.lists[name] = list
+	return true
+}
 
-	if len(h.cmdList) == 0 || h.cmdList[len(h.cmdList)-1] != cmd {
-		h.cmdList = append(h.cmdList, cmd)
+func isSearchCommand(cmd string) bool {
+	fields := strings.Fields(cmd)
+	return len(fields) > 0 && searchCommands[fields[0]]
+}
 
-		h.writeHistory()
+type historyCursor struct {
+	history *cmdHistory
+	context string
+	// name of the list being browsed
+	list string
+	// current placement in list
+	current int
+}
+
+func (c *historyCursor) Add(cmd string) {
+	changed := c.history.add(c.context, cmd)
+	if c.context != lib.HistoryPrompt && isSearchCommand(cmd) {
+		changed = c.history.add(lib.HistorySearch, cmd) || changed
+	}
+	if changed && !volatileHistories[c.context] {
+		c.history.writeHistory()
 	}
 
 	// whenever we add a new command, reset the current
 	// pointer to the "beginning" of the list
-	h.Reset()
+	c.Reset()
+}
+
+// entries returns the list to browse for the given text. Search commands are
+// looked up in the shared search history.
+func (c *historyCursor) entries(text string) []string {
+	name := c.context
+	if c.context != lib.HistoryPrompt && isSearchCommand(text) {
+		name = lib.HistorySearch
+	}
+	if name != c.list {
+		c.list = name
+		c.current = len(c.history.lists[name])
+	}
+	return c.history.lists[name]
 }
 
-// Prev returns the previous command in history.
+// Prev returns the previous command in history which starts with prefix.
 // Since the list is reverse-order, this will return elements
 // increasingly towards index 0.
-func (h *cmdHistory) Prev() string {
-	h.initHistfile.Do(h.initialize)
-
-	if h.current <= 0 || len(h.cmdList) == 0 {
-		h.current = -1
-		return "(Already at beginning)"
+func (c *historyCursor) Prev(prefix string) (string, bool) {
+	list := c.entries(prefix)
+	for i := c.current - 1; i >= 0; i-- {
+		if strings.HasPrefix(list[i], prefix) {
+			c.current = i
+			return list[i], true
+		}
 	}
-	h.current--
-
-	return h.cmdList[h.current]
+	return "", false
 }
 
-// Next returns the next command in history.
+// Next returns the next command in history which starts with prefix.
 // Since the list is reverse-order, this will return elements
-// increasingly towards index len(cmdList).
-func (h *cmdHistory) Next() string {
-	h.initHistfile.Do(h.initialize)
-
-	if h.current >= len(h.cmdList)-1 || len(h.cmdList) == 0 {
-		h.current = len(h.cmdList)
-		return "(Already at end)"
+// increasingly towards index len(list).
+func (c *historyCursor) Next(prefix string) (string, bool) {
+	list := c.entries(prefix)
+	for i := c.current + 1; i < len(list); i++ {
+		if strings.HasPrefix(list[i], prefix) {
+			c.current = i
+			return list[i], true
+		}
 	}
-	h.current++
+	c.current = len(list)
+	return "", false
+}
 
-	return h.cmdList[h.current]
+// Search returns the closest command before the current placement which
+// contains text.
+func (c *historyCursor) Search(text string) (string, bool) {
+	list := c.entries(text)
+	for i := c.current - 1; i >= 0; i-- {
+		if strings.Contains(list[i], text) {
+			c.current = i
+			return list[i], true
+		}
+	}
+	return "", false
 }
 
 // Reset the current pointer to the beginning of history.
-func (h *cmdHistory) Reset() {
-	h.current = len(h.cmdList)
+func (c *historyCursor) Reset() {
+	c.list = c.context
+	c.current = len(c.history.lists[c.context])
 }
 
 func (h *cmdHistory) initialize() {
 	var err error
 	openFlags := os.O_RDWR | os.O_EXCL
 
+	h.lists = make(map[string][]string)
+
 	histPath := path.Join(xdg.CacheHome(), "aerc", "history")
 	if _, err := os.Stat(histPath); os.IsNotExist(err) {
 		_ = os.MkdirAll(path.Join(xdg.CacheHome(), "aerc"), 0o700) // caught by OpenFile
@@ -111,13 +192,25 @@ func (h *cmdHistory) initialize() {
 		return
 	}
 
-	s := bufio.NewScanner(h.histfile)
+	h.readHistory(h.histfile)
+}
 
+// readHistory parses history entries. Each line is made of the history name
+// and the command separated by a tab. Lines without a history name were
+// written by older versions and are imported in the message list history.
+func (h *cmdHistory) readHistory(r io.Reader) {
+	s := bufio.NewScanner(r)
 	for s.Scan() {
-		h.cmdList = append(h.cmdList, s.Text())
+		name, cmd, found := strings.Cut(s.Text(), "\t")
+		if !found || name == "" || strings.ContainsRune(name, ' ') {
+			h.add(lib.HistoryAccount, s.Text())
+			continue
+		}
+		if volatileHistories[name] {
+			continue
+		}
+		h.add(name, cmd)
 	}
-
-	h.Reset()
 }
 
 func (h *cmdHistory) writeHistory() {
@@ -132,8 +225,18 @@ func (h *cmdHistory) writeHistory() {
 			// if we can't delete it, don't break it.
 			return
 		}
-		for _, entry := range h.cmdList {
-			fmt.Fprintln(fh, entry)
+		names := make([]string, 0, len(h.lists))
+		for name := range h.lists {
+			if volatileHistories[name] {
+				continue
+			}
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			for _, entry := range h.lists[name] {
+				fmt.Fprintf(fh, "%s\t%s\n", name, entry)
+			}
 		}
 
 		fh.Sync() //nolint:errcheck // if your computer can't sync you're in bigger trouble
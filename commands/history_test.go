@@ -0,0 +1,140 @@
+package commands
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"git.sr.ht/~rjarry/aerc/lib"
+)
+
+func newTestHistory(content string) *cmdHistory {
+	h := &cmdHistory{lists: make(map[string][]string)}
+	// do not touch the history file
+	h.initHistfile.Do(func() {})
+	h.readHistory(strings.NewReader(content))
+	return h
+}
+
+func TestHistoryRead(t *testing.T) {
+	h := newTestHistory("cf INBOX\naccount\tarchive flat\ncompose\tsend\n" +
+		"prompt\tsecret\n")
+	tests := []struct {
+		name    string
+		entries []string
+	}{
+		// legacy lines are only imported in the message list history
+		{lib.HistoryAccount, []string{"cf INBOX", "archive flat"}},
+		{lib.HistoryCompose, []string{"send"}},
+		{lib.HistoryTerminal, nil},
+		// prompt answers are not loaded from disk
+		{lib.HistoryPrompt, nil},
+	}
+	for _, test := range tests {
+		if !reflect.DeepEqual(h.lists[test.name], test.entries) {
+			t.Errorf("%s: got %q, expected %q",
+				test.name, h.lists[test.name], test.entries)
+		}
+	}
+}
+
+func TestHistoryWrite(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	h := newTestHistory("")
+	h.histfile = f
+	h.For(lib.HistoryAccount).Add("cf INBOX")
+	h.For(lib.HistoryPrompt).Add("secret")
+	h.For(lib.HistoryCompose).Add("send")
+
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "account\tcf INBOX\ncompose\tsend\n"; string(data) != expected {
+		t.Errorf("got %q, expected %q", data, expected)
+	}
+	if !reflect.DeepEqual(h.lists[lib.HistoryPrompt], []string{"secret"}) {
+		t.Errorf("prompt: got %q", h.lists[lib.HistoryPrompt])
+	}
+}
+
+func TestHistoryNavigation(t *testing.T) {
+	h := newTestHistory("")
+	c := h.For(lib.HistoryAccount)
+	for _, cmd := range []string{
+		"cf INBOX", "archive flat", "cf Sent", "search foo", "cf INBOX",
+	} {
+		c.Add(cmd)
+	}
+	// duplicates are removed
+	expected := []string{"archive flat", "cf Sent", "search foo", "cf INBOX"}
+	if !reflect.DeepEqual(h.lists[lib.HistoryAccount], expected) {
+		t.Errorf("got %q, expected %q", h.lists[lib.HistoryAccount], expected)
+	}
+	if !reflect.DeepEqual(h.lists[lib.HistorySearch], []string{"search foo"}) {
+		t.Errorf("search: got %q", h.lists[lib.HistorySearch])
+	}
+
+	type step struct {
+		prev   bool
+		prefix string
+		cmd    string
+		ok     bool
+	}
+	for i, s := range []step{
+		{true, "cf", "cf INBOX", true},
+		{true, "cf", "cf Sent", true},
+		{true, "cf", "", false},
+		{false, "cf", "cf INBOX", true},
+		{false, "cf", "", false},
+		{true, "", "cf INBOX", true},
+		{true, "", "search foo", true},
+	} {
+		var cmd string
+		var ok bool
+		if s.prev {
+			cmd, ok = c.Prev(s.prefix)
+		} else {
+			cmd, ok = c.Next(s.prefix)
+		}
+		if cmd != s.cmd || ok != s.ok {
+			t.Errorf("step %d: got (%q, %v), expected (%q, %v)",
+				i, cmd, ok, s.cmd, s.ok)
+		}
+	}
+
+	// search commands are shared between contexts
+	c = h.For(lib.HistoryMsgView)
+	if cmd, ok := c.Prev("search"); !ok || cmd != "search foo" {
+		t.Errorf("search: got (%q, %v)", cmd, ok)
+	}
+	if _, ok := c.Prev("cf"); ok {
+		t.Errorf("msgview history should be empty")
+	}
+}
+
+func TestHistorySearch(t *testing.T) {
+	h := newTestHistory("")
+	c := h.For(lib.HistoryAccount)
+	for _, cmd := range []string{"move Archive", "cf Archive", "read"} {
+		c.Add(cmd)
+	}
+	if cmd, ok := c.Search("Arch"); !ok || cmd != "cf Archive" {
+		t.Errorf("got (%q, %v), expected cf Archive", cmd, ok)
+	}
+	if cmd, ok := c.Search("Arch"); !ok || cmd != "move Archive" {
+		t.Errorf("got (%q, %v), expected move Archive", cmd, ok)
+	}
+	if _, ok := c.Search("Arch"); ok {
+		t.Errorf("expected no more matches")
+	}
+	c.Reset()
+	if cmd, ok := c.Search("rea"); !ok || cmd != "read" {
+		t.Errorf("got (%q, %v), expected read", cmd, ok)
+	}
+}
@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"git.sr.ht/~rjarry/aerc/config"
+	"git.sr.ht/~rjarry/aerc/widgets"
+)
+
+type OpenLayout struct{}
+
+func init() {
+	register(OpenLayout{})
+}
+
+func (OpenLayout) Aliases() []string {
+	return []string{"layout"}
+}
+
+func (OpenLayout) Complete(aerc *widgets.Aerc, args []string) []string {
+	var names []string
+	for _, l := range config.Layouts {
+		names = append(names, l.Name)
+	}
+	return CompletionFromList(aerc, names, args)
+}
+
+func (OpenLayout) Execute(aerc *widgets.Aerc, args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("Usage: %s <name>|<panes>", args[0])
+	}
+	// panes may be separated by spaces for readability
+	name := strings.Join(args[1:], " ")
+	var rows [][]*config.PaneConfig
+	if l := config.FindLayout(name); l != nil {
+		rows = l.Rows
+	} else {
+		var err error
+		rows, err = config.ParsePanes(name)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	layout, err := widgets.NewLayout(aerc, rows, aerc.SelectedAccount())
+	if err != nil {
+		return err
+	}
+	aerc.NewTab(layout, name)
+	return nil
+}
@@ -0,0 +1,43 @@
+package layout
+
+import (
+	"errors"
+
+	"git.sr.ht/~rjarry/aerc/commands"
+	"git.sr.ht/~rjarry/aerc/widgets"
+)
+
+type FocusPane struct{}
+
+func init() {
+	register(FocusPane{})
+}
+
+func (FocusPane) Aliases() []string {
+	return []string{"focus-pane"}
+}
+
+func (FocusPane) Complete(aerc *widgets.Aerc, args []string) []string {
+	return commands.CompletionFromList(aerc,
+		[]string{"left", "down", "up", "right"}, args)
+}
+
+func (FocusPane) Execute(aerc *widgets.Aerc, args []string) error {
+	if len(args) != 2 {
+		return errors.New("Usage: focus-pane left|down|up|right")
+	}
+	layout, _ := aerc.SelectedTabContent().(*widgets.Layout)
+	switch args[1] {
+	case "left":
+		layout.MovePane(0, -1)
+	case "down":
+		layout.MovePane(1, 0)
+	case "up":
+		layout.MovePane(-1, 0)
+	case "right":
+		layout.MovePane(0, 1)
+	default:
+		return errors.New("Usage: focus-pane left|down|up|right")
+	}
+	return nil
+}
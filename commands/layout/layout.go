@@ -0,0 +1,14 @@
+package layout
+
+import (
+	"git.sr.ht/~rjarry/aerc/commands"
+)
+
+var LayoutCommands *commands.Commands
+
+func register(cmd commands.Command) {
+	if LayoutCommands == nil {
+		LayoutCommands = commands.NewCommands()
+	}
+	LayoutCommands.Register(cmd)
+}
@@ -0,0 +1,48 @@
+package layout
+
+import (
+	"fmt"
+	"strconv"
+
+	"git.sr.ht/~rjarry/aerc/widgets"
+)
+
+type NextPrevPane struct{}
+
+func init() {
+	register(NextPrevPane{})
+}
+
+func (NextPrevPane) Aliases() []string {
+	return []string{"next-pane", "prev-pane"}
+}
+
+func (NextPrevPane) Complete(aerc *widgets.Aerc, args []string) []string {
+	return nil
+}
+
+func (NextPrevPane) Execute(aerc *widgets.Aerc, args []string) error {
+	if len(args) > 2 {
+		return nextPrevPaneUsage(args[0])
+	}
+	var (
+		n   int = 1
+		err error
+	)
+	if len(args) > 1 {
+		n, err = strconv.Atoi(args[1])
+		if err != nil {
+			return nextPrevPaneUsage(args[0])
+		}
+	}
+	if args[0] == "prev-pane" {
+		n = -n
+	}
+	layout, _ := aerc.SelectedTabContent().(*widgets.Layout)
+	layout.NextPane(n)
+	return nil
+}
+
+func nextPrevPaneUsage(cmd string) error {
+	return fmt.Errorf("Usage: %s [n]", cmd)
+}
@@ -0,0 +1,383 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/emersion/go-message/mail"
+	"github.com/kyoh86/xdg"
+	"github.com/mitchellh/go-homedir"
+	lua "github.com/yuin/gopher-lua"
+
+	"git.sr.ht/~rjarry/aerc/config"
+	"git.sr.ht/~rjarry/aerc/lib/parse"
+	"git.sr.ht/~rjarry/aerc/lib/templates"
+	"git.sr.ht/~rjarry/aerc/lib/ui"
+	"git.sr.ht/~rjarry/aerc/log"
+	"git.sr.ht/~rjarry/aerc/models"
+	"git.sr.ht/~rjarry/aerc/widgets"
+)
+
+// luaRuntime holds the embedded interpreter used by user scripts. The Lua
+// state is not thread safe, all accesses must be done with the lock held.
+type luaRuntime struct {
+	sync.Mutex
+	state    *lua.LState
+	aerc     *widgets.Aerc
+	builtins []*Commands
+	columns  map[string]*lua.LFunction
+}
+
+var scripts *luaRuntime
+
+// LoadScripts initializes the Lua interpreter and executes the user script
+// configured in [general].lua-script, or $XDG_CONFIG_HOME/aerc/init.lua if it
+// exists.
+func LoadScripts(aerc *widgets.Aerc, builtins []*Commands) error {
+	scripts = newLuaRuntime(aerc, builtins)
+	templates.ScriptHandler = scripts.column
+
+	file := config.General.LuaScript
+	if file == "" {
+		file = path.Join(xdg.ConfigHome(), "aerc", "init.lua")
+		if _, err := os.Stat(file); err != nil {
+			return nil
+		}
+	}
+	file, err := homedir.Expand(file)
+	if err != nil {
+		return err
+	}
+
+	scripts.Lock()
+	defer scripts.Unlock()
+	if err := scripts.state.DoFile(file); err != nil {
+		return fmt.Errorf("lua-script: %w", err)
+	}
+	return nil
+}
+
+func newLuaRuntime(aerc *widgets.Aerc, builtins []*Commands) *luaRuntime {
+	rt := &luaRuntime{
+		state:    lua.NewState(),
+		aerc:     aerc,
+		builtins: builtins,
+		columns:  make(map[string]*lua.LFunction),
+	}
+	mod := rt.state.NewTable()
+	rt.state.SetFuncs(mod, map[string]lua.LGFunction{
+		"command":  rt.luaCommand,
+		"column":   rt.luaColumn,
+		"on":       rt.luaOn,
+		"exec":     rt.luaExec,
+		"status":   rt.luaStatus,
+		"error":    rt.luaError,
+		"accounts": rt.luaAccounts,
+		"account":  rt.luaAccount,
+		"selected": rt.luaSelected,
+		"marked":   rt.luaMarked,
+	})
+	rt.state.SetGlobal("aerc", mod)
+	return rt
+}
+
+// call invokes a Lua function in protected mode and returns its first return
+// value. The lock must be held.
+func (rt *luaRuntime) call(fn *lua.LFunction, args ...lua.LValue) (lua.LValue, error) {
+	err := rt.state.CallByParam(lua.P{Fn: fn, NRet: 1, Protect: true}, args...)
+	if err != nil {
+		return lua.LNil, err
+	}
+	ret := rt.state.Get(-1)
+	rt.state.Pop(1)
+	return ret, nil
+}
+
+// aerc.command(name, function(args) ... end)
+//
+// Registers a new command. The function receives the command arguments as a
+// list (without the command name). It may return an error message.
+func (rt *luaRuntime) luaCommand(L *lua.LState) int {
+	name := L.CheckString(1)
+	fn := L.CheckFunction(2)
+	for _, cmds := range rt.builtins {
+		if cmds.ByName(name) != nil {
+			L.ArgError(1, fmt.Sprintf("%q conflicts with built-in command", name))
+			return 0
+		}
+	}
+	register(&LuaCommand{name: name, fn: fn})
+	return 0
+}
+
+// aerc.column(name, function(msg) return "text" end)
+//
+// Registers a function which can be used in templates with {{lua "name" .}}.
+func (rt *luaRuntime) luaColumn(L *lua.LState) int {
+	rt.columns[L.CheckString(1)] = L.CheckFunction(2)
+	return 0
+}
+
+// aerc.on(event, function(...) ... end)
+//
+// Registers an event handler. Supported events:
+//
+//	new-email: function(msg) called when a new message arrives
+func (rt *luaRuntime) luaOn(L *lua.LState) int {
+	event := L.CheckString(1)
+	fn := L.CheckFunction(2)
+	switch event {
+	case "new-email":
+		config.Triggers.NewEmailHooks = append(config.Triggers.NewEmailHooks,
+			func(account *config.AccountConfig, msg *models.MessageInfo) {
+				rt.Lock()
+				defer rt.Unlock()
+				t := rt.messageTable(account.Name, msg)
+				if _, err := rt.call(fn, t); err != nil {
+					log.Errorf("lua: new-email: %v", err)
+				}
+			})
+	default:
+		L.ArgError(1, fmt.Sprintf("unsupported event %q", event))
+	}
+	return 0
+}
+
+// aerc.exec(cmdline) -> nil or error message
+//
+// The commands may call back into lua, which must not be reentered while
+// this function is running. They are executed once it has returned.
+func (rt *luaRuntime) luaExec(L *lua.LState) int {
+	line := L.CheckString(1)
+	chain, err := parse.CommandLine(line)
+	if err == nil && len(chain) == 0 {
+		err = errors.New("Expected a command.")
+	}
+	if err != nil {
+		L.Push(lua.LString(err.Error()))
+		return 1
+	}
+	ui.QueueFunc(func() {
+		if err := rt.aerc.RunCommandLine(line); err != nil {
+			rt.aerc.PushError(err.Error())
+		}
+	})
+	L.Push(lua.LNil)
+	return 1
+}
+
+// aerc.status(text)
+func (rt *luaRuntime) luaStatus(L *lua.LState) int {
+	rt.aerc.PushStatus(L.CheckString(1), 10*time.Second)
+	return 0
+}
+
+// aerc.error(text)
+func (rt *luaRuntime) luaError(L *lua.LState) int {
+	rt.aerc.PushError(L.CheckString(1))
+	return 0
+}
+
+// aerc.accounts() -> {"name", ...}
+func (rt *luaRuntime) luaAccounts(L *lua.LState) int {
+	t := L.NewTable()
+	for _, name := range rt.aerc.AccountNames() {
+		t.Append(lua.LString(name))
+	}
+	L.Push(t)
+	return 1
+}
+
+// aerc.account() -> {name=..., folder=..., folders={...}} or nil
+func (rt *luaRuntime) luaAccount(L *lua.LState) int {
+	acct := rt.aerc.SelectedAccount()
+	if acct == nil {
+		L.Push(lua.LNil)
+		return 1
+	}
+	t := L.NewTable()
+	t.RawSetString("name", lua.LString(acct.Name()))
+	t.RawSetString("folder", lua.LString(acct.Directories().Selected()))
+	folders := L.NewTable()
+	for _, f := range acct.Directories().List() {
+		folders.Append(lua.LString(f))
+	}
+	t.RawSetString("folders", folders)
+	if store := acct.Store(); store != nil {
+		t.RawSetString("count", lua.LNumber(len(store.Uids())))
+	}
+	L.Push(t)
+	return 1
+}
+
+// aerc.selected() -> msg or nil
+func (rt *luaRuntime) luaSelected(L *lua.LState) int {
+	pm, ok := rt.aerc.SelectedTabContent().(widgets.ProvidesMessage)
+	if !ok {
+		L.Push(lua.LNil)
+		return 1
+	}
+	msg, err := pm.SelectedMessage()
+	if err != nil || msg == nil {
+		L.Push(lua.LNil)
+		return 1
+	}
+	L.Push(rt.messageTable(pm.SelectedAccount().Name(), msg))
+	return 1
+}
+
+// aerc.marked() -> {msg, ...}
+func (rt *luaRuntime) luaMarked(L *lua.LState) int {
+	t := L.NewTable()
+	pm, ok := rt.aerc.SelectedTabContent().(widgets.ProvidesMessages)
+	if !ok {
+		L.Push(t)
+		return 1
+	}
+	uids, err := pm.MarkedMessages()
+	if err != nil || pm.Store() == nil {
+		L.Push(t)
+		return 1
+	}
+	for _, uid := range uids {
+		msg := pm.Store().Messages[uid]
+		if msg == nil {
+			msg = &models.MessageInfo{Uid: uid}
+		}
+		t.Append(rt.messageTable(pm.SelectedAccount().Name(), msg))
+	}
+	L.Push(t)
+	return 1
+}
+
+// column is the template handler for {{lua "name" .}}
+func (rt *luaRuntime) column(name string, data *templates.TemplateData) (string, error) {
+	rt.Lock()
+	defer rt.Unlock()
+	fn, ok := rt.columns[name]
+	if !ok {
+		return "", fmt.Errorf("lua: unknown column function %q", name)
+	}
+	ret, err := rt.call(fn, rt.templateTable(data))
+	if err != nil {
+		return "", err
+	}
+	if ret == lua.LNil {
+		return "", nil
+	}
+	return ret.String(), nil
+}
+
+func (rt *luaRuntime) messageTable(account string, msg *models.MessageInfo) *lua.LTable {
+	var data *templates.TemplateData
+	if acct, err := rt.aerc.Account(account); err == nil {
+		data = accountTemplateData(acct)
+	} else {
+		data = new(templates.TemplateData)
+	}
+	data.SetInfo(msg, 0, false)
+	t := rt.templateTable(data)
+	t.RawSetString("uid", lua.LNumber(msg.Uid))
+	return t
+}
+
+func (rt *luaRuntime) templateTable(data *templates.TemplateData) *lua.LTable {
+	L := rt.state
+	t := L.NewTable()
+	strs := func(list []string) *lua.LTable {
+		l := L.NewTable()
+		for _, s := range list {
+			l.Append(lua.LString(s))
+		}
+		return l
+	}
+	t.RawSetString("account", lua.LString(data.Account()))
+	t.RawSetString("folder", lua.LString(data.Folder()))
+	t.RawSetString("subject", lua.LString(data.Subject()))
+	t.RawSetString("from", strs(addressList(data.From())))
+	t.RawSetString("to", strs(addressList(data.To())))
+	t.RawSetString("cc", strs(addressList(data.Cc())))
+	t.RawSetString("date", lua.LNumber(data.Date().Unix()))
+	t.RawSetString("message_id", lua.LString(data.MessageId()))
+	t.RawSetString("size", lua.LNumber(data.Size()))
+	t.RawSetString("flags", strs(data.Flags()))
+	t.RawSetString("labels", strs(data.Labels()))
+	t.RawSetString("header", L.NewFunction(func(L *lua.LState) int {
+		L.Push(lua.LString(data.Header(L.CheckString(1))))
+		return 1
+	}))
+	return t
+}
+
+func addressList(addrs []*mail.Address) []string {
+	var list []string
+	for _, a := range addrs {
+		list = append(list, a.String())
+	}
+	return list
+}
+
+// LuaCommand is a command implemented by a user script.
+type LuaCommand struct {
+	name string
+	fn   *lua.LFunction
+}
+
+func (c *LuaCommand) Aliases() []string {
+	return []string{c.name}
+}
+
+func (c *LuaCommand) Complete(aerc *widgets.Aerc, args []string) []string {
+	return nil
+}
+
+func (c *LuaCommand) Execute(aerc *widgets.Aerc, args []string) error {
+	scripts.Lock()
+	defer scripts.Unlock()
+	t := scripts.state.NewTable()
+	for _, arg := range args[1:] {
+		t.Append(lua.LString(arg))
+	}
+	ret, err := scripts.call(c.fn, t)
+	if err != nil {
+		return fmt.Errorf("%s: %w", c.name, err)
+	}
+	if ret != lua.LNil && ret != lua.LFalse && ret != lua.LTrue {
+		return errors.New(strings.TrimSpace(ret.String()))
+	}
+	return nil
+}
+
+// Lua executes a chunk of Lua code.
+type Lua struct{}
+
+func init() {
+	register(Lua{})
+}
+
+func (Lua) Aliases() []string {
+	return []string{"lua"}
+}
+
+func (Lua) Complete(aerc *widgets.Aerc, args []string) []string {
+	return nil
+}
+
+func (Lua) Execute(aerc *widgets.Aerc, args []string) error {
+	if len(args) != 2 {
+		// the code must be quoted to preserve its own quotes, spaces
+		// and semicolons
+		return errors.New("Usage: lua '<code>'")
+	}
+	if scripts == nil {
+		return errors.New("lua: scripts not initialized")
+	}
+	scripts.Lock()
+	defer scripts.Unlock()
+	return scripts.state.DoString(args[1])
+}
@@ -0,0 +1,39 @@
+package commands
+
+import (
+	"testing"
+
+	"git.sr.ht/~rjarry/aerc/lib/templates"
+)
+
+func TestLuaColumn(t *testing.T) {
+	rt := newLuaRuntime(nil, nil)
+	err := rt.state.DoString(`
+		aerc.column("test", function(msg)
+			return msg.subject .. " " .. table.concat(msg.labels, ",") ..
+				" " .. msg.from[1]
+		end)
+		aerc.column("fail", function(msg)
+			error("boom")
+		end)
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := rt.column("test", templates.DummyData())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `[PATCH aerc 2/3] foo: baz bar buz inbox,patch "John Doe" <john@example.com>`
+	if s != expected {
+		t.Errorf("expected %q got %q", expected, s)
+	}
+
+	if _, err := rt.column("fail", templates.DummyData()); err == nil {
+		t.Errorf("expected error")
+	}
+	if _, err := rt.column("unknown", templates.DummyData()); err == nil {
+		t.Errorf("expected error")
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"git.sr.ht/~rjarry/aerc/models"
 	"git.sr.ht/~rjarry/aerc/widgets"
 	"git.sr.ht/~rjarry/aerc/worker/types"
+	"git.sr.ht/~sircmpwn/getopt"
 )
 
 const (
@@ -35,15 +36,24 @@ func (Archive) Complete(aerc *widgets.Aerc, args []string) []string {
 }
 
 func (Archive) Execute(aerc *widgets.Aerc, args []string) error {
-	if len(args) != 2 {
-		return errors.New("Usage: archive <flat|year|month>")
+	opts, optind, err := getopt.Getopts(args, "T")
+	if err != nil {
+		return err
+	}
+	if len(args) != optind+1 {
+		return errors.New("Usage: archive [-T] <flat|year|month>")
 	}
 	h := newHelper(aerc)
+	for _, opt := range opts {
+		if opt.Option == 'T' {
+			h.threads = true
+		}
+	}
 	msgs, err := h.messages()
 	if err != nil {
 		return err
 	}
-	err = archive(aerc, msgs, args[1])
+	err = archive(aerc, msgs, args[optind])
 	return err
 }
 
@@ -8,6 +8,7 @@ import (
 	"git.sr.ht/~sircmpwn/getopt"
 
 	"git.sr.ht/~rjarry/aerc/commands"
+	"git.sr.ht/~rjarry/aerc/config"
 	"git.sr.ht/~rjarry/aerc/widgets"
 	"git.sr.ht/~rjarry/aerc/worker/types"
 )
@@ -27,13 +28,16 @@ func (Copy) Complete(aerc *widgets.Aerc, args []string) []string {
 }
 
 func (Copy) Execute(aerc *widgets.Aerc, args []string) error {
-	if len(args) == 1 {
-		return errors.New("Usage: cp [-p] <folder>")
-	}
 	opts, optind, err := getopt.Getopts(args, "p")
 	if err != nil {
 		return err
 	}
+	if optind == len(args) {
+		if config.Ui.GlobalSidebar {
+			return newHelper(aerc).pickFolder(aerc, Copy{}, args)
+		}
+		return errors.New("Usage: cp [-p] <folder>")
+	}
 	var createParents bool
 	for _, opt := range opts {
 		if opt.Option == 'p' {
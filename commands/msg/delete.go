@@ -10,6 +10,7 @@ import (
 	"git.sr.ht/~rjarry/aerc/models"
 	"git.sr.ht/~rjarry/aerc/widgets"
 	"git.sr.ht/~rjarry/aerc/worker/types"
+	"git.sr.ht/~sircmpwn/getopt"
 )
 
 type Delete struct{}
@@ -27,11 +28,20 @@ func (Delete) Complete(aerc *widgets.Aerc, args []string) []string {
 }
 
 func (Delete) Execute(aerc *widgets.Aerc, args []string) error {
-	if len(args) != 1 {
-		return errors.New("Usage: :delete")
+	opts, optind, err := getopt.Getopts(args, "T")
+	if err != nil {
+		return err
+	}
+	if len(args) != optind {
+		return errors.New("Usage: :delete [-T]")
 	}
 
 	h := newHelper(aerc)
+	for _, opt := range opts {
+		if opt.Option == 'T' {
+			h.threads = true
+		}
+	}
 	store, err := h.store()
 	if err != nil {
 		return err
@@ -0,0 +1,76 @@
+package msg
+
+import (
+	"errors"
+	"fmt"
+
+	"git.sr.ht/~rjarry/aerc/lib/ui"
+	"git.sr.ht/~rjarry/aerc/widgets"
+	"git.sr.ht/~sircmpwn/getopt"
+)
+
+type Fold struct{}
+
+func init() {
+	register(Fold{})
+}
+
+func (Fold) Aliases() []string {
+	return []string{"fold", "unfold", "toggle-fold"}
+}
+
+func (Fold) Complete(aerc *widgets.Aerc, args []string) []string {
+	return nil
+}
+
+func (Fold) Execute(aerc *widgets.Aerc, args []string) error {
+	opts, optind, err := getopt.Getopts(args, "a")
+	if err != nil {
+		return err
+	}
+	if len(args) != optind {
+		return fmt.Errorf("Usage: %s [-a]", args[0])
+	}
+	var all bool
+	for _, opt := range opts {
+		if opt.Option == 'a' {
+			all = true
+		}
+	}
+	h := newHelper(aerc)
+	store, err := h.store()
+	if err != nil {
+		return err
+	}
+	if !store.ThreadedView() {
+		return errors.New("threads are not displayed")
+	}
+
+	if all {
+		switch args[0] {
+		case "fold":
+			store.FoldAll(true)
+		case "unfold":
+			store.FoldAll(false)
+		default:
+			return errors.New("Usage: toggle-fold")
+		}
+		ui.Invalidate()
+		return nil
+	}
+
+	msg, err := h.msgProvider.SelectedMessage()
+	if err != nil {
+		return err
+	}
+	switch args[0] {
+	case "fold":
+		err = store.Fold(msg.Uid)
+	case "unfold":
+		err = store.Unfold(msg.Uid)
+	case "toggle-fold":
+		err = store.ToggleFold(msg.Uid)
+	}
+	ui.Invalidate()
+	return err
+}
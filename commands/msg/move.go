@@ -30,21 +30,29 @@ func (Move) Complete(aerc *widgets.Aerc, args []string) []string {
 }
 
 func (Move) Execute(aerc *widgets.Aerc, args []string) error {
-	if len(args) == 1 {
-		return errors.New("Usage: mv [-p] <folder>")
-	}
-	opts, optind, err := getopt.Getopts(args, "p")
+	opts, optind, err := getopt.Getopts(args, "pT")
 	if err != nil {
 		return err
 	}
+	if optind == len(args) {
+		if config.Ui.GlobalSidebar {
+			return newHelper(aerc).pickFolder(aerc, Move{}, args)
+		}
+		return errors.New("Usage: mv [-p] [-T] <folder>")
+	}
 	var createParents bool
+	var threads bool
 	for _, opt := range opts {
-		if opt.Option == 'p' {
+		switch opt.Option {
+		case 'p':
 			createParents = true
+		case 'T':
+			threads = true
 		}
 	}
 
 	h := newHelper(aerc)
+	h.threads = threads
 	acct, err := h.account()
 	if err != nil {
 		return err
@@ -0,0 +1,53 @@
+package msg
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"git.sr.ht/~rjarry/aerc/lib/threadstate"
+	"git.sr.ht/~rjarry/aerc/lib/ui"
+	"git.sr.ht/~rjarry/aerc/widgets"
+)
+
+type MuteThread struct{}
+
+func init() {
+	register(MuteThread{})
+}
+
+func (MuteThread) Aliases() []string {
+	return []string{"mute-thread", "watch-thread"}
+}
+
+func (MuteThread) Complete(aerc *widgets.Aerc, args []string) []string {
+	return nil
+}
+
+func (MuteThread) Execute(aerc *widgets.Aerc, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("Usage: %s", args[0])
+	}
+	state, name := threadstate.Muted, "muted"
+	if args[0] == "watch-thread" {
+		state, name = threadstate.Watched, "watched"
+	}
+	h := newHelper(aerc)
+	msg, err := h.msgProvider.SelectedMessage()
+	if err != nil {
+		return err
+	}
+	if msg.Envelope == nil {
+		return errors.New("message headers are not loaded")
+	}
+	// toggle the state of the thread
+	if threadstate.Get(msg) == state {
+		state, name = threadstate.Normal, "un"+name
+	}
+	if err := threadstate.Set(msg, state); err != nil {
+		return err
+	}
+	aerc.PushStatus("Thread "+name+".", 10*time.Second)
+	ui.Invalidate()
+	return nil
+}
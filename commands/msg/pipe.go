@@ -66,11 +66,12 @@ func (Pipe) Execute(aerc *widgets.Aerc, args []string) error {
 
 	provider := aerc.SelectedTabContent().(widgets.ProvidesMessage)
 	if !pipeFull && !pipePart {
-		if _, ok := provider.(*widgets.MessageViewer); ok {
+		switch provider.(type) {
+		case *widgets.MessageViewer:
 			pipePart = true
-		} else if _, ok := provider.(*widgets.AccountView); ok {
+		case *widgets.AccountView, *widgets.Layout:
 			pipeFull = true
-		} else {
+		default:
 			return errors.New(
 				"Neither -m nor -p specified and cannot infer default")
 		}
@@ -39,6 +39,8 @@ func (FlagMsg) Execute(aerc *widgets.Aerc, args []string) error {
 	var flagName string
 	// Whether to toggle the flag (true) or to enable/disable it (false)
 	var toggle bool
+	// Whether to act on the whole threads of the messages
+	var threads bool
 	// Whether to enable (true) or disable (false) the flag
 	enable := (args[0] == "read" || args[0] == "flag")
 	// User-readable name for the action being performed
@@ -54,13 +56,13 @@ func (FlagMsg) Execute(aerc *widgets.Aerc, args []string) error {
 	if args[0] == "read" || args[0] == "unread" {
 		flag = models.SeenFlag
 		flagName = "read"
-		getoptString = "t"
-		helpMessage = "Usage: " + args[0] + " [-t]"
+		getoptString = "tT"
+		helpMessage = "Usage: " + args[0] + " [-t] [-T]"
 	} else { // 'flag' / 'unflag'
 		flag = models.FlaggedFlag
 		flagName = "flagged"
-		getoptString = "tax:"
-		helpMessage = "Usage: " + args[0] + " [-t] [-a | -x <flag>]"
+		getoptString = "tTax:"
+		helpMessage = "Usage: " + args[0] + " [-t] [-T] [-a | -x <flag>]"
 	}
 
 	opts, optind, err := getopt.Getopts(args, getoptString)
@@ -71,6 +73,8 @@ func (FlagMsg) Execute(aerc *widgets.Aerc, args []string) error {
 		switch opt.Option {
 		case 't':
 			toggle = true
+		case 'T':
+			threads = true
 		case 'a':
 			if flagChosen {
 				return fmt.Errorf("Cannot choose a flag multiple times! " + helpMessage)
@@ -113,6 +117,7 @@ func (FlagMsg) Execute(aerc *widgets.Aerc, args []string) error {
 	}
 
 	h := newHelper(aerc)
+	h.threads = threads
 	store, err := h.store()
 	if err != nil {
 		return err
@@ -13,6 +13,8 @@ import (
 type helper struct {
 	msgProvider widgets.ProvidesMessages
 	statusInfo  func(string)
+	// act on all the messages of the selected threads
+	threads bool
 }
 
 func newHelper(aerc *widgets.Aerc) *helper {
@@ -25,7 +27,15 @@ func newHelper(aerc *widgets.Aerc) *helper {
 }
 
 func (h *helper) markedOrSelectedUids() ([]uint32, error) {
-	return commands.MarkedOrSelected(h.msgProvider)
+	uids, err := commands.MarkedOrSelected(h.msgProvider)
+	if err != nil || !h.threads {
+		return uids, err
+	}
+	store, err := h.store()
+	if err != nil {
+		return nil, err
+	}
+	return store.ThreadUids(uids), nil
 }
 
 func (h *helper) store() (*lib.MessageStore, error) {
@@ -45,7 +55,7 @@ func (h *helper) account() (*widgets.AccountView, error) {
 }
 
 func (h *helper) messages() ([]*models.MessageInfo, error) {
-	uid, err := commands.MarkedOrSelected(h.msgProvider)
+	uid, err := h.markedOrSelectedUids()
 	if err != nil {
 		return nil, err
 	}
@@ -55,3 +65,20 @@ func (h *helper) messages() ([]*models.MessageInfo, error) {
 	}
 	return commands.MsgInfoFromUids(store, uid, h.statusInfo)
 }
+
+// pickFolder lets the user choose the target folder in the global sidebar
+// and runs cmd again with the chosen folder appended to its arguments.
+func (h *helper) pickFolder(
+	aerc *widgets.Aerc, cmd commands.Command, args []string,
+) error {
+	acct, err := h.account()
+	if err != nil {
+		return err
+	}
+	return aerc.PickFolder(acct, func(folder string) {
+		args = append(args, folder)
+		if err := cmd.Execute(aerc, args); err != nil {
+			aerc.PushError(err.Error())
+		}
+	})
+}
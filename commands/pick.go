@@ -0,0 +1,255 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"git.sr.ht/~rjarry/aerc/lib/format"
+	"git.sr.ht/~rjarry/aerc/models"
+	"git.sr.ht/~rjarry/aerc/widgets"
+)
+
+type Pick struct{}
+
+var pickSources = []string{
+	"folder", "message", "tab", "command", "attachment", "link",
+}
+
+func init() {
+	register(Pick{})
+}
+
+func (Pick) Aliases() []string {
+	return []string{"pick"}
+}
+
+func (Pick) Complete(aerc *widgets.Aerc, args []string) []string {
+	return CompletionFromList(aerc, pickSources, args)
+}
+
+func (Pick) Execute(aerc *widgets.Aerc, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("Usage: %s %s", args[0],
+			strings.Join(pickSources, "|"))
+	}
+	var (
+		items   []*widgets.PickerItem
+		multi   bool
+		preview func(*widgets.PickerItem) []string
+		action  func([]*widgets.PickerItem) error
+		err     error
+	)
+	switch args[1] {
+	case "folder":
+		items, preview, action, err = pickFolder(aerc)
+	case "message":
+		multi = true
+		items, preview, action, err = pickMessage(aerc)
+	case "tab":
+		items, action = pickTab(aerc)
+	case "command":
+		items, action = pickCommand(aerc)
+	case "attachment":
+		items, action, err = pickAttachment(aerc)
+	case "link":
+		multi = true
+		items, action, err = pickLink(aerc)
+	default:
+		return fmt.Errorf("Unknown source: %s", args[1])
+	}
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return fmt.Errorf("No %s to pick from", args[1])
+	}
+
+	picker := widgets.NewPicker(
+		fmt.Sprintf("Pick %s", args[1]), items, multi,
+		aerc.SelectedAccountUiConfig(), preview,
+		func(selected []*widgets.PickerItem) {
+			aerc.CloseDialog()
+			if len(selected) == 0 {
+				return
+			}
+			if err := action(selected); err != nil {
+				aerc.PushError(err.Error())
+			}
+		},
+	)
+	aerc.AddDialog(widgets.NewDialog(picker,
+		func(h int) int { return h / 8 },
+		func(h int) int { return h * 3 / 4 },
+	))
+	return nil
+}
+
+func pickFolder(aerc *widgets.Aerc) (
+	[]*widgets.PickerItem, func(*widgets.PickerItem) []string,
+	func([]*widgets.PickerItem) error, error,
+) {
+	acct := aerc.SelectedAccount()
+	if acct == nil {
+		return nil, nil, nil, errors.New("No account selected")
+	}
+	var items []*widgets.PickerItem
+	for _, dir := range acct.Directories().List() {
+		items = append(items, &widgets.PickerItem{Text: dir, Value: dir})
+	}
+	preview := func(item *widgets.PickerItem) []string {
+		store, ok := acct.Directories().MsgStore(item.Value.(string))
+		if !ok {
+			return []string{item.Text}
+		}
+		return []string{
+			item.Text,
+			"",
+			fmt.Sprintf("Messages: %d", store.DirInfo.Exists),
+			fmt.Sprintf("Unread:   %d", store.DirInfo.Unseen),
+			fmt.Sprintf("Recent:   %d", store.DirInfo.Recent),
+		}
+	}
+	action := func(selected []*widgets.PickerItem) error {
+		return aerc.RunCommand([]string{"cf", selected[0].Value.(string)})
+	}
+	return items, preview, action, nil
+}
+
+func pickMessage(aerc *widgets.Aerc) (
+	[]*widgets.PickerItem, func(*widgets.PickerItem) []string,
+	func([]*widgets.PickerItem) error, error,
+) {
+	acct := aerc.SelectedAccount()
+	if acct == nil {
+		return nil, nil, nil, errors.New("No account selected")
+	}
+	store := acct.Store()
+	if store == nil {
+		return nil, nil, nil, errors.New("Cannot perform action. Messages still loading")
+	}
+	var items []*widgets.PickerItem
+	for _, uid := range store.Uids() {
+		msg, ok := store.Messages[uid]
+		if !ok || msg == nil || msg.Envelope == nil {
+			continue
+		}
+		var from string
+		if len(msg.Envelope.From) > 0 {
+			from = format.AddressForHumans(msg.Envelope.From[0])
+		}
+		items = append(items, &widgets.PickerItem{
+			Text:  fmt.Sprintf("%s  %s", msg.Envelope.Subject, from),
+			Value: msg,
+		})
+	}
+	preview := func(item *widgets.PickerItem) []string {
+		msg := item.Value.(*models.MessageInfo)
+		lines := []string{
+			"From:    " + format.FormatAddresses(msg.Envelope.From),
+			"To:      " + format.FormatAddresses(msg.Envelope.To),
+			"Date:    " + msg.Envelope.Date.Format(
+				acct.UiConfig().TimestampFormat),
+			"Subject: " + msg.Envelope.Subject,
+		}
+		if len(msg.Labels) > 0 {
+			lines = append(lines,
+				"Labels:  "+strings.Join(msg.Labels, ", "))
+		}
+		return lines
+	}
+	action := func(selected []*widgets.PickerItem) error {
+		if len(selected) == 1 {
+			store.Select(selected[0].Value.(*models.MessageInfo).Uid)
+			return nil
+		}
+		marker := store.Marker()
+		for _, item := range selected {
+			marker.Mark(item.Value.(*models.MessageInfo).Uid)
+		}
+		acct.Invalidate()
+		return nil
+	}
+	return items, preview, action, nil
+}
+
+func pickTab(aerc *widgets.Aerc) (
+	[]*widgets.PickerItem, func([]*widgets.PickerItem) error,
+) {
+	var items []*widgets.PickerItem
+	for _, name := range aerc.TabNames() {
+		items = append(items, &widgets.PickerItem{Text: name, Value: name})
+	}
+	action := func(selected []*widgets.PickerItem) error {
+		if !aerc.SelectTab(selected[0].Value.(string)) {
+			return errors.New("No tab with that name")
+		}
+		return nil
+	}
+	return items, action
+}
+
+func pickCommand(aerc *widgets.Aerc) (
+	[]*widgets.PickerItem, func([]*widgets.PickerItem) error,
+) {
+	var items []*widgets.PickerItem
+	for _, name := range aerc.CompleteCommand("") {
+		items = append(items, &widgets.PickerItem{Text: name, Value: name})
+	}
+	action := func(selected []*widgets.PickerItem) error {
+		aerc.BeginExCommand(selected[0].Value.(string) + " ")
+		return nil
+	}
+	return items, action
+}
+
+func pickAttachment(aerc *widgets.Aerc) (
+	[]*widgets.PickerItem, func([]*widgets.PickerItem) error, error,
+) {
+	mv, ok := aerc.SelectedTabContent().(*widgets.MessageViewer)
+	if !ok {
+		return nil, nil, errors.New("No message viewer selected")
+	}
+	var items []*widgets.PickerItem
+	for _, part := range mv.AttachmentParts() {
+		name := part.Part.FileName()
+		if name == "" {
+			name = "(unnamed)"
+		}
+		items = append(items, &widgets.PickerItem{
+			Text: fmt.Sprintf("%s  %s/%s", name,
+				part.Part.MIMEType, part.Part.MIMESubType),
+			Value: part,
+		})
+	}
+	action := func(selected []*widgets.PickerItem) error {
+		mv.SelectPart(selected[0].Value.(*widgets.PartInfo).Index)
+		return nil
+	}
+	return items, action, nil
+}
+
+func pickLink(aerc *widgets.Aerc) (
+	[]*widgets.PickerItem, func([]*widgets.PickerItem) error, error,
+) {
+	mv, ok := aerc.SelectedTabContent().(*widgets.MessageViewer)
+	if !ok {
+		return nil, nil, errors.New("No message viewer selected")
+	}
+	var items []*widgets.PickerItem
+	if part := mv.SelectedMessagePart(); part != nil {
+		for _, link := range part.Links {
+			items = append(items, &widgets.PickerItem{Text: link, Value: link})
+		}
+	}
+	action := func(selected []*widgets.PickerItem) error {
+		for _, item := range selected {
+			err := aerc.RunCommand([]string{"open-link", item.Value.(string)})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return items, action, nil
+}
@@ -0,0 +1,63 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+
+	"git.sr.ht/~rjarry/aerc/widgets"
+	"github.com/mitchellh/go-homedir"
+)
+
+type Session struct{}
+
+func init() {
+	register(Session{})
+}
+
+func (Session) Aliases() []string {
+	return []string{"session"}
+}
+
+func (Session) Complete(aerc *widgets.Aerc, args []string) []string {
+	switch len(args) {
+	case 0:
+		return []string{"save", "load"}
+	case 1:
+		return CompletionFromList(aerc, []string{"save", "load"}, args)
+	default:
+		var completions []string
+		for _, file := range CompletePath(args[1]) {
+			completions = append(completions,
+				fmt.Sprintf("%s %s", args[0], file))
+		}
+		return completions
+	}
+}
+
+func (Session) Execute(aerc *widgets.Aerc, args []string) error {
+	if len(args) < 2 || len(args) > 3 {
+		return errors.New("Usage: session save|load [<file>]")
+	}
+	file := widgets.SessionFile()
+	if len(args) == 3 {
+		var err error
+		file, err = homedir.Expand(args[2])
+		if err != nil {
+			return err
+		}
+	}
+	switch args[1] {
+	case "save":
+		if err := aerc.SaveSession(file); err != nil {
+			return err
+		}
+		aerc.PushSuccess("Session saved to " + file)
+	case "load":
+		if err := aerc.LoadSession(file); err != nil {
+			return err
+		}
+	default:
+		return errors.New("Usage: session save|load [<file>]")
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"git.sr.ht/~rjarry/aerc/config"
+	"git.sr.ht/~rjarry/aerc/widgets"
+)
+
+type Sidebar struct{}
+
+func init() {
+	register(Sidebar{})
+}
+
+func (Sidebar) Aliases() []string {
+	return []string{"sidebar-next", "sidebar-prev", "sidebar-toggle"}
+}
+
+func (Sidebar) Complete(aerc *widgets.Aerc, args []string) []string {
+	return nil
+}
+
+func (Sidebar) Execute(aerc *widgets.Aerc, args []string) error {
+	if !config.Ui.GlobalSidebar {
+		return errors.New("global-sidebar is not enabled")
+	}
+	if args[0] == "sidebar-toggle" {
+		if len(args) > 1 {
+			return errors.New("Usage: sidebar-toggle")
+		}
+		aerc.Sidebar().Toggle()
+		return nil
+	}
+	if len(args) > 2 {
+		return sidebarUsage(args[0])
+	}
+	var (
+		n   int = 1
+		err error
+	)
+	if len(args) > 1 {
+		n, err = strconv.Atoi(args[1])
+		if err != nil {
+			return sidebarUsage(args[0])
+		}
+	}
+	if args[0] == "sidebar-prev" {
+		n = -n
+	}
+	aerc.Sidebar().NextPrev(n)
+	aerc.UpdateStatus()
+	return nil
+}
+
+func sidebarUsage(cmd string) error {
+	return fmt.Errorf("Usage: %s [n]", cmd)
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/lithammer/fuzzysearch/fuzzy"
 
 	"git.sr.ht/~rjarry/aerc/lib"
+	"git.sr.ht/~rjarry/aerc/lib/templates"
 	"git.sr.ht/~rjarry/aerc/log"
 	"git.sr.ht/~rjarry/aerc/models"
 	"git.sr.ht/~rjarry/aerc/widgets"
@@ -171,7 +172,8 @@ func listDir(path string, hidden bool) []string {
 }
 
 // MarkedOrSelected returns either all marked messages if any are marked or the
-// selected message instead
+// selected message instead. In the message list, a folded message comes with
+// its hidden replies.
 func MarkedOrSelected(pm widgets.ProvidesMessages) ([]uint32, error) {
 	// marked has priority over the selected message
 	marked, err := pm.MarkedMessages()
@@ -185,6 +187,12 @@ func MarkedOrSelected(pm widgets.ProvidesMessages) ([]uint32, error) {
 	if err != nil {
 		return nil, err
 	}
+	switch pm.(type) {
+	case *widgets.AccountView, *widgets.Layout:
+		if pm.Store() != nil {
+			return pm.Store().FoldedUids(msg.Uid), nil
+		}
+	}
 	return []uint32{msg.Uid}, nil
 }
 
@@ -230,6 +238,23 @@ func MsgInfoFromUids(store *lib.MessageStore, uids []uint32, statusInfo func(str
 	return infos, nil
 }
 
+// accountTemplateData returns template data initialized with the account
+// and ui configuration.
+func accountTemplateData(acct *widgets.AccountView) *templates.TemplateData {
+	uiConfig := acct.UiConfig()
+	return templates.NewTemplateData(
+		acct.AccountConfig().From,
+		acct.AccountConfig().Aliases,
+		acct.Name(),
+		acct.Directories().Selected(),
+		uiConfig.TimestampFormat,
+		uiConfig.ThisDayTimeFormat,
+		uiConfig.ThisWeekTimeFormat,
+		uiConfig.ThisYearTimeFormat,
+		uiConfig.IconAttachment,
+	)
+}
+
 // FilterList takes a list of valid completions and filters it, either
 // by case smart prefix, or by fuzzy matching, prepending "prefix" to each completion
 func FilterList(valid []string, search, prefix string, isFuzzy bool) []string {
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"git.sr.ht/~rjarry/aerc/log"
+	"github.com/go-ini/ini"
+)
+
+type AliasConfig struct {
+	Name    string
+	Command string
+}
+
+var Aliases []*AliasConfig
+
+func parseAliases(file *ini.File) error {
+	aliases, err := file.GetSection("aliases")
+	if err != nil {
+		goto out
+	}
+
+	for _, key := range aliases.Keys() {
+		name := key.Name()
+		if name == "" || strings.ContainsAny(name, " \t\"'") {
+			return fmt.Errorf("aliases: invalid alias name %q", name)
+		}
+		if strings.TrimSpace(key.Value()) == "" {
+			return fmt.Errorf("aliases: %s: empty command", name)
+		}
+		Aliases = append(Aliases, &AliasConfig{
+			Name:    name,
+			Command: key.Value(),
+		})
+	}
+
+out:
+	log.Debugf("aerc.conf: [aliases] %#v", Aliases)
+	return nil
+}
@@ -22,6 +22,7 @@ type BindingConfig struct {
 	ComposeEditor          *KeyBindings
 	ComposeReview          *KeyBindings
 	MessageList            *KeyBindings
+	MessageListLayout      *KeyBindings
 	MessageView            *KeyBindings
 	MessageViewPassthrough *KeyBindings
 	Terminal               *KeyBindings
@@ -90,6 +91,7 @@ func defaultBindsConfig() *BindingConfig {
 		ComposeEditor:          NewKeyBindings(),
 		ComposeReview:          NewKeyBindings(),
 		MessageList:            NewKeyBindings(),
+		MessageListLayout:      NewKeyBindings(),
 		MessageView:            NewKeyBindings(),
 		MessageViewPassthrough: NewKeyBindings(),
 		Terminal:               NewKeyBindings(),
@@ -116,6 +118,7 @@ func parseBinds(root string) error {
 		"default":           &Binds.Global,
 		"compose":           &Binds.Compose,
 		"messages":          &Binds.MessageList,
+		"messages::layout":  &Binds.MessageListLayout,
 		"terminal":          &Binds.Terminal,
 		"view":              &Binds.MessageView,
 		"view::passthrough": &Binds.MessageViewPassthrough,
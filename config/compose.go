@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"regexp"
+	"time"
 
 	"git.sr.ht/~rjarry/aerc/log"
 	"github.com/go-ini/ini"
@@ -15,6 +16,7 @@ type ComposeConfig struct {
 	ReplyToSelf         bool           `ini:"reply-to-self"`
 	NoAttachmentWarning *regexp.Regexp `ini:"-"`
 	FilePickerCmd       string         `ini:"file-picker-cmd"`
+	AutosaveInterval    time.Duration  `ini:"autosave-interval"`
 }
 
 func defaultComposeConfig() *ComposeConfig {
@@ -23,7 +25,8 @@ func defaultComposeConfig() *ComposeConfig {
 			{"To", "From"},
 			{"Subject"},
 		},
-		ReplyToSelf: true,
+		ReplyToSelf:      true,
+		AutosaveInterval: 30 * time.Second,
 	}
 }
 
@@ -150,6 +150,12 @@ func LoadConfigFromFile(root *string, accts []string) error {
 	if err := parseTriggers(file); err != nil {
 		return err
 	}
+	if err := parseAliases(file); err != nil {
+		return err
+	}
+	if err := parseLayouts(file); err != nil {
+		return err
+	}
 	if err := parseUi(file); err != nil {
 		return err
 	}
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"git.sr.ht/~rjarry/aerc/log"
 	"github.com/go-ini/ini"
@@ -11,18 +12,22 @@ import (
 )
 
 type GeneralConfig struct {
-	DefaultSavePath    string       `ini:"default-save-path"`
-	PgpProvider        string       `ini:"pgp-provider"`
-	UnsafeAccountsConf bool         `ini:"unsafe-accounts-conf"`
-	LogFile            string       `ini:"log-file"`
-	LogLevel           log.LogLevel `ini:"-"`
+	DefaultSavePath     string        `ini:"default-save-path"`
+	PgpProvider         string        `ini:"pgp-provider"`
+	UnsafeAccountsConf  bool          `ini:"unsafe-accounts-conf"`
+	LogFile             string        `ini:"log-file"`
+	LogLevel            log.LogLevel  `ini:"-"`
+	LuaScript           string        `ini:"lua-script"`
+	RestoreSession      bool          `ini:"restore-session"`
+	SessionSaveInterval time.Duration `ini:"session-save-interval"`
 }
 
 func defaultGeneralConfig() *GeneralConfig {
 	return &GeneralConfig{
-		PgpProvider:        "auto",
-		UnsafeAccountsConf: false,
-		LogLevel:           log.INFO,
+		PgpProvider:         "auto",
+		UnsafeAccountsConf:  false,
+		LogLevel:            log.INFO,
+		SessionSaveInterval: time.Minute,
 	}
 }
 
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"git.sr.ht/~rjarry/aerc/log"
+	"github.com/go-ini/ini"
+)
+
+// PaneConfig is a message list displayed in a layout
+type PaneConfig struct {
+	// Empty for the account of the tab from which the layout is opened
+	Account string
+	Folder  string
+}
+
+type LayoutConfig struct {
+	Name string
+	Rows [][]*PaneConfig
+}
+
+var Layouts []*LayoutConfig
+
+func parseLayouts(file *ini.File) error {
+	layouts, err := file.GetSection("layouts")
+	if err != nil {
+		goto out
+	}
+
+	for _, key := range layouts.Keys() {
+		name := key.Name()
+		if name == "" || strings.ContainsAny(name, " \t\"'") {
+			return fmt.Errorf("layouts: invalid layout name %q", name)
+		}
+		rows, err := ParsePanes(key.Value())
+		if err != nil {
+			return fmt.Errorf("layouts: %s: %w", name, err)
+		}
+		Layouts = append(Layouts, &LayoutConfig{
+			Name: name,
+			Rows: rows,
+		})
+	}
+
+out:
+	log.Debugf("aerc.conf: [layouts] %#v", Layouts)
+	return nil
+}
+
+// ParsePanes parses a comma separated list of rows, each made of panes
+// separated by pipes. A pane is a folder name, optionally prefixed by an
+// account name and a colon.
+func ParsePanes(value string) ([][]*PaneConfig, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, fmt.Errorf("no panes")
+	}
+	var rows [][]*PaneConfig
+	for _, r := range parseLayout(value) {
+		var row []*PaneConfig
+		for _, p := range r {
+			pane := &PaneConfig{Folder: strings.TrimSpace(p)}
+			if i := strings.Index(pane.Folder, ":"); i >= 0 {
+				pane.Account = strings.TrimSpace(pane.Folder[:i])
+				pane.Folder = strings.TrimSpace(pane.Folder[i+1:])
+			}
+			if pane.Folder == "" {
+				return nil, fmt.Errorf("%q: empty folder name", p)
+			}
+			row = append(row, pane)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// FindLayout returns the layout with the given name or nil if none is
+// defined.
+func FindLayout(name string) *LayoutConfig {
+	for _, l := range Layouts {
+		if l.Name == name {
+			return l
+		}
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePanes(t *testing.T) {
+	rows, err := ParsePanes("INBOX | Sent, work:INBOX|work:Lists:aerc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, [][]*PaneConfig{
+		{
+			{Folder: "INBOX"},
+			{Folder: "Sent"},
+		},
+		{
+			{Account: "work", Folder: "INBOX"},
+			{Account: "work", Folder: "Lists:aerc"},
+		},
+	}, rows)
+
+	_, err = ParsePanes("INBOX|")
+	assert.NotNil(t, err)
+	_, err = ParsePanes("work:")
+	assert.NotNil(t, err)
+	_, err = ParsePanes(" ")
+	assert.NotNil(t, err)
+}
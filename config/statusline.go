@@ -1,21 +1,43 @@
 package config
 
 import (
+	"fmt"
+	"strings"
+
+	"git.sr.ht/~rjarry/aerc/lib/templates"
 	"git.sr.ht/~rjarry/aerc/log"
 	"github.com/go-ini/ini"
 )
 
 type StatuslineConfig struct {
+	StatusColumns   []*ColumnDef `ini:"-"`
+	ColumnSeparator string       `ini:"column-separator"`
+	Separator       string
+	DisplayMode     string `ini:"display-mode"`
+	// deprecated
 	RenderFormat string `ini:"render-format"`
-	Separator    string
-	DisplayMode  string `ini:"display-mode"`
 }
 
 func defaultStatuslineConfig() *StatuslineConfig {
+	left, _ := templates.ParseTemplate("column-left",
+		"[{{.Account}}] {{.StatusInfo}}")
+	right, _ := templates.ParseTemplate("column-right", "{{.TrayInfo}}")
 	return &StatuslineConfig{
-		RenderFormat: "[%a] %S %>%T",
-		Separator:    " | ",
-		DisplayMode:  "",
+		StatusColumns: []*ColumnDef{
+			{
+				Name:     "left",
+				Flags:    ALIGN_LEFT | WIDTH_AUTO,
+				Template: left,
+			},
+			{
+				Name:     "right",
+				Flags:    ALIGN_RIGHT | WIDTH_FIT,
+				Template: right,
+			},
+		},
+		ColumnSeparator: " ",
+		Separator:       " | ",
+		DisplayMode:     "",
 	}
 }
 
@@ -29,7 +51,92 @@ func parseStatusline(file *ini.File) error {
 	if err := statusline.MapTo(&Statusline); err != nil {
 		return err
 	}
+
+	if Statusline.RenderFormat != "" {
+		log.Warnf("%s %s",
+			"The render-format setting has been replaced by status-columns.",
+			"render-format will be removed in aerc 0.17.")
+	}
+	if key, err := statusline.GetKey("status-columns"); err == nil {
+		// an empty value mutes the statusline
+		columns := []*ColumnDef{}
+		if key.String() != "" {
+			columns, err = ParseColumnDefs(key, statusline)
+			if err != nil {
+				return err
+			}
+		}
+		Statusline.StatusColumns = columns
+		Statusline.RenderFormat = "" // to silence popup at startup
+	} else if Statusline.RenderFormat != "" {
+		columns, err := convertRenderFormat(Statusline.RenderFormat)
+		if err != nil {
+			return err
+		}
+		Statusline.StatusColumns = columns
+	}
 out:
 	log.Debugf("aerc.conf: [statusline] %#v", Statusline)
 	return nil
 }
+
+// convertRenderFormat translates a render-format string to a left column
+// and a right column for everything that follows %>.
+func convertRenderFormat(renderFormat string) ([]*ColumnDef, error) {
+	var columns []*ColumnDef
+	for i, part := range strings.SplitN(renderFormat, "%>", 2) {
+		var f strings.Builder
+		mute := false
+		for j := 0; j < len(part); j++ {
+			if part[j] != '%' || j == len(part)-1 {
+				f.WriteByte(part[j])
+				continue
+			}
+			j++
+			// fmt flags, width and precision are ignored
+			for j < len(part)-1 && strings.IndexByte("+-# 0123456789.", part[j]) >= 0 {
+				j++
+			}
+			switch part[j] {
+			case '%':
+				f.WriteByte('%')
+			case 'a':
+				f.WriteString("{{.Account}}")
+			case 'c':
+				f.WriteString("{{.ConnectionInfo}}")
+			case 'd':
+				f.WriteString("{{.Folder}}")
+			case 'm':
+				mute = true
+			case 'p':
+				f.WriteString("{{cwd}}")
+			case 'S':
+				f.WriteString("{{.StatusInfo}}")
+			case 'T':
+				f.WriteString("{{.TrayInfo}}")
+			default:
+				f.WriteByte('%')
+				f.WriteByte(part[j])
+			}
+		}
+		if mute {
+			f.Reset()
+		}
+
+		name, flags := "left", ALIGN_LEFT|WIDTH_AUTO
+		if i > 0 {
+			name, flags = "right", ALIGN_RIGHT|WIDTH_FIT
+		}
+		t, err := templates.ParseTemplate(fmt.Sprintf("column-%s", name),
+			strings.TrimSpace(f.String()))
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, &ColumnDef{
+			Name:     name,
+			Flags:    flags,
+			Template: t,
+		})
+	}
+	return columns, nil
+}
@@ -0,0 +1,44 @@
+package config
+
+import (
+	"bytes"
+	"testing"
+
+	"git.sr.ht/~rjarry/aerc/lib/templates"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertRenderFormat(t *testing.T) {
+	columns, err := convertRenderFormat("[%a] %-10S %>%T %%")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, columns, 2)
+
+	data := templates.DummyData()
+	data.SetStatus(&templates.Status{
+		StatusInfo: "Connected",
+		TrayInfo:   "threading",
+	})
+	var buf bytes.Buffer
+
+	assert.Equal(t, "left", columns[0].Name)
+	assert.Equal(t, ALIGN_LEFT|WIDTH_AUTO, columns[0].Flags)
+	assert.Nil(t, columns[0].Template.Execute(&buf, data))
+	assert.Equal(t, "[account] Connected", buf.String())
+
+	buf.Reset()
+	assert.Equal(t, "right", columns[1].Name)
+	assert.Equal(t, ALIGN_RIGHT|WIDTH_FIT, columns[1].Flags)
+	assert.Nil(t, columns[1].Template.Execute(&buf, data))
+	assert.Equal(t, "threading %", buf.String())
+
+	columns, err = convertRenderFormat("%m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, columns, 1)
+	buf.Reset()
+	assert.Nil(t, columns[0].Template.Execute(&buf, data))
+	assert.Equal(t, "", buf.String())
+}
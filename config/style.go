@@ -9,9 +9,13 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/emersion/go-message/mail"
+	"github.com/emersion/go-msgauth/authres"
 	"github.com/gdamore/tcell/v2"
 	"github.com/go-ini/ini"
 	"github.com/mitchellh/go-homedir"
+
+	"git.sr.ht/~rjarry/aerc/models"
 )
 
 type StyleObject int32
@@ -38,6 +42,7 @@ const (
 	STYLE_MSGLIST_MARKED
 	STYLE_MSGLIST_RESULT
 	STYLE_MSGLIST_ANSWERED
+	STYLE_MSGLIST_WATCHED
 
 	STYLE_DIRLIST_DEFAULT
 	STYLE_DIRLIST_UNREAD
@@ -79,6 +84,7 @@ var StyleNames = map[string]StyleObject{
 	"msglist_marked":   STYLE_MSGLIST_MARKED,
 	"msglist_result":   STYLE_MSGLIST_RESULT,
 	"msglist_answered": STYLE_MSGLIST_ANSWERED,
+	"msglist_watched":  STYLE_MSGLIST_WATCHED,
 
 	"dirlist_default": STYLE_DIRLIST_DEFAULT,
 	"dirlist_unread":  STYLE_DIRLIST_UNREAD,
@@ -239,15 +245,171 @@ func (s Style) composeWith(styles []*Style) Style {
 	return newStyle
 }
 
+// labelsHeader is the pseudo header used in conditional styles to match the
+// labels of a message.
+const labelsHeader = "Labels"
+
+// authresPrefix starts the pseudo headers used in conditional styles to match
+// the DKIM, SPF and DMARC results of a message, e.g. Authres-DKIM.
+const authresPrefix = "Authres-"
+
+// authResults returns the results of the given authentication method found
+// in the topmost Authentication-Results header, which is the one added by the
+// receiving server. It returns "none" if the method is not found.
+func authResults(h *mail.Header, method string) []string {
+	text, err := h.Text("Authentication-Results")
+	if err != nil || text == "" {
+		return []string{string(authres.ResultNone)}
+	}
+	_, results, err := authres.Parse(text)
+	if err != nil {
+		// some servers omit the authserv-id
+		_, results, err = authres.Parse("unknown;" + text)
+		if err != nil {
+			return []string{string(authres.ResultNone)}
+		}
+	}
+	var values []string
+	for _, result := range results {
+		var value authres.ResultValue
+		switch r := result.(type) {
+		case *authres.DKIMResult:
+			if !strings.EqualFold(method, "dkim") {
+				continue
+			}
+			value = r.Value
+		case *authres.SPFResult:
+			if !strings.EqualFold(method, "spf") {
+				continue
+			}
+			value = r.Value
+		case *authres.DMARCResult:
+			if !strings.EqualFold(method, "dmarc") {
+				continue
+			}
+			value = r.Value
+		default:
+			continue
+		}
+		values = append(values, string(value))
+	}
+	if len(values) == 0 {
+		values = append(values, string(authres.ResultNone))
+	}
+	return values
+}
+
+// A conditionalStyle is applied on top of its style object when a header of
+// the message matches. The value is either compared literally to the header
+// or, when it starts with a tilde, used as a regular expression.
+type conditionalStyle struct {
+	header   string
+	value    string
+	re       *regexp.Regexp
+	style    Style
+	selected Style
+}
+
+func newConditionalStyle(header, value string) (*conditionalStyle, error) {
+	cs := &conditionalStyle{header: header, value: value}
+	if strings.HasPrefix(value, "~") {
+		re, err := regexp.Compile(value[1:])
+		if err != nil {
+			return nil, err
+		}
+		cs.re = re
+	}
+	cs.style.Reset()
+	cs.selected.Reset()
+	return cs, nil
+}
+
+func (cs *conditionalStyle) match(s string) bool {
+	if cs.re != nil {
+		return cs.re.MatchString(s)
+	}
+	return s == cs.value
+}
+
+// Matches returns true if a value of the header of msg matches the style.
+func (cs *conditionalStyle) Matches(msg *models.MessageInfo) bool {
+	if msg == nil {
+		return false
+	}
+	if strings.EqualFold(cs.header, labelsHeader) {
+		for _, label := range msg.Labels {
+			if cs.match(label) {
+				return true
+			}
+		}
+		return false
+	}
+	if msg.RFC822Headers == nil {
+		return false
+	}
+	if len(cs.header) > len(authresPrefix) &&
+		strings.EqualFold(cs.header[:len(authresPrefix)], authresPrefix) {
+		method := cs.header[len(authresPrefix):]
+		for _, result := range authResults(msg.RFC822Headers, method) {
+			if cs.match(result) {
+				return true
+			}
+		}
+		return false
+	}
+	fields := msg.RFC822Headers.FieldsByKey(cs.header)
+	for fields.Next() {
+		value, err := fields.Text()
+		if err != nil {
+			value = fields.Value()
+		}
+		if cs.match(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// The style objects of the message list and statusline columns are made of
+// these prefixes followed by the column name.
+const (
+	msglistColumnPrefix    = "msglist_column_"
+	statuslineColumnPrefix = "statusline_column_"
+)
+
+// columnStyleBases maps the column style prefixes to the style object the
+// column styles are layered on.
+var columnStyleBases = map[string]StyleObject{
+	msglistColumnPrefix:    STYLE_MSGLIST_DEFAULT,
+	statuslineColumnPrefix: STYLE_STATUSLINE_DEFAULT,
+}
+
+func columnStyleBase(key string) (StyleObject, bool) {
+	for prefix, so := range columnStyleBases {
+		if strings.HasPrefix(key, prefix) {
+			return so, true
+		}
+	}
+	return 0, false
+}
+
 type StyleSet struct {
-	objects  map[StyleObject]*Style
-	selected map[StyleObject]*Style
+	objects     map[StyleObject]*Style
+	selected    map[StyleObject]*Style
+	conditional map[StyleObject][]*conditionalStyle
+	// styles of the message list and statusline columns, by style
+	// object name
+	columns         map[string]*Style
+	selectedColumns map[string]*Style
 }
 
 func NewStyleSet() StyleSet {
 	ss := StyleSet{
-		objects:  make(map[StyleObject]*Style),
-		selected: make(map[StyleObject]*Style),
+		objects:         make(map[StyleObject]*Style),
+		selected:        make(map[StyleObject]*Style),
+		conditional:     make(map[StyleObject][]*conditionalStyle),
+		columns:         make(map[string]*Style),
+		selectedColumns: make(map[string]*Style),
 	}
 	for _, so := range StyleNames {
 		ss.objects[so] = new(Style)
@@ -261,13 +423,68 @@ func (ss StyleSet) reset() {
 	for _, so := range StyleNames {
 		ss.objects[so].Reset()
 		ss.selected[so].Reset()
+		delete(ss.conditional, so)
+	}
+	for name := range ss.columns {
+		delete(ss.columns, name)
+		delete(ss.selectedColumns, name)
+	}
+}
+
+// parseColumns sets the styles of the columns. They start as a copy of the
+// style object they are layered on so that only the attributes which are set
+// in keys override the other layers.
+func (ss StyleSet) parseColumns(section *ini.Section, keys []string) error {
+	for _, selected := range []bool{false, true} {
+		for _, key := range keys {
+			tokens := strings.Split(key, ".")
+			switch {
+			case len(tokens) == 3 && tokens[1] != "selected":
+				return errors.New("Unknown modifier: " + tokens[1])
+			case len(tokens) != 2 && len(tokens) != 3:
+				return errors.New("Style parsing error: " + key)
+			}
+			name := tokens[0]
+			if _, ok := ss.columns[name]; !ok {
+				base, _ := columnStyleBase(name)
+				def := *ss.objects[base]
+				ss.columns[name] = &def
+				sel := *ss.selected[base]
+				ss.selectedColumns[name] = &sel
+			}
+			if (len(tokens) == 3) != selected {
+				continue
+			}
+			val := section.KeysHash()[key]
+			attr := tokens[len(tokens)-1]
+			if !selected {
+				if err := ss.columns[name].Set(attr, val); err != nil {
+					return err
+				}
+			}
+			if err := ss.selectedColumns[name].Set(attr, val); err != nil {
+				return err
+			}
+		}
 	}
+	return nil
 }
 
 func (ss StyleSet) Get(so StyleObject) tcell.Style {
 	return ss.objects[so].Get()
 }
 
+// StatuslineColumn returns the style of a statusline column, layered on top
+// of statusline_default.
+func (ss StyleSet) StatuslineColumn(column string) tcell.Style {
+	base := ss.objects[STYLE_STATUSLINE_DEFAULT]
+	st, ok := ss.columns[statuslineColumnPrefix+column]
+	if !ok {
+		return base.Get()
+	}
+	return base.composeWith([]*Style{st}).Get()
+}
+
 func (ss StyleSet) Selected(so StyleObject) tcell.Style {
 	return ss.selected[so].Get()
 }
@@ -294,6 +511,110 @@ func (ss StyleSet) ComposeSelected(so StyleObject,
 	return base.composeWith(styles).Get()
 }
 
+// ComposeMessage is like Compose but the conditional styles of each style
+// object which match msg are applied after it. If column is not empty, the
+// style of that message list column is applied after the base style.
+func (ss StyleSet) ComposeMessage(so StyleObject, sos []StyleObject,
+	msg *models.MessageInfo, column string,
+) tcell.Style {
+	return ss.composeMessage(so, sos, msg, column, false)
+}
+
+// ComposeMessageSelected is like ComposeMessage for the selected message.
+func (ss StyleSet) ComposeMessageSelected(so StyleObject, sos []StyleObject,
+	msg *models.MessageInfo, column string,
+) tcell.Style {
+	return ss.composeMessage(so, sos, msg, column, true)
+}
+
+func (ss StyleSet) composeMessage(so StyleObject, sos []StyleObject,
+	msg *models.MessageInfo, column string, selected bool,
+) tcell.Style {
+	objects, columns := ss.objects, ss.columns
+	if selected {
+		objects, columns = ss.selected, ss.selectedColumns
+	}
+	base := *objects[so]
+	var styles []*Style
+	for i, o := range append([]StyleObject{so}, sos...) {
+		if i > 0 {
+			styles = append(styles, objects[o])
+		} else if st, ok := columns[msglistColumnPrefix+column]; ok {
+			styles = append(styles, st)
+		}
+		for _, cs := range ss.conditional[o] {
+			if !cs.Matches(msg) {
+				continue
+			}
+			if selected {
+				styles = append(styles, &cs.selected)
+			} else {
+				styles = append(styles, &cs.style)
+			}
+		}
+	}
+
+	return base.composeWith(styles).Get()
+}
+
+// conditionalKeyRe matches the keys of conditional styles, e.g.
+// msglist_*.From,~@example\.org$.selected.fg
+var conditionalKeyRe = regexp.MustCompile(
+	`^([\w\*\?]+)\.([\w-]+),(.+?)(\.selected)?\.(\w+)$`)
+
+// parseConditional sets an attribute of the conditional styles of the
+// msglist objects matching the key. If the selected modifier is not given,
+// the attribute also applies to selected messages.
+func (ss StyleSet) parseConditional(key, val string) error {
+	m := conditionalKeyRe.FindStringSubmatch(key)
+	if m == nil {
+		return errors.New("Style parsing error: " + key)
+	}
+	styleName, header, value, selected, attr := m[1], m[2], m[3], m[4] != "", m[5]
+
+	regex := "^" + fnmatchToRegex(styleName) + "$"
+	found := false
+	for sn, so := range StyleNames {
+		if !strings.HasPrefix(sn, "msglist_") {
+			continue
+		}
+		if matched, err := regexp.MatchString(regex, sn); err != nil {
+			return err
+		} else if !matched {
+			continue
+		}
+		found = true
+
+		var cs *conditionalStyle
+		for _, c := range ss.conditional[so] {
+			if c.header == header && c.value == value {
+				cs = c
+				break
+			}
+		}
+		if cs == nil {
+			var err error
+			cs, err = newConditionalStyle(header, value)
+			if err != nil {
+				return err
+			}
+			ss.conditional[so] = append(ss.conditional[so], cs)
+		}
+		if !selected {
+			if err := cs.style.Set(attr, val); err != nil {
+				return err
+			}
+		}
+		if err := cs.selected.Set(attr, val); err != nil {
+			return err
+		}
+	}
+	if !found {
+		return errors.New("Conditional styles only apply to msglist objects: " + key)
+	}
+	return nil
+}
+
 func findStyleSet(stylesetName string, stylesetsDir []string) (string, error) {
 	for _, dir := range stylesetsDir {
 		stylesetPath, err := homedir.Expand(path.Join(dir, stylesetName))
@@ -321,8 +642,18 @@ func (ss *StyleSet) ParseStyleSet(file *ini.File) error {
 	}
 
 	selectedKeys := []string{}
+	conditionalKeys := []string{}
+	columnKeys := []string{}
 
 	for _, key := range defaultSection.KeyStrings() {
+		if strings.Contains(key, ",") {
+			conditionalKeys = append(conditionalKeys, key)
+			continue
+		}
+		if _, ok := columnStyleBase(key); ok {
+			columnKeys = append(columnKeys, key)
+			continue
+		}
 		tokens := strings.Split(key, ".")
 		var styleName, attr string
 		switch len(tokens) {
@@ -408,7 +739,24 @@ func (ss *StyleSet) ParseStyleSet(file *ini.File) error {
 		}
 	}
 
+	// the attributes of the selected modifier override the other ones
+	for _, selected := range []bool{false, true} {
+		for _, key := range conditionalKeys {
+			m := conditionalKeyRe.FindStringSubmatch(key)
+			if m != nil && (m[4] != "") != selected {
+				continue
+			}
+			val := defaultSection.KeysHash()[key]
+			if err := ss.parseConditional(key, val); err != nil {
+				return err
+			}
+		}
+	}
+
 	for _, key := range defaultSection.KeyStrings() {
+		if strings.Contains(key, ",") {
+			continue
+		}
 		tokens := strings.Split(key, ".")
 		styleName, attr := tokens[0], tokens[1]
 		val := defaultSection.KeysHash()[key]
@@ -424,7 +772,7 @@ func (ss *StyleSet) ParseStyleSet(file *ini.File) error {
 		}
 	}
 
-	return nil
+	return ss.parseColumns(defaultSection, columnKeys)
 }
 
 func (ss *StyleSet) LoadStyleSet(stylesetName string, stylesetDirs []string) error {
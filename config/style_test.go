@@ -0,0 +1,130 @@
+package config
+
+import (
+	"testing"
+
+	"git.sr.ht/~rjarry/aerc/models"
+	"github.com/emersion/go-message/mail"
+	"github.com/gdamore/tcell/v2"
+	"github.com/go-ini/ini"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConditionalStyles(t *testing.T) {
+	file, err := ini.LoadSources(ini.LoadOptions{SpaceBeforeInlineComment: true}, []byte(`
+msglist_unread.bold=true
+msglist_*.From,~@boss\.com>?$.fg=red
+msglist_*.From,~@boss\.com>?$.selected.fg=blue
+msglist_unread.X-Priority,1.underline=true
+msglist_*.Labels,~^patch.bg=green
+`))
+	assert.Nil(t, err)
+	ss := NewStyleSet()
+	assert.Nil(t, ss.ParseStyleSet(file))
+
+	var h mail.Header
+	h.SetAddressList("From", []*mail.Address{{Address: "joe@boss.com"}})
+	h.Set("X-Priority", "1")
+	msg := &models.MessageInfo{RFC822Headers: &h, Labels: []string{"patches"}}
+
+	fg, bg, attrs := ss.ComposeMessage(STYLE_MSGLIST_DEFAULT,
+		[]StyleObject{STYLE_MSGLIST_READ}, msg, "").Decompose()
+	assert.Equal(t, tcell.ColorRed, fg)
+	assert.Equal(t, tcell.ColorGreen, bg)
+	assert.Equal(t, tcell.AttrNone, attrs)
+
+	fg, _, attrs = ss.ComposeMessageSelected(STYLE_MSGLIST_DEFAULT,
+		[]StyleObject{STYLE_MSGLIST_UNREAD}, msg, "").Decompose()
+	assert.Equal(t, tcell.ColorBlue, fg)
+	assert.Equal(t, tcell.AttrBold|tcell.AttrUnderline, attrs)
+
+	h.SetAddressList("From", []*mail.Address{{Address: "joe@example.com"}})
+	fg, _, _ = ss.ComposeMessage(STYLE_MSGLIST_DEFAULT,
+		[]StyleObject{STYLE_MSGLIST_UNREAD}, msg, "").Decompose()
+	assert.Equal(t, tcell.ColorDefault, fg)
+}
+
+func TestColumnStyles(t *testing.T) {
+	file, err := ini.LoadSources(ini.LoadOptions{}, []byte(`
+msglist_unread.bold=true
+msglist_column_date.fg=blue
+msglist_column_date.selected.fg=yellow
+msglist_column_flags.bold=false
+`))
+	assert.Nil(t, err)
+	ss := NewStyleSet()
+	assert.Nil(t, ss.ParseStyleSet(file))
+
+	msg := &models.MessageInfo{}
+	unread := []StyleObject{STYLE_MSGLIST_UNREAD}
+
+	fg, _, attrs := ss.ComposeMessage(STYLE_MSGLIST_DEFAULT,
+		unread, msg, "date").Decompose()
+	assert.Equal(t, tcell.ColorBlue, fg)
+	assert.Equal(t, tcell.AttrBold, attrs)
+
+	fg, _, _ = ss.ComposeMessageSelected(STYLE_MSGLIST_DEFAULT,
+		unread, msg, "date").Decompose()
+	assert.Equal(t, tcell.ColorYellow, fg)
+
+	// the message list styles override the column styles
+	_, _, attrs = ss.ComposeMessage(STYLE_MSGLIST_DEFAULT,
+		unread, msg, "flags").Decompose()
+	assert.Equal(t, tcell.AttrBold, attrs)
+
+	fg, _, _ = ss.ComposeMessage(STYLE_MSGLIST_DEFAULT,
+		unread, msg, "subject").Decompose()
+	assert.Equal(t, tcell.ColorDefault, fg)
+}
+
+func TestAuthresStyles(t *testing.T) {
+	file, err := ini.LoadSources(ini.LoadOptions{}, []byte(`
+msglist_*.Authres-DKIM,fail.fg=red
+msglist_*.Authres-DMARC,~^(none|fail)$.bold=true
+`))
+	assert.Nil(t, err)
+	ss := NewStyleSet()
+	assert.Nil(t, ss.ParseStyleSet(file))
+
+	tests := []struct {
+		authres string
+		fg      tcell.Color
+		attrs   tcell.AttrMask
+	}{
+		{
+			authres: "mx.example.com; dkim=fail header.d=example.com; " +
+				"dmarc=pass header.from=example.com",
+			fg:    tcell.ColorRed,
+			attrs: tcell.AttrNone,
+		},
+		{
+			authres: "mx.example.com; dkim=pass header.d=example.com; " +
+				"spf=pass smtp.mailfrom=example.com",
+			fg:    tcell.ColorDefault,
+			attrs: tcell.AttrBold,
+		},
+		{
+			// no authserv-id
+			authres: "dkim=fail header.d=example.com",
+			fg:      tcell.ColorRed,
+			attrs:   tcell.AttrBold,
+		},
+		{
+			authres: "",
+			fg:      tcell.ColorDefault,
+			attrs:   tcell.AttrBold,
+		},
+	}
+
+	for _, test := range tests {
+		var h mail.Header
+		if test.authres != "" {
+			h.Set("Authentication-Results", test.authres)
+		}
+		msg := &models.MessageInfo{RFC822Headers: &h}
+		fg, _, attrs := ss.ComposeMessage(STYLE_MSGLIST_DEFAULT,
+			nil, msg, "").Decompose()
+		assert.Equal(t, test.fg, fg, test.authres)
+		assert.Equal(t, test.attrs, attrs, test.authres)
+	}
+}
@@ -15,6 +15,8 @@ import (
 type TriggersConfig struct {
 	NewEmail       string `ini:"new-email"`
 	ExecuteCommand func(command []string) error
+	// in-process handlers registered by user scripts
+	NewEmailHooks []func(account *AccountConfig, msg *models.MessageInfo)
 }
 
 var Triggers = &TriggersConfig{}
@@ -57,6 +59,12 @@ func (trig *TriggersConfig) ExecTrigger(triggerCmd string,
 func (trig *TriggersConfig) ExecNewEmail(
 	account *AccountConfig, msg *models.MessageInfo,
 ) {
+	for _, hook := range trig.NewEmailHooks {
+		hook(account, msg)
+	}
+	if trig.NewEmail == "" && len(trig.NewEmailHooks) > 0 {
+		return
+	}
 	err := trig.ExecTrigger(trig.NewEmail,
 		func(part string) (string, error) {
 			formatstr, args, err := format.ParseMessageFormat(
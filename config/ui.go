@@ -10,6 +10,7 @@ import (
 
 	"git.sr.ht/~rjarry/aerc/lib/templates"
 	"git.sr.ht/~rjarry/aerc/log"
+	"git.sr.ht/~rjarry/aerc/models"
 	"github.com/gdamore/tcell/v2"
 	"github.com/go-ini/ini"
 	"github.com/imdario/mergo"
@@ -34,6 +35,7 @@ type UIConfig struct {
 	RenderAccountTabs             string        `ini:"render-account-tabs"`
 	PinnedTabMarker               string        `ini:"pinned-tab-marker"`
 	SidebarWidth                  int           `ini:"sidebar-width"`
+	GlobalSidebar                 bool          `ini:"global-sidebar"`
 	PreviewHeight                 int           `ini:"preview-height"`
 	EmptyMessage                  string        `ini:"empty-message"`
 	EmptyDirlist                  string        `ini:"empty-dirlist"`
@@ -58,6 +60,8 @@ type UIConfig struct {
 	DirListCollapse               int           `ini:"dirlist-collapse"`
 	Sort                          []string      `delim:" "`
 	NextMessageOnDelete           bool          `ini:"next-message-on-delete"`
+	PrefetchCount                 int           `ini:"prefetch-count"`
+	PrefetchMaxSize               int           `ini:"prefetch-max-size"`
 	CompletionDelay               time.Duration `ini:"completion-delay"`
 	CompletionMinChars            int           `ini:"completion-min-chars"`
 	CompletionPopovers            bool          `ini:"completion-popovers"`
@@ -160,6 +164,8 @@ func defaultUiConfig() *UIConfig {
 		DirListFormat:       "%n %>r",
 		DirListDelay:        200 * time.Millisecond,
 		NextMessageOnDelete: true,
+		PrefetchCount:       0,
+		PrefetchMaxSize:     256 * 1024,
 		CompletionDelay:     250 * time.Millisecond,
 		CompletionMinChars:  1,
 		CompletionPopovers:  true,
@@ -501,6 +507,10 @@ func (uiConfig *UIConfig) GetStyle(so StyleObject) tcell.Style {
 	return uiConfig.style.Get(so)
 }
 
+func (uiConfig *UIConfig) GetStatuslineColumnStyle(column string) tcell.Style {
+	return uiConfig.style.StatuslineColumn(column)
+}
+
 func (uiConfig *UIConfig) GetStyleSelected(so StyleObject) tcell.Style {
 	return uiConfig.style.Selected(so)
 }
@@ -517,6 +527,20 @@ func (uiConfig *UIConfig) GetComposedStyleSelected(
 	return uiConfig.style.ComposeSelected(base, styles)
 }
 
+func (uiConfig *UIConfig) MsgComposedStyle(
+	base StyleObject, styles []StyleObject, msg *models.MessageInfo,
+	column string,
+) tcell.Style {
+	return uiConfig.style.ComposeMessage(base, styles, msg, column)
+}
+
+func (uiConfig *UIConfig) MsgComposedStyleSelected(
+	base StyleObject, styles []StyleObject, msg *models.MessageInfo,
+	column string,
+) tcell.Style {
+	return uiConfig.style.ComposeMessageSelected(base, styles, msg, column)
+}
+
 func (base *UIConfig) contextual(
 	ctxType uiContextType, value string, useCache bool,
 ) *UIConfig {
@@ -30,6 +30,7 @@ require (
 	github.com/golangci/golangci-lint v1.50.1
 	github.com/google/shlex v0.0.0-20191202100458-e7afc7fbc510
 	github.com/imdario/mergo v0.3.13
+	github.com/klauspost/compress v1.15.15
 	github.com/kyoh86/xdg v1.2.0
 	github.com/lithammer/fuzzysearch v1.1.5
 	github.com/mattn/go-isatty v0.0.17
@@ -41,7 +42,9 @@ require (
 	github.com/riywo/loginshell v0.0.0-20200815045211-7d26008be1ab
 	github.com/stretchr/testify v1.8.1
 	github.com/syndtr/goleveldb v1.0.0
+	github.com/ulikunitz/xz v0.5.11
 	github.com/xo/terminfo v0.0.0-20220910002029-abceb7e1c41e
+	github.com/yuin/gopher-lua v1.1.0
 	github.com/zenhack/go.notmuch v0.0.0-20220918173508-0c918632c39e
 	golang.org/x/oauth2 v0.4.0
 	golang.org/x/tools v0.5.0
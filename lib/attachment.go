@@ -127,6 +127,10 @@ func (pa *PartAttachment) Name() string {
 	return pa.name
 }
 
+func (pa *PartAttachment) Part() *Part {
+	return pa.part
+}
+
 func (pa *PartAttachment) WriteTo(w *mail.Writer) error {
 	// set header fields
 	ah := mail.AttachmentHeader{}
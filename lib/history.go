@@ -4,10 +4,27 @@ package lib
 type History interface {
 	// Add a new element to the history
 	Add(string)
-	// Get the next element in history
-	Next() string
-	// Get the previous element in history
-	Prev() string
+	// Get the next element in history which starts with prefix
+	Next(prefix string) (string, bool)
+	// Get the previous element in history which starts with prefix
+	Prev(prefix string) (string, bool)
+	// Search backwards from the current location for an element which
+	// contains text
+	Search(text string) (string, bool)
 	// Reset the current location in history
 	Reset()
 }
+
+// Names of the command histories. Commands are recorded separately depending
+// on the kind of tab they were entered from. Search commands are additionally
+// recorded in a history shared by all contexts. Answers to :prompt have their
+// own history which is not saved to disk.
+const (
+	HistoryAccount  = "account"
+	HistoryCompose  = "compose"
+	HistoryMsgView  = "msgview"
+	HistoryTerminal = "terminal"
+	HistoryGlobal   = "global"
+	HistorySearch   = "search"
+	HistoryPrompt   = "prompt"
+)
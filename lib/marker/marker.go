@@ -5,10 +5,12 @@ type Marker interface {
 	Mark(uint32)
 	Unmark(uint32)
 	ToggleMark(uint32)
+	MarkRange(uint32, uint32)
 	Remark()
 	Marked() []uint32
 	IsMarked(uint32) bool
 	ToggleVisualMark(bool)
+	IsVisualMark() bool
 	UpdateVisualMark()
 	ClearVisualMark()
 }
@@ -74,6 +76,33 @@ func (mc *controller) ToggleMark(uid uint32) {
 	}
 }
 
+// MarkRange marks the uids between from and to, both included
+func (mc *controller) MarkRange(from, to uint32) {
+	if mc.visualMarkMode {
+		// visual mode has override, bogus input from user
+		return
+	}
+	start, end := -1, -1
+	uids := mc.uidProvider.Uids()
+	for idx, u := range uids {
+		if u == from {
+			start = idx
+		}
+		if u == to {
+			end = idx
+		}
+	}
+	if start < 0 || end < 0 {
+		return
+	}
+	if start > end {
+		start, end = end, start
+	}
+	for _, uid := range uids[start : end+1] {
+		mc.marked[uid] = struct{}{}
+	}
+}
+
 // resetMark removes the marking from all messages
 func (mc *controller) resetMark() {
 	mc.lastMarked = mc.marked
@@ -134,6 +163,11 @@ func (mc *controller) ToggleVisualMark(clear bool) {
 	}
 }
 
+// IsVisualMark returns true when in visual marking mode
+func (mc *controller) IsVisualMark() bool {
+	return mc.visualMarkMode
+}
+
 // ClearVisualMark leaves the visual marking mode and resets any marking
 func (mc *controller) ClearVisualMark() {
 	mc.resetMark()
@@ -89,8 +89,15 @@ func TestMarker_Marked(t *testing.T) {
 func TestMarker_VisualMode(t *testing.T) {
 	m, up := createMarker()
 
+	if m.IsVisualMark() {
+		t.Errorf("visual mode should not be active")
+	}
+
 	// activate visual mode
 	m.ToggleVisualMark(false)
+	if !m.IsVisualMark() {
+		t.Errorf("visual mode should be active")
+	}
 
 	// marking should now fail silently because we're in visual mode
 	m.Mark(1)
@@ -115,6 +122,9 @@ func TestMarker_VisualMode(t *testing.T) {
 	if len(m.Marked()) > 0 {
 		t.Errorf("no uids should be marked after clearing visual mark")
 	}
+	if m.IsVisualMark() {
+		t.Errorf("visual mode should not be active after clearing")
+	}
 
 	// test remark
 	m.Remark()
@@ -137,3 +147,21 @@ func TestMarker_MarkOutOfBound(t *testing.T) {
 		}
 	}
 }
+
+func TestMarker_MarkRange(t *testing.T) {
+	m, _ := createMarker()
+
+	m.Mark(1)
+	m.MarkRange(4, 2)
+	for _, uid := range []uint32{1, 2, 3, 4} {
+		if !m.IsMarked(uid) {
+			t.Errorf("MarkRange: uid %v should be marked", uid)
+		}
+	}
+
+	m, _ = createMarker()
+	m.MarkRange(2, 100)
+	if len(m.Marked()) > 0 {
+		t.Errorf("MarkRange: no uids should be marked with an unknown bound")
+	}
+}
@@ -1,6 +1,8 @@
 package lib
 
 import (
+	"bytes"
+	"errors"
 	"io"
 	"sync"
 	"time"
@@ -45,6 +47,8 @@ type MessageStore struct {
 	sortThreadSiblings bool
 	buildThreads       bool
 	builder            *ThreadBuilder
+	// uids of the messages whose replies are folded
+	folded map[uint32]struct{}
 
 	// Map of uids we've asked the worker to fetch
 	onUpdate       func(store *MessageStore) // TODO: multiple onUpdate handlers
@@ -70,6 +74,8 @@ type MessageStore struct {
 
 	iterFactory iterator.Factory
 	onSelect    func(*models.MessageInfo)
+
+	prefetcher *prefetcher
 }
 
 const MagicUid = 0xFFFFFFFF
@@ -99,6 +105,7 @@ func NewMessageStore(worker *types.Worker,
 		buildThreads:       clientThreads,
 		reverseThreadOrder: reverseThreadOrder,
 		sortThreadSiblings: sortThreadSiblings,
+		folded:             make(map[uint32]struct{}),
 
 		filter:       []string{"filter"},
 		sortCriteria: defaultSortCriteria,
@@ -179,6 +186,12 @@ func (store *MessageStore) FetchFull(uids []uint32, cb func(*types.FullMessage))
 }
 
 func (store *MessageStore) FetchBodyPart(uid uint32, part []int, cb func(io.Reader)) {
+	if store.prefetcher != nil {
+		if data, ok := store.prefetcher.get(uid, part); ok {
+			cb(bytes.NewReader(data))
+			return
+		}
+	}
 	store.worker.PostAction(&types.FetchMessageBodyPart{
 		Uid:  uid,
 		Part: part,
@@ -199,7 +212,10 @@ func merge(to *models.MessageInfo, from *models.MessageInfo) {
 		to.Envelope = from.Envelope
 	}
 	to.Flags = from.Flags
-	to.Labels = from.Labels
+	// nil labels are unknown, e.g. flag updates from some backends
+	if from.Labels != nil {
+		to.Labels = from.Labels
+	}
 	if from.Size != 0 {
 		to.Size = from.Size
 	}
@@ -239,6 +255,9 @@ func (store *MessageStore) Update(msg types.WorkerMessage) {
 		newMap := make(map[uint32]*models.MessageInfo)
 
 		store.builder = NewThreadBuilder(store.iterFactory)
+		store.threadsMutex.Lock()
+		store.applyFolds(msg.Threads)
+		store.threadsMutex.Unlock()
 		store.builder.RebuildUids(msg.Threads, store.reverseThreadOrder)
 		store.uids = store.builder.Uids()
 		store.threads = msg.Threads
@@ -280,6 +299,12 @@ func (store *MessageStore) Update(msg types.WorkerMessage) {
 		if store.builder != nil {
 			store.builder.Update(msg.Info)
 		}
+		if p := store.prefetcher; p != nil && msg.Info.BodyStructure != nil {
+			if _, ok := p.window[msg.Info.Uid]; ok {
+				// headers of a message around the selected one
+				store.prefetch(store.selectedUid)
+			}
+		}
 		update = true
 		updateThreads = true
 	case *types.FullMessage:
@@ -453,6 +478,9 @@ func (store *MessageStore) runThreadBuilderNow() {
 	// save local threads to the message store variable and
 	// run callback if defined (callback should reposition cursor)
 	store.threadsMutex.Lock()
+	if store.applyFolds(th) {
+		store.builder.RebuildUids(th, store.reverseThreadOrder)
+	}
 	store.threads = th
 	if store.threadCallback != nil {
 		store.threadCallback()
@@ -467,18 +495,23 @@ func (store *MessageStore) runThreadBuilderNow() {
 
 // SelectedThread returns the thread with the UID from the selected message
 func (store *MessageStore) SelectedThread() *types.Thread {
+	return store.Thread(store.SelectedUid())
+}
+
+// Thread returns the thread with the given UID
+func (store *MessageStore) Thread(uid uint32) *types.Thread {
 	var thread *types.Thread
 	for _, root := range store.Threads() {
 		found := false
 		err := root.Walk(func(t *types.Thread, _ int, _ error) error {
-			if t.Uid == store.SelectedUid() {
+			if t.Uid == uid {
 				thread = t
 				found = true
 			}
 			return nil
 		})
 		if err != nil {
-			log.Errorf("SelectedThread failed: %v", err)
+			log.Errorf("Thread failed: %v", err)
 		}
 		if found {
 			break
@@ -487,6 +520,180 @@ func (store *MessageStore) SelectedThread() *types.Thread {
 	return thread
 }
 
+// Fold hides the replies of the message with the given UID. If the message
+// has no replies, the replies of its parent are hidden instead.
+func (store *MessageStore) Fold(uid uint32) error {
+	thread := store.Thread(uid)
+	if thread == nil {
+		return errors.New("message is not in a thread")
+	}
+	if thread.FirstChild == nil && thread.Parent != nil {
+		thread = thread.Parent
+	}
+	store.setFolded(thread, true)
+	store.refreshFolds()
+	return nil
+}
+
+// Unfold shows the replies of the message with the given UID.
+func (store *MessageStore) Unfold(uid uint32) error {
+	thread := store.Thread(uid)
+	if thread == nil {
+		return errors.New("message is not in a thread")
+	}
+	store.setFolded(thread, false)
+	store.refreshFolds()
+	return nil
+}
+
+// ToggleFold folds the message with the given UID if it is unfolded and
+// unfolds it otherwise.
+func (store *MessageStore) ToggleFold(uid uint32) error {
+	if store.IsFolded(uid) {
+		return store.Unfold(uid)
+	}
+	return store.Fold(uid)
+}
+
+// FoldAll folds all threads at their root, or unfolds every message.
+func (store *MessageStore) FoldAll(fold bool) {
+	for _, root := range store.Threads() {
+		if fold {
+			store.setFolded(root, true)
+			continue
+		}
+		_ = root.Walk(func(t *types.Thread, _ int, _ error) error {
+			store.setFolded(t, false)
+			return nil
+		})
+	}
+	store.refreshFolds()
+}
+
+// IsFolded returns true if the replies of the message with the given UID are
+// hidden.
+func (store *MessageStore) IsFolded(uid uint32) bool {
+	store.threadsMutex.Lock()
+	defer store.threadsMutex.Unlock()
+	_, ok := store.folded[uid]
+	return ok
+}
+
+// FoldedUids returns the given UID followed by the UIDs of its hidden replies
+// if the message is folded.
+func (store *MessageStore) FoldedUids(uid uint32) []uint32 {
+	uids := []uint32{uid}
+	if !store.IsFolded(uid) {
+		return uids
+	}
+	thread := store.Thread(uid)
+	if thread == nil {
+		return uids
+	}
+	_ = thread.Walk(func(t *types.Thread, _ int, _ error) error {
+		if _, ok := store.Messages[t.Uid]; ok && t != thread && !t.Deleted {
+			uids = append(uids, t.Uid)
+		}
+		return nil
+	})
+	return uids
+}
+
+// ThreadUids returns the UIDs of all the messages of the threads containing
+// the given UIDs. Outside of the threaded view, the UIDs are returned as is.
+func (store *MessageStore) ThreadUids(uids []uint32) []uint32 {
+	if !store.ThreadedView() {
+		return uids
+	}
+	wanted := make(map[uint32]struct{}, len(uids))
+	for _, uid := range uids {
+		wanted[uid] = struct{}{}
+	}
+	var result []uint32
+	for _, root := range store.Threads() {
+		found := false
+		_ = root.Walk(func(t *types.Thread, _ int, _ error) error {
+			if _, ok := wanted[t.Uid]; ok {
+				found = true
+			}
+			return nil
+		})
+		if !found {
+			continue
+		}
+		_ = root.Walk(func(t *types.Thread, _ int, _ error) error {
+			if _, ok := store.Messages[t.Uid]; ok && !t.Deleted {
+				result = append(result, t.Uid)
+				delete(wanted, t.Uid)
+			}
+			return nil
+		})
+	}
+	// messages which are not part of the threads yet
+	for _, uid := range uids {
+		if _, ok := wanted[uid]; ok {
+			result = append(result, uid)
+		}
+	}
+	return result
+}
+
+func (store *MessageStore) setFolded(thread *types.Thread, fold bool) {
+	store.threadsMutex.Lock()
+	defer store.threadsMutex.Unlock()
+	if fold && thread.FirstChild != nil {
+		thread.Folded = true
+		store.folded[thread.Uid] = struct{}{}
+	} else if !fold {
+		thread.Folded = false
+		delete(store.folded, thread.Uid)
+	}
+}
+
+// applyFolds folds the messages of freshly built threads which were folded
+// before. It returns true if any message was folded. The caller must hold
+// threadsMutex.
+func (store *MessageStore) applyFolds(threads []*types.Thread) bool {
+	if len(store.folded) == 0 {
+		return false
+	}
+	folded := false
+	for _, root := range threads {
+		_ = root.Walk(func(t *types.Thread, _ int, _ error) error {
+			if _, ok := store.folded[t.Uid]; ok && t.FirstChild != nil {
+				t.Folded = true
+				folded = true
+			}
+			return nil
+		})
+	}
+	return folded
+}
+
+// refreshFolds rebuilds the list of visible messages after a message was
+// folded or unfolded. If the selected message was hidden, its outermost
+// folded parent is selected.
+func (store *MessageStore) refreshFolds() {
+	store.threadsMutex.Lock()
+	if store.builder != nil {
+		store.builder.RebuildUids(store.threads, store.reverseThreadOrder)
+	}
+	store.threadsMutex.Unlock()
+
+	if thread := store.SelectedThread(); thread != nil {
+		var folded *types.Thread
+		for t := thread.Parent; t != nil; t = t.Parent {
+			if t.Folded {
+				folded = t
+			}
+		}
+		if folded != nil {
+			store.Select(folded.Uid)
+		}
+	}
+	store.update(false)
+}
+
 func (store *MessageStore) Delete(uids []uint32,
 	cb func(msg types.WorkerMessage),
 ) {
@@ -617,6 +824,7 @@ func (store *MessageStore) selectPriv(uid uint32) {
 	if store.onSelect != nil {
 		store.onSelect(store.Selected())
 	}
+	store.prefetch(uid)
 }
 
 func (store *MessageStore) NextPrev(delta int) {
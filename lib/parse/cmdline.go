@@ -0,0 +1,175 @@
+package parse
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/google/shlex"
+)
+
+// Operator joins a command to the previous one in a command line.
+type Operator int
+
+const (
+	// unconditional sequence: ;
+	OpSequence Operator = iota
+	// execute only if the previous command succeeded: &&
+	OpAnd
+	// execute only if the previous command failed: ||
+	OpOr
+)
+
+func (op Operator) String() string {
+	switch op {
+	case OpAnd:
+		return "&&"
+	case OpOr:
+		return "||"
+	default:
+		return ";"
+	}
+}
+
+// ChainedCommand is a single command of a command line along with the
+// operator joining it to the previous command.
+type ChainedCommand struct {
+	Op   Operator
+	Args []string
+}
+
+// CommandChain is a list of commands separated by ;, && or ||.
+type CommandChain []*ChainedCommand
+
+// CommandLine splits a command line into a list of commands. Unquoted ;, &&
+// and || are operators. Each command is split into arguments following the
+// same quoting rules as shlex.
+func CommandLine(line string) (CommandChain, error) {
+	var chain CommandChain
+	op := OpSequence
+
+	flush := func(text string, next Operator) error {
+		args, err := shlex.Split(text)
+		if err != nil {
+			return err
+		}
+		if len(args) == 0 {
+			// tolerate trailing and duplicate semicolons
+			if next == OpSequence && op == OpSequence {
+				return nil
+			}
+			return fmt.Errorf("syntax error near %q", next.String())
+		}
+		chain = append(chain, &ChainedCommand{Op: op, Args: args})
+		op = next
+		return nil
+	}
+
+	rest, err := scan(line, flush)
+	if err != nil {
+		return nil, err
+	}
+	args, err := shlex.Split(rest)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case len(args) > 0:
+		chain = append(chain, &ChainedCommand{Op: op, Args: args})
+	case op != OpSequence:
+		return nil, fmt.Errorf("syntax error near %q", op.String())
+	}
+
+	return chain, nil
+}
+
+// LastCommand splits line before the last command of the chain, e.g. to
+// complete the command being typed. The prefix contains the previous
+// commands and the operator following them.
+func LastCommand(line string) (prefix string, last string) {
+	rest, _ := scan(line, func(string, Operator) error { return nil })
+	last = strings.TrimLeftFunc(rest, unicode.IsSpace)
+	return line[:len(line)-len(last)], last
+}
+
+// scan calls flush with the text of each command of line followed by an
+// unquoted operator, along with this operator. It returns the text after the
+// last operator.
+func scan(line string, flush func(string, Operator) error) (string, error) {
+	var cur strings.Builder
+	var quote rune
+	escaped := false
+
+	runes := []rune(line)
+loop:
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#' && (cur.Len() == 0 || endsWithSpace(&cur)):
+			// shlex comment, ignore the remaining text
+			cur.WriteString(string(runes[i:]))
+			break loop
+		case r == ';':
+			if err := flush(cur.String(), OpSequence); err != nil {
+				return "", err
+			}
+			cur.Reset()
+			continue
+		case (r == '&' || r == '|') && i+1 < len(runes) && runes[i+1] == r:
+			next := OpAnd
+			if r == '|' {
+				next = OpOr
+			}
+			if err := flush(cur.String(), next); err != nil {
+				return "", err
+			}
+			cur.Reset()
+			i++
+			continue
+		}
+		cur.WriteRune(r)
+	}
+
+	return cur.String(), nil
+}
+
+func endsWithSpace(b *strings.Builder) bool {
+	s := b.String()
+	return unicode.IsSpace(rune(s[len(s)-1]))
+}
+
+// Run executes all commands of the chain with the provided function. A
+// command preceded by && is skipped if the previous executed command failed
+// and a command preceded by || is skipped if it succeeded. The execution
+// stops at the first error which is not handled by a || operator.
+func (chain CommandChain) Run(exec func([]string) error) error {
+	var status error
+	for _, cmd := range chain {
+		switch cmd.Op {
+		case OpAnd:
+			if status != nil {
+				continue
+			}
+		case OpOr:
+			if status == nil {
+				continue
+			}
+		default:
+			if status != nil {
+				return status
+			}
+		}
+		status = exec(cmd.Args)
+	}
+	return status
+}
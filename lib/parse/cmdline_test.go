@@ -0,0 +1,174 @@
+package parse_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"git.sr.ht/~rjarry/aerc/lib/parse"
+)
+
+func TestCommandLine(t *testing.T) {
+	tests := []struct {
+		line  string
+		cmds  [][]string
+		ops   []parse.Operator
+		error bool
+	}{
+		{
+			line: "",
+		},
+		{
+			line: "read",
+			cmds: [][]string{{"read"}},
+			ops:  []parse.Operator{parse.OpSequence},
+		},
+		{
+			line: "read; archive flat;",
+			cmds: [][]string{{"read"}, {"archive", "flat"}},
+			ops:  []parse.Operator{parse.OpSequence, parse.OpSequence},
+		},
+		{
+			line: "mark -a&&read||exec echo 'a && b'",
+			cmds: [][]string{
+				{"mark", "-a"}, {"read"}, {"exec", "echo", "a && b"},
+			},
+			ops: []parse.Operator{parse.OpSequence, parse.OpAnd, parse.OpOr},
+		},
+		{
+			line: `exec sh -c "a; b" \; c`,
+			cmds: [][]string{{"exec", "sh", "-c", "a; b", ";", "c"}},
+			ops:  []parse.Operator{parse.OpSequence},
+		},
+		{
+			line: "pipe -m a | b",
+			cmds: [][]string{{"pipe", "-m", "a", "|", "b"}},
+			ops:  []parse.Operator{parse.OpSequence},
+		},
+		{
+			// operators are split even in :pipe arguments
+			line: "pipe grep x || true",
+			cmds: [][]string{{"pipe", "grep", "x"}, {"true"}},
+			ops:  []parse.Operator{parse.OpSequence, parse.OpOr},
+		},
+		{
+			line: "pipe 'grep x || true'",
+			cmds: [][]string{{"pipe", "grep x || true"}},
+			ops:  []parse.Operator{parse.OpSequence},
+		},
+		{
+			line: `filter -b "sort && uniq"`,
+			cmds: [][]string{{"filter", "-b", "sort && uniq"}},
+			ops:  []parse.Operator{parse.OpSequence},
+		},
+		{
+			line: "read # comment; archive",
+			cmds: [][]string{{"read"}},
+			ops:  []parse.Operator{parse.OpSequence},
+		},
+		{
+			line:  "&& read",
+			error: true,
+		},
+		{
+			line:  "read ||",
+			error: true,
+		},
+		{
+			line:  "read; && archive",
+			error: true,
+		},
+		{
+			line:  `read "unterminated`,
+			error: true,
+		},
+	}
+
+	for _, test := range tests {
+		chain, err := parse.CommandLine(test.line)
+		if test.error {
+			if err == nil {
+				t.Errorf("%q: expected error", test.line)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", test.line, err)
+			continue
+		}
+		var cmds [][]string
+		var ops []parse.Operator
+		for _, c := range chain {
+			cmds = append(cmds, c.Args)
+			ops = append(ops, c.Op)
+		}
+		if !reflect.DeepEqual(cmds, test.cmds) {
+			t.Errorf("%q: expected %q got %q", test.line, test.cmds, cmds)
+		}
+		if !reflect.DeepEqual(ops, test.ops) {
+			t.Errorf("%q: expected %v got %v", test.line, test.ops, ops)
+		}
+	}
+}
+
+func TestCommandChainRun(t *testing.T) {
+	tests := []struct {
+		line  string
+		ran   []string
+		error bool
+	}{
+		{line: "ok a; ok b", ran: []string{"a", "b"}},
+		{line: "fail a; ok b", ran: []string{"a"}, error: true},
+		{line: "fail a && ok b", ran: []string{"a"}, error: true},
+		{line: "fail a || ok b; ok c", ran: []string{"a", "b", "c"}},
+		{line: "ok a || ok b; ok c", ran: []string{"a", "c"}},
+		{line: "fail a && ok b || ok c", ran: []string{"a", "c"}},
+		{line: "ok a && fail b || ok c", ran: []string{"a", "b", "c"}},
+	}
+
+	for _, test := range tests {
+		chain, err := parse.CommandLine(test.line)
+		if err != nil {
+			t.Errorf("%q: %v", test.line, err)
+			continue
+		}
+		var ran []string
+		err = chain.Run(func(args []string) error {
+			ran = append(ran, args[1])
+			if args[0] == "fail" {
+				return errors.New("failed")
+			}
+			return nil
+		})
+		if (err != nil) != test.error {
+			t.Errorf("%q: unexpected error %v", test.line, err)
+		}
+		if !reflect.DeepEqual(ran, test.ran) {
+			t.Errorf("%q: expected %q got %q", test.line, test.ran, ran)
+		}
+	}
+}
+
+func TestLastCommand(t *testing.T) {
+	tests := []struct {
+		line   string
+		prefix string
+		last   string
+	}{
+		{line: "", prefix: "", last: ""},
+		{line: "arch", prefix: "", last: "arch"},
+		{line: "read; arch", prefix: "read; ", last: "arch"},
+		{line: "mark -a && move ", prefix: "mark -a && ", last: "move "},
+		{line: "read ||", prefix: "read ||", last: ""},
+		{line: "exec 'a || b' -", prefix: "", last: "exec 'a || b' -"},
+		{line: `exec a \; b`, prefix: "", last: `exec a \; b`},
+	}
+
+	for _, test := range tests {
+		prefix, last := parse.LastCommand(test.line)
+		if prefix != test.prefix || last != test.last {
+			t.Errorf("%q: expected %q %q got %q %q", test.line,
+				test.prefix, test.last, prefix, last)
+		}
+	}
+}
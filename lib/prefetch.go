@@ -0,0 +1,183 @@
+package lib
+
+import (
+	"fmt"
+	"io"
+
+	"git.sr.ht/~rjarry/aerc/log"
+	"git.sr.ht/~rjarry/aerc/models"
+	"git.sr.ht/~rjarry/aerc/worker/types"
+)
+
+// partKey identifies a body part of a message.
+type partKey struct {
+	uid  uint32
+	part string
+}
+
+func newPartKey(uid uint32, part []int) partKey {
+	return partKey{uid: uid, part: fmt.Sprint(part)}
+}
+
+// prefetcher keeps in memory the text parts of the messages surrounding the
+// selected one, so that they can be displayed without waiting for the worker.
+// There is no disk cache, the parts are lost when aerc exits.
+type prefetcher struct {
+	count   int
+	maxSize int
+	parts   map[partKey][]byte
+	pending map[partKey]struct{}
+	// uids of the messages around the selected one
+	window map[uint32]struct{}
+}
+
+func newPrefetcher(count, maxSize int) *prefetcher {
+	return &prefetcher{
+		count:   count,
+		maxSize: maxSize,
+		parts:   make(map[partKey][]byte),
+		pending: make(map[partKey]struct{}),
+	}
+}
+
+func (p *prefetcher) get(uid uint32, part []int) ([]byte, bool) {
+	data, ok := p.parts[newPartKey(uid, part)]
+	return data, ok
+}
+
+// put reads a prefetched part. Parts larger than maxSize are discarded but
+// remain pending so that they are not fetched again.
+func (p *prefetcher) put(key partKey, r io.Reader) {
+	data, err := io.ReadAll(io.LimitReader(r, int64(p.maxSize)+1))
+	if err != nil {
+		log.Warnf("prefetch of %v failed: %v", key, err)
+		delete(p.pending, key)
+		return
+	}
+	if len(data) > p.maxSize {
+		return
+	}
+	delete(p.pending, key)
+	p.parts[key] = data
+}
+
+// setWindow forgets the parts of the messages which are not in window.
+func (p *prefetcher) setWindow(window map[uint32]struct{}) {
+	p.window = window
+	for key := range p.parts {
+		if _, ok := window[key.uid]; !ok {
+			delete(p.parts, key)
+		}
+	}
+	for key := range p.pending {
+		if _, ok := window[key.uid]; !ok {
+			delete(p.pending, key)
+		}
+	}
+}
+
+// textParts returns the paths of the parts of a message which are displayed
+// as text and whose size does not exceed maxSize. The path of a single part
+// message is nil.
+func textParts(bs *models.BodyStructure, maxSize int) [][]int {
+	isText := func(part *models.BodyStructure) bool {
+		return part.MIMEType == "text" &&
+			part.Disposition != "attachment" &&
+			int64(part.Size) <= int64(maxSize)
+	}
+	if len(bs.Parts) == 0 {
+		if isText(bs) {
+			return [][]int{nil}
+		}
+		return nil
+	}
+	var paths [][]int
+	var walk func(*models.BodyStructure, []int)
+	walk = func(bs *models.BodyStructure, index []int) {
+		for i, part := range bs.Parts {
+			curindex := append(append([]int{}, index...), i+1)
+			if part.MIMEType == "multipart" {
+				walk(part, curindex)
+			} else if isText(part) {
+				paths = append(paths, curindex)
+			}
+		}
+	}
+	walk(bs, nil)
+	return paths
+}
+
+// SetPrefetch enables the prefetching of the text parts of the count
+// messages before and after the selected one. Parts larger than maxSize bytes
+// are not prefetched.
+func (store *MessageStore) SetPrefetch(count int, maxSize int) {
+	if count <= 0 || maxSize <= 0 {
+		store.prefetcher = nil
+		return
+	}
+	store.prefetcher = newPrefetcher(count, maxSize)
+}
+
+// prefetch fetches in the background the text parts of the messages around
+// the selected one.
+func (store *MessageStore) prefetch(selected uint32) {
+	p := store.prefetcher
+	if p == nil {
+		return
+	}
+	uids := store.Uids()
+	idx := -1
+	for i, uid := range uids {
+		if uid == selected {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return
+	}
+	lo, hi := idx-p.count, idx+p.count+1
+	if lo < 0 {
+		lo = 0
+	}
+	if hi > len(uids) {
+		hi = len(uids)
+	}
+	window := make(map[uint32]struct{}, hi-lo)
+	for _, uid := range uids[lo:hi] {
+		window[uid] = struct{}{}
+	}
+	p.setWindow(window)
+
+	for _, uid := range uids[lo:hi] {
+		info := store.Messages[uid]
+		if uid == selected || info == nil || info.Error != nil ||
+			info.BodyStructure == nil || usePGP(info.BodyStructure) {
+			continue
+		}
+		for _, part := range textParts(info.BodyStructure, p.maxSize) {
+			key := newPartKey(uid, part)
+			if _, ok := p.parts[key]; ok {
+				continue
+			}
+			if _, ok := p.pending[key]; ok {
+				continue
+			}
+			p.pending[key] = struct{}{}
+			store.worker.PostAction(&types.FetchMessageBodyPart{
+				Uid:        uid,
+				Part:       part,
+				Background: true,
+			}, func(msg types.WorkerMessage) {
+				switch msg := msg.(type) {
+				case *types.MessageBodyPart:
+					if p == store.prefetcher {
+						p.put(key, msg.Part.Reader)
+					}
+				case *types.Error, *types.Unsupported:
+					delete(p.pending, key)
+				}
+			})
+		}
+	}
+}
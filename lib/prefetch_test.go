@@ -0,0 +1,38 @@
+package lib
+
+import (
+	"reflect"
+	"testing"
+
+	"git.sr.ht/~rjarry/aerc/models"
+)
+
+func TestPrefetchTextParts(t *testing.T) {
+	bs := &models.BodyStructure{
+		MIMEType: "multipart",
+		Parts: []*models.BodyStructure{
+			{
+				MIMEType: "multipart",
+				Parts: []*models.BodyStructure{
+					{MIMEType: "text", MIMESubType: "plain", Size: 100},
+					{MIMEType: "text", MIMESubType: "html", Size: 5000},
+				},
+			},
+			{MIMEType: "image", MIMESubType: "png", Size: 100},
+			{
+				MIMEType: "text", MIMESubType: "plain", Size: 100,
+				Disposition: "attachment",
+			},
+		},
+	}
+	parts := textParts(bs, 1000)
+	if expected := [][]int{{1, 1}}; !reflect.DeepEqual(parts, expected) {
+		t.Errorf("expected %v, got %v", expected, parts)
+	}
+
+	single := &models.BodyStructure{MIMEType: "text", MIMESubType: "plain"}
+	parts = textParts(single, 1000)
+	if len(parts) != 1 || parts[0] != nil {
+		t.Errorf("expected [[]], got %v", parts)
+	}
+}
@@ -4,13 +4,13 @@ import (
 	"fmt"
 
 	"git.sr.ht/~rjarry/aerc/config"
+	"git.sr.ht/~rjarry/aerc/lib/templates"
 )
 
 type State struct {
-	renderer renderFunc
-	acct     *accountState
-	fldr     map[string]*folderState
-	width    int
+	texter Texter
+	acct   *accountState
+	fldr   map[string]*folderState
 }
 
 type accountState struct {
@@ -19,6 +19,7 @@ type accountState struct {
 	ConnActivity string
 	Connected    bool
 	Passthrough  bool
+	Outbox       int
 }
 
 type folderState struct {
@@ -32,19 +33,17 @@ type folderState struct {
 
 func NewState(name string, multipleAccts bool) *State {
 	return &State{
-		renderer: newRenderer(),
-		acct:     &accountState{Name: name, Multiple: multipleAccts},
-		fldr:     make(map[string]*folderState),
+		texter: newTexter(),
+		acct:   &accountState{Name: name, Multiple: multipleAccts},
+		fldr:   make(map[string]*folderState),
 	}
 }
 
-func (s *State) StatusLine(folder string) string {
-	return s.renderer(renderParams{
-		width: s.width,
-		sep:   config.Statusline.Separator,
-		acct:  s.acct,
-		fldr:  s.folderState(folder),
-	})
+// Status returns the state of the account and of a folder for the statusline
+// templates. The message counts are left to the caller.
+func (s *State) Status(folder string) *templates.Status {
+	return status(s.acct, s.folderState(folder), s.texter,
+		config.Statusline.Separator)
 }
 
 func (s *State) folderState(folder string) *folderState {
@@ -54,15 +53,6 @@ func (s *State) folderState(folder string) *folderState {
 	return s.fldr[folder]
 }
 
-func (s *State) SetWidth(w int) bool {
-	changeState := false
-	if s.width != w {
-		s.width = w
-		changeState = true
-	}
-	return changeState
-}
-
 func (s *State) Connected() bool {
 	return s.acct.Connected
 }
@@ -133,3 +123,10 @@ func Passthrough(on bool) SetStateFunc {
 		s.acct.Passthrough = on
 	}
 }
+
+// Outbox adds delta to the number of messages being sent.
+func Outbox(delta int) SetStateFunc {
+	return func(s *State, folder string) {
+		s.acct.Outbox += delta
+	}
+}
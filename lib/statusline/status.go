@@ -0,0 +1,84 @@
+package statusline
+
+import (
+	"strings"
+
+	"git.sr.ht/~rjarry/aerc/config"
+	"git.sr.ht/~rjarry/aerc/lib/templates"
+)
+
+func newTexter() Texter {
+	switch strings.ToLower(config.Statusline.DisplayMode) {
+	case "icon":
+		return &icon{}
+	default:
+		return &text{}
+	}
+}
+
+// status returns the state of an account and of one of its folders, as
+// passed to the statusline templates.
+func status(acct *accountState, fldr *folderState, texter Texter, sep string) *templates.Status {
+	conn := connectionInfo(acct, texter)
+	var content, tray []string
+	if acct.Connected {
+		content = contentInfo(acct, fldr, texter)
+		tray = trayInfo(acct, fldr, texter)
+	}
+	var info []string
+	if conn != "" {
+		info = append(info, conn)
+	}
+	info = append(info, content...)
+
+	return &templates.Status{
+		Connected:      acct.Connected,
+		ConnectionInfo: conn,
+		ContentInfo:    strings.Join(content, sep),
+		StatusInfo:     strings.Join(info, sep),
+		TrayInfo:       strings.Join(tray, sep),
+		Search:         fldr.Search,
+		Filter:         fldr.Filter,
+		Outbox:         acct.Outbox,
+	}
+}
+
+func connectionInfo(acct *accountState, texter Texter) (conn string) {
+	if acct.ConnActivity != "" {
+		conn += acct.ConnActivity
+	} else {
+		if acct.Connected {
+			conn += texter.Connected()
+		} else {
+			conn += texter.Disconnected()
+		}
+	}
+	return
+}
+
+func contentInfo(acct *accountState, fldr *folderState, texter Texter) []string {
+	var status []string
+	if fldr.FilterActivity != "" {
+		status = append(status, fldr.FilterActivity)
+	} else if fldr.Filter != "" {
+		status = append(status, texter.FormatFilter(fldr.Filter))
+	}
+	if fldr.Search != "" {
+		status = append(status, texter.FormatSearch(fldr.Search))
+	}
+	return status
+}
+
+func trayInfo(acct *accountState, fldr *folderState, texter Texter) []string {
+	var tray []string
+	if fldr.Sorting {
+		tray = append(tray, texter.Sorting())
+	}
+	if fldr.Threading {
+		tray = append(tray, texter.Threading())
+	}
+	if acct.Passthrough {
+		tray = append(tray, texter.Passthrough())
+	}
+	return tray
+}
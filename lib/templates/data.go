@@ -20,9 +20,15 @@ type TemplateData struct {
 	marked bool
 	msgNum int
 
+	// only available for the statusline
+	status *Status
+
 	// message list threading
 	ThreadSameSubject bool
 	ThreadPrefix      string
+	ThreadFolded      bool
+	ThreadCount       int
+	ThreadUnread      int
 
 	// account config
 	myAddresses map[string]bool
@@ -77,6 +83,89 @@ func (d *TemplateData) SetInfo(info *models.MessageInfo, num int, marked bool) {
 	d.marked = marked
 }
 
+// Status is the state of an account and of its selected folder, as shown in
+// the statusline.
+type Status struct {
+	Connected bool
+	// connection state or activity
+	ConnectionInfo string
+	// filter and search
+	ContentInfo string
+	// connection and content info
+	StatusInfo string
+	// sorting, threading and passthrough indicators
+	TrayInfo string
+	Search   string
+	Filter   string
+
+	Total  int
+	Unread int
+	Recent int
+	Marked int
+	// messages being sent in the background
+	Outbox int
+}
+
+// only used for statusline templates
+func (d *TemplateData) SetStatus(status *Status) {
+	d.status = status
+}
+
+func (d *TemplateData) getStatus() *Status {
+	if d.status == nil {
+		return &Status{}
+	}
+	return d.status
+}
+
+func (d *TemplateData) Connected() bool {
+	return d.getStatus().Connected
+}
+
+func (d *TemplateData) ConnectionInfo() string {
+	return d.getStatus().ConnectionInfo
+}
+
+func (d *TemplateData) ContentInfo() string {
+	return d.getStatus().ContentInfo
+}
+
+func (d *TemplateData) StatusInfo() string {
+	return d.getStatus().StatusInfo
+}
+
+func (d *TemplateData) TrayInfo() string {
+	return d.getStatus().TrayInfo
+}
+
+func (d *TemplateData) Search() string {
+	return d.getStatus().Search
+}
+
+func (d *TemplateData) Filter() string {
+	return d.getStatus().Filter
+}
+
+func (d *TemplateData) Total() int {
+	return d.getStatus().Total
+}
+
+func (d *TemplateData) Unread() int {
+	return d.getStatus().Unread
+}
+
+func (d *TemplateData) Recent() int {
+	return d.getStatus().Recent
+}
+
+func (d *TemplateData) Marked() int {
+	return d.getStatus().Marked
+}
+
+func (d *TemplateData) Outbox() int {
+	return d.getStatus().Outbox
+}
+
 func (d *TemplateData) Account() string {
 	return d.account
 }
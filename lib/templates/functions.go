@@ -2,15 +2,21 @@ package templates
 
 import (
 	"bytes"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"os/exec"
+	"regexp"
+	"strconv"
 	"strings"
 	"text/template"
 	"time"
 
 	"git.sr.ht/~rjarry/aerc/lib/format"
+	"git.sr.ht/~rjarry/aerc/log"
 	"github.com/emersion/go-message/mail"
+	"github.com/gdamore/tcell/v2"
 )
 
 var version string
@@ -181,6 +187,109 @@ func join(sep string, elems []string) string {
 	return strings.Join(elems, sep)
 }
 
+// ScriptHandler executes a named script function with the template data. It
+// is registered when user scripts are loaded.
+var ScriptHandler func(name string, data *TemplateData) (string, error)
+
+func script(name string, data *TemplateData) string {
+	if ScriptHandler == nil {
+		return ""
+	}
+	s, err := ScriptHandler(name, data)
+	if err != nil {
+		log.Errorf("lua %q: %v", name, err)
+		return ""
+	}
+	return s
+}
+
+var styleAttrs = map[string]string{
+	"bold":      "1",
+	"dim":       "2",
+	"italic":    "3",
+	"underline": "4",
+	"blink":     "5",
+	"reverse":   "7",
+}
+
+// sgrColor returns the SGR parameters which set a color. base is 30 for the
+// foreground and 40 for the background.
+func sgrColor(val string, base int) (string, error) {
+	if val == "default" {
+		return strconv.Itoa(base + 9), nil
+	}
+	var c tcell.Color
+	if i, err := strconv.ParseUint(val, 10, 8); err == nil {
+		c = tcell.PaletteColor(int(i))
+	} else {
+		c = tcell.GetColor(val)
+	}
+	switch {
+	case c&tcell.ColorIsRGB != 0:
+		r, g, b := c.RGB()
+		return fmt.Sprintf("%d;2;%d;%d;%d", base+8, r, g, b), nil
+	case c&tcell.ColorValid != 0:
+		return fmt.Sprintf("%d;5;%d", base+8, c-tcell.ColorValid), nil
+	}
+	return "", fmt.Errorf("unknown color: %q", val)
+}
+
+// StyleMarker starts the SGR sequences produced by the style function, in
+// place of the escape character. Only these sequences are interpreted when
+// rendering columns so that the escape sequences found in messages are not.
+// It contains a random token so that it cannot be forged by the text of the
+// messages or the output of commands.
+var StyleMarker = newStyleMarker()
+
+var styleRegexp = regexp.MustCompile(regexp.QuoteMeta(StyleMarker) +
+	`\[[0-9;]*m`)
+
+func newStyleMarker() string {
+	token := make([]byte, 8)
+	if _, err := rand.Read(token); err != nil {
+		panic(err)
+	}
+	// U+FDD0 is a noncharacter reserved for internal use
+	return "\uFDD0" + hex.EncodeToString(token) + "\uFDD0"
+}
+
+// Unstyle removes the sequences of the style function from s. It is used
+// to render the templates whose output is not displayed in columns.
+func Unstyle(s string) string {
+	return styleRegexp.ReplaceAllString(s, "")
+}
+
+// style surrounds text with SGR sequences which apply the given space
+// separated attributes (e.g. "fg=red bold"). They are rendered by the
+// message list and statusline columns and removed everywhere else.
+func style(attrs string, text string) (string, error) {
+	var params []string
+	for _, attr := range strings.Fields(attrs) {
+		var p string
+		var err error
+		switch {
+		case strings.HasPrefix(attr, "fg="):
+			p, err = sgrColor(strings.TrimPrefix(attr, "fg="), 30)
+		case strings.HasPrefix(attr, "bg="):
+			p, err = sgrColor(strings.TrimPrefix(attr, "bg="), 40)
+		default:
+			var ok bool
+			if p, ok = styleAttrs[attr]; !ok {
+				err = fmt.Errorf("unknown style attribute: %q", attr)
+			}
+		}
+		if err != nil {
+			return "", err
+		}
+		params = append(params, p)
+	}
+	if len(params) == 0 {
+		return text, nil
+	}
+	return StyleMarker + "[" + strings.Join(params, ";") + "m" + text +
+		StyleMarker + "[0m", nil
+}
+
 var templateFuncs = template.FuncMap{
 	"quote":         quote,
 	"wrapText":      wrapText,
@@ -196,4 +305,6 @@ var templateFuncs = template.FuncMap{
 	"humanReadable": humanReadable,
 	"cwd":           cwd,
 	"join":          join,
+	"lua":           script,
+	"style":         style,
 }
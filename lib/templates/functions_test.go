@@ -0,0 +1,18 @@
+package templates
+
+import "testing"
+
+func TestUnstyle(t *testing.T) {
+	styled, err := style("fg=red bold", "urgent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := Unstyle("[" + styled + "] subject"); s != "[urgent] subject" {
+		t.Errorf("expected %q, got %q", "[urgent] subject", s)
+	}
+	// text which looks like a style sequence is kept
+	forged := "\uFDD0[31mtext"
+	if s := Unstyle(forged); s != forged {
+		t.Errorf("expected %q, got %q", forged, s)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"io"
 	"os"
 	"path"
+	"strings"
 	"text/template"
 
 	"github.com/mitchellh/go-homedir"
@@ -43,7 +44,7 @@ func ParseTemplateFromFile(templateName string, templateDirs []string, data inte
 	if err := emailTemplate.Execute(&body, data); err != nil {
 		return nil, err
 	}
-	return &body, nil
+	return strings.NewReader(Unstyle(body.String())), nil
 }
 
 func ParseTemplate(name, content string) (*template.Template, error) {
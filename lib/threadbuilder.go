@@ -184,11 +184,15 @@ func (builder *ThreadBuilder) RebuildUids(threads []*types.Thread, inverse bool)
 		var threaduids []uint32
 		_ = iterT.Value().(*types.Thread).Walk(
 			func(t *types.Thread, level int, currentErr error) error {
+				var err error
+				if t.Folded {
+					err = types.ErrSkipThread
+				}
 				if t.Deleted || t.Hidden {
-					return nil
+					return err
 				}
 				threaduids = append(threaduids, t.Uid)
-				return nil
+				return err
 			})
 		if inverse {
 			for j := len(threaduids) - 1; j >= 0; j-- {
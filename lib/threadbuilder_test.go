@@ -0,0 +1,37 @@
+package lib
+
+import (
+	"reflect"
+	"testing"
+
+	"git.sr.ht/~rjarry/aerc/lib/iterator"
+	"git.sr.ht/~rjarry/aerc/worker/types"
+)
+
+func TestThreadBuilderRebuildUidsFolded(t *testing.T) {
+	root := &types.Thread{Uid: 1}
+	reply := &types.Thread{Uid: 2}
+	root.AddChild(reply)
+	reply.AddChild(&types.Thread{Uid: 3})
+	root.AddChild(&types.Thread{Uid: 4})
+	other := &types.Thread{Uid: 5}
+	threads := []*types.Thread{root, other}
+
+	builder := NewThreadBuilder(iterator.NewFactory(true))
+	builder.RebuildUids(threads, false)
+	if uids, expected := builder.Uids(), []uint32{1, 2, 3, 4, 5}; !reflect.DeepEqual(uids, expected) {
+		t.Errorf("expected %v, got %v", expected, uids)
+	}
+
+	reply.Folded = true
+	builder.RebuildUids(threads, false)
+	if uids, expected := builder.Uids(), []uint32{1, 2, 4, 5}; !reflect.DeepEqual(uids, expected) {
+		t.Errorf("expected %v, got %v", expected, uids)
+	}
+
+	root.Folded = true
+	builder.RebuildUids(threads, false)
+	if uids, expected := builder.Uids(), []uint32{1, 5}; !reflect.DeepEqual(uids, expected) {
+		t.Errorf("expected %v, got %v", expected, uids)
+	}
+}
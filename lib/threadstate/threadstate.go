@@ -0,0 +1,145 @@
+// Package threadstate records the threads muted or watched by the user. A
+// thread is identified by the Message-ID of its first message so that its
+// state is the same in every folder and with every backend.
+package threadstate
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/kyoh86/xdg"
+
+	"git.sr.ht/~rjarry/aerc/log"
+	"git.sr.ht/~rjarry/aerc/models"
+)
+
+type State int
+
+const (
+	Normal State = iota
+	Muted
+	Watched
+)
+
+var stateNames = map[State]string{
+	Muted:   "mute",
+	Watched: "watch",
+}
+
+var (
+	lock   sync.Mutex
+	states map[string]State
+)
+
+func statePath() string {
+	return filepath.Join(xdg.DataHome(), "aerc", "threads")
+}
+
+// Root returns the Message-ID of the first message of the thread of msg, or
+// an empty string if the headers of msg are not known.
+func Root(msg *models.MessageInfo) string {
+	if msg == nil || msg.Envelope == nil {
+		return ""
+	}
+	if refs, err := msg.References(); err == nil && len(refs) > 0 {
+		return refs[0]
+	}
+	if irt, err := msg.InReplyTo(); err == nil && irt != "" {
+		return irt
+	}
+	return msg.Envelope.MessageId
+}
+
+// Get returns the state of the thread of msg.
+func Get(msg *models.MessageInfo) State {
+	root := Root(msg)
+	if root == "" {
+		return Normal
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	load()
+	return states[root]
+}
+
+// Set changes the state of the thread of msg and saves all states to disk.
+func Set(msg *models.MessageInfo, state State) error {
+	root := Root(msg)
+	if root == "" {
+		return fmt.Errorf("message headers are not loaded")
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	load()
+	if state == Normal {
+		delete(states, root)
+	} else {
+		states[root] = state
+	}
+	return save()
+}
+
+// load reads the states file. Each line is made of a state name and the
+// Message-ID of the root of a thread separated by a space.
+func load() {
+	if states != nil {
+		return
+	}
+	states = make(map[string]State)
+	f, err := os.Open(statePath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Errorf("cannot read thread states: %v", err)
+		}
+		return
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		name, root, found := strings.Cut(s.Text(), " ")
+		if !found {
+			continue
+		}
+		for state, n := range stateNames {
+			if n == name {
+				states[root] = state
+			}
+		}
+	}
+}
+
+// save writes the states sorted by Message-ID so that the file only changes
+// when a state is changed.
+func save() error {
+	p := statePath()
+	if err := os.MkdirAll(filepath.Dir(p), 0o700); err != nil {
+		return err
+	}
+	tmp := p + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	roots := make([]string, 0, len(states))
+	for root := range states {
+		roots = append(roots, root)
+	}
+	sort.Strings(roots)
+	w := bufio.NewWriter(f)
+	for _, root := range roots {
+		fmt.Fprintf(w, "%s %s\n", stateNames[states[root]], root)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p)
+}
@@ -0,0 +1,69 @@
+package threadstate
+
+import (
+	"os"
+	"testing"
+
+	"git.sr.ht/~rjarry/aerc/models"
+)
+
+func TestThreadState(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	states = nil
+
+	root := &models.MessageInfo{
+		Envelope: &models.Envelope{MessageId: "root@example.com"},
+	}
+	reply := &models.MessageInfo{
+		Envelope: &models.Envelope{
+			MessageId: "reply@example.com",
+			InReplyTo: "middle@example.com",
+		},
+		Refs: []string{"root@example.com", "middle@example.com"},
+	}
+	if r := Root(reply); r != "root@example.com" {
+		t.Errorf("expected root@example.com, got %q", r)
+	}
+
+	if err := Set(root, Muted); err != nil {
+		t.Fatal(err)
+	}
+	// reload from disk
+	states = nil
+	if s := Get(reply); s != Muted {
+		t.Errorf("expected muted thread, got %v", s)
+	}
+	if err := Set(reply, Normal); err != nil {
+		t.Fatal(err)
+	}
+	states = nil
+	if s := Get(root); s != Normal {
+		t.Errorf("expected normal thread, got %v", s)
+	}
+}
+
+func TestThreadStateSorted(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	states = nil
+
+	for _, id := range []string{"c@example.com", "a@example.com", "b@example.com"} {
+		msg := &models.MessageInfo{
+			Envelope: &models.Envelope{MessageId: id},
+		}
+		state := Muted
+		if id == "b@example.com" {
+			state = Watched
+		}
+		if err := Set(msg, state); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := os.ReadFile(statePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "mute a@example.com\nwatch b@example.com\nmute c@example.com\n"
+	if string(data) != expected {
+		t.Errorf("expected %q, got %q", expected, data)
+	}
+}
@@ -0,0 +1,168 @@
+package ui
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+
+	"git.sr.ht/~rjarry/aerc/lib/templates"
+)
+
+// styledRune is a character of a string which contains the SGR sequences
+// produced by the style template function, with the style resulting from the
+// preceding sequences.
+type styledRune struct {
+	ch    rune
+	style tcell.Style
+}
+
+var escapeRegexp = regexp.MustCompile("(\x1B|" +
+	regexp.QuoteMeta(templates.StyleMarker) + ")(\\[[0-?]*[ -/]*[@-~])?")
+
+// parseStyled applies the SGR sequences of the style template function found
+// in s on top of base. Other escape sequences, which may come from untrusted
+// message headers, are dropped.
+func parseStyled(s string, base tcell.Style) []styledRune {
+	runes := make([]styledRune, 0, len(s))
+	style := base
+	for len(s) > 0 {
+		text := s
+		seq := ""
+		if loc := escapeRegexp.FindStringIndex(s); loc != nil {
+			text, seq, s = s[:loc[0]], s[loc[0]:loc[1]], s[loc[1]:]
+		} else {
+			s = ""
+		}
+		for _, ch := range text {
+			runes = append(runes, styledRune{ch: ch, style: style})
+		}
+		if strings.HasPrefix(seq, templates.StyleMarker+"[") &&
+			strings.HasSuffix(seq, "m") {
+			params := seq[len(templates.StyleMarker)+1 : len(seq)-1]
+			style = applySGR(style, base, params)
+		}
+	}
+	return runes
+}
+
+// applySGR changes style according to the parameters of an SGR sequence. The
+// reset parameters restore the attributes of base.
+func applySGR(style, base tcell.Style, params string) tcell.Style {
+	baseFg, baseBg, _ := base.Decompose()
+	var codes []int
+	for _, p := range strings.Split(params, ";") {
+		n, _ := strconv.Atoi(p)
+		codes = append(codes, n)
+	}
+	// color returns the extended color starting at codes[i] and the number
+	// of parameters it uses
+	color := func(i int) (tcell.Color, int) {
+		switch {
+		case i+2 < len(codes) && codes[i+1] == 5:
+			return tcell.PaletteColor(codes[i+2]), 2
+		case i+4 < len(codes) && codes[i+1] == 2:
+			return tcell.NewRGBColor(int32(codes[i+2]),
+				int32(codes[i+3]), int32(codes[i+4])), 4
+		}
+		return tcell.ColorDefault, len(codes)
+	}
+	for i := 0; i < len(codes); i++ {
+		switch n := codes[i]; {
+		case n == 0:
+			style = base
+		case n == 1:
+			style = style.Bold(true)
+		case n == 2:
+			style = style.Dim(true)
+		case n == 3:
+			style = style.Italic(true)
+		case n == 4:
+			style = style.Underline(true)
+		case n == 5:
+			style = style.Blink(true)
+		case n == 7:
+			style = style.Reverse(true)
+		case n == 22:
+			style = style.Bold(false).Dim(false)
+		case n == 23:
+			style = style.Italic(false)
+		case n == 24:
+			style = style.Underline(false)
+		case n == 25:
+			style = style.Blink(false)
+		case n == 27:
+			style = style.Reverse(false)
+		case n >= 30 && n <= 37:
+			style = style.Foreground(tcell.PaletteColor(n - 30))
+		case n == 38:
+			c, skip := color(i)
+			style = style.Foreground(c)
+			i += skip
+		case n == 39:
+			style = style.Foreground(baseFg)
+		case n >= 40 && n <= 47:
+			style = style.Background(tcell.PaletteColor(n - 40))
+		case n == 48:
+			c, skip := color(i)
+			style = style.Background(c)
+			i += skip
+		case n == 49:
+			style = style.Background(baseBg)
+		case n >= 90 && n <= 97:
+			style = style.Foreground(tcell.PaletteColor(n - 90 + 8))
+		case n >= 100 && n <= 107:
+			style = style.Background(tcell.PaletteColor(n - 100 + 8))
+		}
+	}
+	return style
+}
+
+func styledWidth(runes []styledRune) int {
+	width := 0
+	for _, r := range runes {
+		width += runewidth.RuneWidth(r.ch)
+	}
+	return width
+}
+
+// truncateStyled keeps the first characters of runes which fit in width,
+// followed by tail.
+func truncateStyled(runes []styledRune, width int, tail string) []styledRune {
+	width -= runewidth.StringWidth(tail)
+	w := 0
+	for i, r := range runes {
+		w += runewidth.RuneWidth(r.ch)
+		if w > width {
+			return appendStyled(runes[:i:i], tail, runes[i].style)
+		}
+	}
+	return runes
+}
+
+// truncateStyledHead keeps the last characters of runes which fit in width,
+// preceded by head.
+func truncateStyledHead(runes []styledRune, width int, head string) []styledRune {
+	width -= runewidth.StringWidth(head)
+	w := 0
+	for i := len(runes) - 1; i >= 0; i-- {
+		w += runewidth.RuneWidth(runes[i].ch)
+		if w > width {
+			return appendStyled(nil, head, runes[i].style, runes[i+1:]...)
+		}
+	}
+	return runes
+}
+
+// appendStyled appends the characters of s with the given style, followed by
+// more.
+func appendStyled(runes []styledRune, s string, style tcell.Style,
+	more ...styledRune,
+) []styledRune {
+	for _, ch := range s {
+		runes = append(runes, styledRune{ch: ch, style: style})
+	}
+	return append(runes, more...)
+}
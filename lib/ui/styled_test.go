@@ -0,0 +1,61 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+
+	"git.sr.ht/~rjarry/aerc/lib/templates"
+)
+
+func TestParseStyled(t *testing.T) {
+	base := tcell.StyleDefault
+	red := base.Foreground(tcell.ColorMaroon)
+	m := templates.StyleMarker
+
+	tests := []struct {
+		input  string
+		text   string
+		styles []tcell.Style
+	}{
+		{
+			input:  m + "[31mab" + m + "[0mc",
+			text:   "abc",
+			styles: []tcell.Style{red, red, base},
+		},
+		{
+			// escape sequences found in messages are not interpreted
+			input:  "a\x1b[31mb\x1bc",
+			text:   "abc",
+			styles: []tcell.Style{base, base, base},
+		},
+		{
+			// the marker cannot be forged without its random token
+			input:  "a\uFDD0[31mb",
+			text:   "a\uFDD0[31mb",
+			styles: []tcell.Style{base, base, base, base, base, base, base},
+		},
+		{
+			input:  "a" + m + "b",
+			text:   "ab",
+			styles: []tcell.Style{base, base},
+		},
+	}
+
+	for _, test := range tests {
+		runes := parseStyled(test.input, base)
+		text := ""
+		for _, r := range runes {
+			text += string(r.ch)
+		}
+		if text != test.text {
+			t.Errorf("%q: expected text %q, got %q", test.input, test.text, text)
+			continue
+		}
+		for i, r := range runes {
+			if r.style != test.styles[i] {
+				t.Errorf("%q: unexpected style at %d", test.input, i)
+			}
+		}
+	}
+}
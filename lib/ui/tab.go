@@ -120,6 +120,10 @@ func (tabs *Tabs) Replace(contentSrc Drawable, contentTarget Drawable, name stri
 	Invalidate()
 }
 
+func (tab *Tab) Pinned() bool {
+	return tab.pinned
+}
+
 func (tabs *Tabs) Get(index int) *Tab {
 	tabs.m.Lock()
 	defer tabs.m.Unlock()
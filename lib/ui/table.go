@@ -6,7 +6,6 @@ import (
 	"strings"
 
 	"git.sr.ht/~rjarry/aerc/config"
-	"git.sr.ht/~rjarry/aerc/lib/format"
 	"github.com/gdamore/tcell/v2"
 	"github.com/mattn/go-runewidth"
 )
@@ -21,6 +20,9 @@ type Table struct {
 	CustomDraw func(t *Table, row int, c *Context) bool
 	// Optional callback that allows returning a custom style for the row.
 	GetRowStyle func(t *Table, row int) tcell.Style
+	// Optional callback that allows returning a custom style for a cell.
+	// It defaults to the row style.
+	GetCellStyle func(t *Table, row int, col int) tcell.Style
 
 	// true if at least one column has WIDTH_FIT
 	autoFitWidths bool
@@ -67,11 +69,14 @@ func NewTable(
 		}
 	}
 	return Table{
-		Columns:       columns,
-		Width:         width,
-		Height:        height,
-		CustomDraw:    customDraw,
-		GetRowStyle:   getRowStyle,
+		Columns:     columns,
+		Width:       width,
+		Height:      height,
+		CustomDraw:  customDraw,
+		GetRowStyle: getRowStyle,
+		GetCellStyle: func(t *Table, row int, _ int) tcell.Style {
+			return t.GetRowStyle(t, row)
+		},
 		autoFitWidths: autoFitWidths,
 	}
 }
@@ -96,7 +101,7 @@ func (t *Table) computeWidths() {
 	if t.autoFitWidths {
 		for _, row := range t.Rows {
 			for c := range t.Columns {
-				w := runewidth.StringWidth(row.Cells[c])
+				w := styledWidth(parseStyled(row.Cells[c], tcell.StyleDefault))
 				if w > contentMaxWidths[c] {
 					contentMaxWidths[c] = w
 				}
@@ -158,34 +163,38 @@ func (t *Table) computeWidths() {
 
 var metaCharsRegexp = regexp.MustCompile(`[\t\r\f\n\v]`)
 
-func (col *Column) alignCell(cell string) string {
+// alignCell pads or truncates a cell to the width of the column. The ANSI SGR
+// sequences of the cell are applied on top of style.
+func (col *Column) alignCell(cell string, style tcell.Style) []styledRune {
 	cell = metaCharsRegexp.ReplaceAllString(cell, " ")
-	width := runewidth.StringWidth(cell)
+	runes := parseStyled(cell, style)
+	width := styledWidth(runes)
 
 	switch {
 	case col.Def.Flags.Has(config.ALIGN_LEFT):
 		if width < col.Width {
-			cell += strings.Repeat(" ", col.Width-width)
+			runes = appendStyled(runes, strings.Repeat(" ", col.Width-width), style)
 		} else if width > col.Width {
-			cell = runewidth.Truncate(cell, col.Width, "…")
+			runes = truncateStyled(runes, col.Width, "…")
 		}
 	case col.Def.Flags.Has(config.ALIGN_CENTER):
 		if width < col.Width {
 			padding := strings.Repeat(" ", col.Width-width)
 			l := len(padding) / 2
-			cell = padding[:l] + cell + padding[l:]
+			runes = appendStyled(appendStyled(nil, padding[:l], style, runes...),
+				padding[l:], style)
 		} else if width > col.Width {
-			cell = runewidth.Truncate(cell, col.Width, "…")
+			runes = truncateStyled(runes, col.Width, "…")
 		}
 	case col.Def.Flags.Has(config.ALIGN_RIGHT):
 		if width < col.Width {
-			cell = strings.Repeat(" ", col.Width-width) + cell
+			runes = appendStyled(nil, strings.Repeat(" ", col.Width-width), style, runes...)
 		} else if width > col.Width {
-			cell = format.TruncateHead(cell, col.Width, "…")
+			runes = truncateStyledHead(runes, col.Width, "…")
 		}
 	}
 
-	return cell
+	return runes
 }
 
 func (t *Table) Draw(ctx *Context) {
@@ -202,9 +211,12 @@ func (t *Table) Draw(ctx *Context) {
 				// column overflows screen width
 				continue
 			}
-			cell := col.alignCell(row.Cells[c])
-			style := t.GetRowStyle(t, r)
-			ctx.Printf(col.Offset, r, style, "%s%s", cell, col.Separator)
+			x := col.Offset
+			for _, sr := range col.alignCell(row.Cells[c], t.GetCellStyle(t, r, c)) {
+				ctx.SetCell(x, r, sr.ch, sr.style)
+				x += runewidth.RuneWidth(sr.ch)
+			}
+			ctx.Printf(x, r, t.GetRowStyle(t, r), "%s", col.Separator)
 		}
 	}
 }
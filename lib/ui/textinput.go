@@ -155,11 +155,24 @@ func (ti *TextInput) drawPopover(ctx *Context) {
 func (ti *TextInput) MouseEvent(localX int, localY int, event tcell.Event) {
 	if event, ok := event.(*tcell.EventMouse); ok {
 		if event.Buttons() == tcell.Button1 {
-			if localX >= len(ti.prompt)+1 && localX <= len(ti.text[ti.scroll:])+len(ti.prompt)+1 {
-				ti.index = localX - len(ti.prompt) - 1
-				ti.ensureScroll()
-				ti.Invalidate()
+			x := localX - runewidth.StringWidth(ti.prompt)
+			if x < 0 {
+				return
+			}
+			// place the cursor on the clicked rune, or after the
+			// last one when clicking past the end of the text
+			index := ti.scroll
+			for index < len(ti.text) {
+				w := runewidth.RuneWidth(ti.text[index])
+				if x < w {
+					break
+				}
+				x -= w
+				index++
 			}
+			ti.index = index
+			ti.ensureScroll()
+			ti.Invalidate()
 		}
 	}
 }
@@ -152,6 +152,8 @@ type BodyStructure struct {
 	Parts             []*BodyStructure
 	Disposition       string
 	DispositionParams map[string]string
+	// Size of the encoded body in bytes, 0 if unknown
+	Size uint32
 }
 
 // PartAtIndex returns the BodyStructure at the requested index
@@ -13,6 +13,8 @@ import (
 	"git.sr.ht/~rjarry/aerc/lib/marker"
 	"git.sr.ht/~rjarry/aerc/lib/sort"
 	"git.sr.ht/~rjarry/aerc/lib/statusline"
+	"git.sr.ht/~rjarry/aerc/lib/templates"
+	"git.sr.ht/~rjarry/aerc/lib/threadstate"
 	"git.sr.ht/~rjarry/aerc/lib/ui"
 	"git.sr.ht/~rjarry/aerc/log"
 	"git.sr.ht/~rjarry/aerc/models"
@@ -41,6 +43,14 @@ type AccountView struct {
 	splitDebounce *time.Timer
 	splitDir      string
 
+	// state of a restored session, applied once its folder is loaded
+	session *sessionRestore
+
+	// set for the views of layout panes, which follow a folder of the
+	// parent account on a connection of their own
+	parent *AccountView
+	folder string
+
 	// Check-mail ticker
 	ticker       *time.Ticker
 	checkingMail bool
@@ -56,6 +66,26 @@ func (acct *AccountView) UiConfig() *config.UIConfig {
 func NewAccountView(
 	aerc *Aerc, acct *config.AccountConfig,
 	host TabHost, deferLoop chan struct{},
+) (*AccountView, error) {
+	view, err := newAccountView(aerc, acct, acct.Name, host, deferLoop)
+	if err != nil {
+		return view, err
+	}
+	view.worker.PostAction(&types.Configure{Config: acct}, nil)
+	view.worker.PostAction(&types.Connect{}, nil)
+	view.SetStatus(statusline.ConnectionActivity("Connecting..."))
+	if acct.CheckMail.Minutes() > 0 {
+		view.CheckMailTimer(acct.CheckMail)
+	}
+
+	return view, nil
+}
+
+// newAccountView creates the view of acct and starts a worker with the given
+// name. The worker is neither configured nor connected.
+func newAccountView(
+	aerc *Aerc, acct *config.AccountConfig, name string,
+	host TabHost, deferLoop chan struct{},
 ) (*AccountView, error) {
 	acctUiConf := config.Ui.ForAccount(acct.Name)
 
@@ -70,13 +100,11 @@ func NewAccountView(
 	view.grid = ui.NewGrid().Rows([]ui.GridSpec{
 		{Strategy: ui.SIZE_WEIGHT, Size: ui.Const(1)},
 	}).Columns([]ui.GridSpec{
-		{Strategy: ui.SIZE_EXACT, Size: func() int {
-			return view.UiConfig().SidebarWidth
-		}},
+		{Strategy: ui.SIZE_EXACT, Size: view.sidebarWidth},
 		{Strategy: ui.SIZE_WEIGHT, Size: ui.Const(1)},
 	})
 
-	worker, err := worker.NewWorker(acct.Source, acct.Name)
+	worker, err := worker.NewWorker(acct.Source, name)
 	if err != nil {
 		host.SetError(fmt.Sprintf("%s: %s", acct.Name, err))
 		log.Errorf("%s: %v", acct.Name, err)
@@ -84,7 +112,7 @@ func NewAccountView(
 	}
 	view.worker = worker
 
-	view.dirlist = NewDirectoryList(acct, worker)
+	view.dirlist = NewDirectoryList(aerc, acct, worker)
 	if acctUiConf.SidebarWidth > 0 {
 		view.grid.AddChild(ui.NewBordered(view.dirlist, ui.BORDER_RIGHT, acctUiConf))
 	}
@@ -102,13 +130,6 @@ func NewAccountView(
 		worker.Backend.Run()
 	}()
 
-	worker.PostAction(&types.Configure{Config: acct}, nil)
-	worker.PostAction(&types.Connect{}, nil)
-	view.SetStatus(statusline.ConnectionActivity("Connecting..."))
-	if acct.CheckMail.Minutes() > 0 {
-		view.CheckMailTimer(acct.CheckMail)
-	}
-
 	return view, nil
 }
 
@@ -119,12 +140,42 @@ func (acct *AccountView) SetStatus(setters ...statusline.SetStateFunc) {
 	acct.UpdateStatus()
 }
 
+// UpdateStatus clears the status message so that the statusline columns of
+// the account are displayed.
 func (acct *AccountView) UpdateStatus() {
 	if acct.isSelected() {
-		acct.host.SetStatus(acct.state.StatusLine(acct.SelectedDirectory()))
+		acct.host.SetStatus("")
 	}
 }
 
+// StatusData returns the template data of the statusline columns.
+func (acct *AccountView) StatusData() *templates.TemplateData {
+	uiConfig := acct.UiConfig()
+	data := templates.NewTemplateData(
+		acct.acct.From,
+		acct.acct.Aliases,
+		acct.Name(),
+		acct.SelectedDirectory(),
+		uiConfig.TimestampFormat,
+		uiConfig.ThisDayTimeFormat,
+		uiConfig.ThisWeekTimeFormat,
+		uiConfig.ThisYearTimeFormat,
+		uiConfig.IconAttachment,
+	)
+	status := acct.state.Status(acct.SelectedDirectory())
+	if store := acct.Store(); store != nil {
+		if !store.DirInfo.AccurateCounts {
+			store.DirInfo.Recent, store.DirInfo.Unseen = countRUE(store)
+		}
+		status.Total = store.DirInfo.Exists
+		status.Unread = store.DirInfo.Unseen
+		status.Recent = store.DirInfo.Recent
+		status.Marked = len(store.Marker().Marked())
+	}
+	data.SetStatus(status)
+	return data
+}
+
 func (acct *AccountView) PushStatus(status string, expiry time.Duration) {
 	acct.aerc.PushStatus(fmt.Sprintf("%s: %s", acct.acct.Name, status), expiry)
 }
@@ -154,9 +205,6 @@ func (acct *AccountView) Invalidate() {
 }
 
 func (acct *AccountView) Draw(ctx *ui.Context) {
-	if acct.state.SetWidth(ctx.Width()) {
-		acct.UpdateStatus()
-	}
 	acct.grid.Draw(ctx)
 }
 
@@ -230,8 +278,14 @@ func (acct *AccountView) onMessage(msg types.WorkerMessage) {
 			acct.SetStatus(statusline.ConnectionActivity("Listing mailboxes..."))
 			log.Tracef("Listing mailboxes...")
 			acct.dirlist.UpdateList(func(dirs []string) {
-				var dir string
+				dir := acct.sessionFolder(dirs)
+				if acct.parent != nil {
+					dir = acct.folder
+				}
 				for _, _dir := range dirs {
+					if dir != "" {
+						break
+					}
 					if _dir == acct.acct.Default {
 						dir = _dir
 						break
@@ -246,7 +300,8 @@ func (acct *AccountView) onMessage(msg types.WorkerMessage) {
 				acct.msglist.SetInitDone()
 				log.Infof("[%s] connected.", acct.acct.Name)
 				acct.SetStatus(statusline.SetConnected(true))
-				acct.newConn = true
+				// the parent account checks the mail
+				acct.newConn = acct.parent == nil
 			})
 		case *types.Disconnect:
 			acct.dirlist.ClearList()
@@ -254,6 +309,9 @@ func (acct *AccountView) onMessage(msg types.WorkerMessage) {
 			log.Infof("[%s] disconnected.", acct.acct.Name)
 			acct.SetStatus(statusline.SetConnected(false))
 		case *types.OpenDirectory:
+			if acct.parent != nil {
+				acct.folder = acct.dirlist.Selected()
+			}
 			if store, ok := acct.dirlist.SelectedMsgStore(); ok {
 				// If we've opened this dir before, we can re-render it from
 				// memory while we wait for the update and the UI feels
@@ -277,6 +335,8 @@ func (acct *AccountView) onMessage(msg types.WorkerMessage) {
 			store.Update(msg)
 		} else {
 			name := msg.Info.Name
+			// the parent account of a pane notifies the new messages
+			notify := acct.parent == nil
 			store = lib.NewMessageStore(acct.worker, msg.Info,
 				acct.GetSortCriteria(),
 				acct.dirlist.UiConfig(name).ThreadingEnabled,
@@ -286,15 +346,19 @@ func (acct *AccountView) onMessage(msg types.WorkerMessage) {
 				acct.dirlist.UiConfig(name).ReverseThreadOrder,
 				acct.dirlist.UiConfig(name).SortThreadSiblings,
 				func(msg *models.MessageInfo) {
-					config.Triggers.ExecNewEmail(acct.acct, msg)
+					if notify {
+						config.Triggers.ExecNewEmail(acct.acct, msg)
+					}
 				}, func() {
-					if acct.dirlist.UiConfig(name).NewMessageBell {
+					if notify && acct.dirlist.UiConfig(name).NewMessageBell {
 						acct.host.Beep()
 					}
 				},
 				acct.updateSplitView,
 			)
 			store.SetMarker(marker.New(store))
+			store.SetPrefetch(acct.dirlist.UiConfig(name).PrefetchCount,
+				acct.dirlist.UiConfig(name).PrefetchMaxSize)
 			acct.dirlist.SetMsgStore(msg.Info.Name, store)
 		}
 	case *types.DirectoryContents:
@@ -304,6 +368,7 @@ func (acct *AccountView) onMessage(msg types.WorkerMessage) {
 			}
 			store.Update(msg)
 			acct.SetStatus(statusline.Threading(store.ThreadedView()))
+			acct.sessionFolderLoaded(store)
 		}
 		if acct.newConn && len(msg.Uids) == 0 {
 			acct.checkMailOnStartup()
@@ -315,6 +380,7 @@ func (acct *AccountView) onMessage(msg types.WorkerMessage) {
 			}
 			store.Update(msg)
 			acct.SetStatus(statusline.Threading(store.ThreadedView()))
+			acct.sessionFolderLoaded(store)
 		}
 		if acct.newConn && len(msg.Threads) == 0 {
 			acct.checkMailOnStartup()
@@ -326,6 +392,18 @@ func (acct *AccountView) onMessage(msg types.WorkerMessage) {
 	case *types.MessageInfo:
 		if store, ok := acct.dirlist.SelectedMsgStore(); ok {
 			store.Update(msg)
+			if acct.parent == nil {
+				acct.archiveMuted(store, msg.Info.Uid)
+			}
+		}
+	case *types.NewMessage:
+		// messages of the selected folder are handled by its store
+		if acct.parent == nil && msg.Directory != acct.dirlist.Selected() &&
+			threadstate.Get(msg.Info) != threadstate.Muted {
+			config.Triggers.ExecNewEmail(acct.acct, msg.Info)
+			if acct.dirlist.UiConfig(msg.Directory).NewMessageBell {
+				acct.host.Beep()
+			}
 		}
 	case *types.MessagesDeleted:
 		if store, ok := acct.dirlist.SelectedMsgStore(); ok {
@@ -393,6 +471,30 @@ func (acct *AccountView) updateDirCounts(destination string, uids []uint32) {
 	}
 }
 
+// archiveMuted marks an unread message of a muted thread as read and moves it
+// to the archive folder. Only messages of the selected folder are archived,
+// the workers cannot act on other folders.
+func (acct *AccountView) archiveMuted(store *lib.MessageStore, uid uint32) {
+	msg := store.Messages[uid]
+	if msg == nil || msg.Flags.Has(models.SeenFlag) ||
+		threadstate.Get(msg) != threadstate.Muted {
+		return
+	}
+	archive := acct.acct.Archive
+	if _, deleted := store.Deleted[uid]; deleted ||
+		archive == "" || acct.dirlist.Selected() == archive {
+		return
+	}
+	log.Debugf("[%s] archiving muted message %d", acct.acct.Name, uid)
+	uids := []uint32{uid}
+	store.Flag(uids, models.SeenFlag, true, nil)
+	store.Move(uids, archive, true, func(msg types.WorkerMessage) {
+		if msg, ok := msg.(*types.Error); ok {
+			acct.PushError(msg.Error)
+		}
+	})
+}
+
 func (acct *AccountView) GetSortCriteria() []*types.SortCriterion {
 	if len(acct.UiConfig().Sort) == 0 {
 		return nil
@@ -473,6 +575,15 @@ func (acct *AccountView) CheckMailTimer(d time.Duration) {
 	}()
 }
 
+// sidebarWidth is the width of the folder list of the account. It is hidden
+// when the global sidebar displays the folders of all accounts.
+func (acct *AccountView) sidebarWidth() int {
+	if config.Ui.GlobalSidebar {
+		return 0
+	}
+	return acct.UiConfig().SidebarWidth
+}
+
 func (acct *AccountView) closeSplit() {
 	if acct.split != nil {
 		acct.split.Close()
@@ -483,9 +594,7 @@ func (acct *AccountView) closeSplit() {
 	acct.grid = ui.NewGrid().Rows([]ui.GridSpec{
 		{Strategy: ui.SIZE_WEIGHT, Size: ui.Const(1)},
 	}).Columns([]ui.GridSpec{
-		{Strategy: ui.SIZE_EXACT, Size: func() int {
-			return acct.UiConfig().SidebarWidth
-		}},
+		{Strategy: ui.SIZE_EXACT, Size: acct.sidebarWidth},
 		{Strategy: ui.SIZE_WEIGHT, Size: ui.Const(1)},
 	})
 
@@ -549,9 +658,7 @@ func (acct *AccountView) Split(n int) error {
 		{Strategy: ui.SIZE_EXACT, Size: func() int { return acct.SplitSize() + 1 }},
 		{Strategy: ui.SIZE_WEIGHT, Size: ui.Const(1)},
 	}).Columns([]ui.GridSpec{
-		{Strategy: ui.SIZE_EXACT, Size: func() int {
-			return acct.UiConfig().SidebarWidth
-		}},
+		{Strategy: ui.SIZE_EXACT, Size: acct.sidebarWidth},
 		{Strategy: ui.SIZE_WEIGHT, Size: ui.Const(1)},
 	})
 
@@ -574,9 +681,7 @@ func (acct *AccountView) Vsplit(n int) error {
 	acct.grid = ui.NewGrid().Rows([]ui.GridSpec{
 		{Strategy: ui.SIZE_WEIGHT, Size: ui.Const(1)},
 	}).Columns([]ui.GridSpec{
-		{Strategy: ui.SIZE_EXACT, Size: func() int {
-			return acct.UiConfig().SidebarWidth
-		}},
+		{Strategy: ui.SIZE_EXACT, Size: acct.sidebarWidth},
 		{Strategy: ui.SIZE_EXACT, Size: acct.SplitSize},
 		{Strategy: ui.SIZE_WEIGHT, Size: ui.Const(1)},
 	})
@@ -13,11 +13,11 @@ import (
 	"github.com/ProtonMail/go-crypto/openpgp"
 	"github.com/emersion/go-message/mail"
 	"github.com/gdamore/tcell/v2"
-	"github.com/google/shlex"
 
 	"git.sr.ht/~rjarry/aerc/config"
 	"git.sr.ht/~rjarry/aerc/lib"
 	"git.sr.ht/~rjarry/aerc/lib/crypto"
+	"git.sr.ht/~rjarry/aerc/lib/parse"
 	"git.sr.ht/~rjarry/aerc/lib/ui"
 	"git.sr.ht/~rjarry/aerc/log"
 	"git.sr.ht/~rjarry/aerc/worker/types"
@@ -26,7 +26,7 @@ import (
 type Aerc struct {
 	accounts    map[string]*AccountView
 	cmd         func(cmd []string) error
-	cmdHistory  lib.History
+	history     func(name string) lib.History
 	complete    func(cmd string) []string
 	focused     ui.Interactive
 	grid        *ui.Grid
@@ -40,6 +40,14 @@ type Aerc struct {
 	ui          *ui.UI
 	beep        func() error
 	dialog      ui.DrawableInteractive
+	dialogCtx   *ui.Context
+	sidebar     *Sidebar
+	buttons     tcell.ButtonMask
+	presses     int
+	// views of the layout panes by worker name, and the ones of the
+	// closed layouts which can be reused
+	panes     map[string]*AccountView
+	idlePanes []*AccountView
 
 	Crypto crypto.Provider
 }
@@ -47,12 +55,12 @@ type Aerc struct {
 type Choice struct {
 	Key     string
 	Text    string
-	Command []string
+	Command string
 }
 
 func NewAerc(
 	crypto crypto.Provider, cmd func(cmd []string) error,
-	complete func(cmd string) []string, cmdHistory lib.History,
+	complete func(cmd string) []string, history func(string) lib.History,
 	deferLoop chan struct{},
 ) *Aerc {
 	tabs := ui.NewTabs(config.Ui)
@@ -66,16 +74,23 @@ func NewAerc(
 		{Strategy: ui.SIZE_WEIGHT, Size: ui.Const(1)},
 		{Strategy: ui.SIZE_EXACT, Size: ui.Const(1)},
 	}).Columns([]ui.GridSpec{
+		{Strategy: ui.SIZE_EXACT, Size: func() int {
+			if config.Ui.GlobalSidebar {
+				return config.Ui.SidebarWidth
+			}
+			return 0
+		}},
 		{Strategy: ui.SIZE_WEIGHT, Size: ui.Const(1)},
 	})
-	grid.AddChild(tabs.TabStrip)
-	grid.AddChild(tabs.TabContent).At(1, 0)
-	grid.AddChild(statusbar).At(2, 0)
+	grid.AddChild(tabs.TabStrip).Span(1, 2)
+	grid.AddChild(tabs.TabContent).At(1, 1)
+	grid.AddChild(statusbar).At(2, 0).Span(1, 2)
 
 	aerc := &Aerc{
 		accounts:   make(map[string]*AccountView),
+		panes:      make(map[string]*AccountView),
 		cmd:        cmd,
-		cmdHistory: cmdHistory,
+		history:    history,
 		complete:   complete,
 		grid:       grid,
 		statusbar:  statusbar,
@@ -86,6 +101,8 @@ func NewAerc(
 	}
 
 	statusline.SetAerc(aerc)
+	aerc.sidebar = NewSidebar(aerc)
+	grid.AddChild(aerc.sidebar).At(1, 0)
 	config.Triggers.ExecuteCommand = cmd
 
 	for _, acct := range config.Accounts {
@@ -123,34 +140,50 @@ func NewAerc(
 			content.Close(nil)
 		case *MessageViewer:
 			aerc.RemoveTab(content)
+		case *Layout:
+			aerc.RemoveTab(content)
+			content.Close()
 		}
 	}
 
+	var deprecated []string
 	if config.Ui.IndexFormat != "" {
-		ini := config.ColumnDefsToIni(
-			config.Ui.IndexColumns, "index-columns")
-		title := "DEPRECATION WARNING"
-		text := `
-The index-format setting is deprecated. It has been replaced by index-columns.
+		deprecated = append(deprecated, deprecationWarning(
+			"index-format", "index-columns", "ui", config.ColumnDefsToIni(
+				config.Ui.IndexColumns, "index-columns")))
+	}
+	if config.Statusline.RenderFormat != "" {
+		deprecated = append(deprecated, deprecationWarning(
+			"render-format", "status-columns", "statusline",
+			config.ColumnDefsToIni(
+				config.Statusline.StatusColumns, "status-columns")))
+	}
+	if len(deprecated) > 0 {
+		aerc.AddDialog(NewSelectorDialog(
+			"DEPRECATION WARNING", strings.Join(deprecated, ""),
+			[]string{"OK"}, 0,
+			aerc.SelectedAccountUiConfig(),
+			func(string, error) { aerc.CloseDialog() },
+		))
+	}
+
+	return aerc
+}
+
+func deprecationWarning(setting, replacement, section, ini string) string {
+	return `
+The ` + setting + ` setting is deprecated. It has been replaced by ` + replacement + `.
 
 Your configuration in this instance was automatically converted to:
 
-[ui]
+[` + section + `]
 ` + ini + `
 Your configuration file was not changed. To make this change permanent and to
 dismiss this deprecation warning on launch, copy the above lines into aerc.conf
-and remove index-format from it. See aerc-config(5) for more details.
+and remove ` + setting + ` from it. See aerc-config(5) for more details.
 
-index-format will be removed in aerc 0.17.
+` + setting + ` will be removed in aerc 0.17.
 `
-		aerc.AddDialog(NewSelectorDialog(
-			title, text, []string{"OK"}, 0,
-			aerc.SelectedAccountUiConfig(),
-			func(string, error) { aerc.CloseDialog() },
-		))
-	}
-
-	return aerc
 }
 
 func (aerc *Aerc) OnBeep(f func() error) {
@@ -170,6 +203,8 @@ func (aerc *Aerc) Beep() {
 func (aerc *Aerc) HandleMessage(msg types.WorkerMessage) {
 	if acct, ok := aerc.accounts[msg.Account()]; ok {
 		acct.onMessage(msg)
+	} else if pane, ok := aerc.panes[msg.Account()]; ok {
+		pane.onMessage(msg)
 	}
 }
 
@@ -198,12 +233,12 @@ func (aerc *Aerc) Draw(ctx *ui.Context) {
 		if w, h := ctx.Width(), ctx.Height(); w > 8 && h > 4 {
 			if d, ok := aerc.dialog.(Dialog); ok {
 				start, height := d.ContextHeight()
-				aerc.dialog.Draw(
-					ctx.Subcontext(4, start(h),
-						w-8, height(h)))
+				aerc.dialogCtx = ctx.Subcontext(4, start(h),
+					w-8, height(h))
 			} else {
-				aerc.dialog.Draw(ctx.Subcontext(4, h/2-2, w-8, 4))
+				aerc.dialogCtx = ctx.Subcontext(4, h/2-2, w-8, 4)
 			}
+			aerc.dialog.Draw(aerc.dialogCtx)
 		}
 	}
 }
@@ -251,6 +286,13 @@ func (aerc *Aerc) getBindings() *config.KeyBindings {
 	case *AccountView:
 		binds := config.Binds.MessageList.ForAccount(selectedAccountName)
 		return binds.ForFolder(view.SelectedDirectory())
+	case *Layout:
+		binds := config.Binds.MessageList.ForAccount(selectedAccountName)
+		binds = binds.ForFolder(view.SelectedDirectory())
+		merged := config.MergeBindings(config.Binds.MessageListLayout, binds)
+		merged.ExKey = binds.ExKey
+		merged.Globals = binds.Globals
+		return merged
 	case *AccountWizard:
 		return config.Binds.AccountWizard
 	case *Composer:
@@ -301,7 +343,18 @@ func (aerc *Aerc) simulate(strokes []config.KeyStroke) {
 }
 
 func (aerc *Aerc) Event(event tcell.Event) bool {
+	if mouse, ok := event.(*tcell.EventMouse); ok {
+		aerc.trackPress(mouse)
+	}
 	if aerc.dialog != nil {
+		mouse, ok := event.(*tcell.EventMouse)
+		handler, isHandler := aerc.dialog.(ui.MouseHandler)
+		if ok && isHandler && aerc.dialogCtx != nil {
+			x, y := mouse.Position()
+			handler.MouseEvent(x-aerc.dialogCtx.X(),
+				y-aerc.dialogCtx.Y(), event)
+			return true
+		}
 		return aerc.dialog.Event(event)
 	}
 
@@ -445,6 +498,8 @@ func (aerc *Aerc) account(d ui.Drawable) *AccountView {
 	switch tab := d.(type) {
 	case *AccountView:
 		return tab
+	case *Layout:
+		return tab.SelectedAccount()
 	case *MessageViewer:
 		return tab.SelectedAccount()
 	case *Composer:
@@ -572,6 +627,19 @@ func (aerc *Aerc) PushSuccess(text string) *StatusMessage {
 	return aerc.statusline.PushSuccess(text)
 }
 
+// Sidebar returns the sidebar displayed when global-sidebar is enabled
+func (aerc *Aerc) Sidebar() *Sidebar {
+	return aerc.sidebar
+}
+
+// PickFolder lets the user choose a folder of acct in the global sidebar
+func (aerc *Aerc) PickFolder(acct *AccountView, onPick func(string)) error {
+	if !config.Ui.GlobalSidebar {
+		return errors.New("global-sidebar is not enabled")
+	}
+	return aerc.sidebar.Pick(acct, onPick)
+}
+
 func (aerc *Aerc) focus(item ui.Interactive) {
 	if aerc.focused == item {
 		return
@@ -602,35 +670,75 @@ func (aerc *Aerc) BeginExCommand(cmd string) {
 			return aerc.complete(cmd), ""
 		}
 	}
+	history := aerc.history(aerc.historyContext())
 	exline := NewExLine(cmd, func(cmd string) {
-		parts, err := shlex.Split(cmd)
-		if err != nil {
-			aerc.PushError(err.Error())
-		}
-		err = aerc.cmd(parts)
+		err := aerc.RunCommandLine(cmd)
 		if err != nil {
 			aerc.PushError(err.Error())
 		}
 		// only add to history if this is an unsimulated command,
 		// ie one not executed from a keybinding
 		if aerc.simulating == 0 {
-			aerc.cmdHistory.Add(cmd)
+			history.Add(cmd)
 		}
 	}, func() {
 		aerc.statusbar.Pop()
 		aerc.focus(previous)
-	}, tabComplete, aerc.cmdHistory)
+	}, tabComplete, history)
 	aerc.statusbar.Push(exline)
 	aerc.focus(exline)
 }
 
+// historyContext returns the name of the command history to use for the
+// selected tab.
+func (aerc *Aerc) historyContext() string {
+	switch aerc.SelectedTabContent().(type) {
+	case *AccountView, *Layout:
+		return lib.HistoryAccount
+	case *Composer:
+		return lib.HistoryCompose
+	case *MessageViewer:
+		return lib.HistoryMsgView
+	case *Terminal:
+		return lib.HistoryTerminal
+	default:
+		return lib.HistoryGlobal
+	}
+}
+
+// RunCommand executes a command in the context of the selected tab, as if it
+// had been entered in the command line.
+func (aerc *Aerc) RunCommand(cmd []string) error {
+	return aerc.cmd(cmd)
+}
+
+// RunCommandLine executes a command line which may contain multiple commands
+// separated by ;, && or ||.
+func (aerc *Aerc) RunCommandLine(line string) error {
+	chain, err := parse.CommandLine(line)
+	if err != nil {
+		return err
+	}
+	if len(chain) == 0 {
+		return errors.New("Expected a command.")
+	}
+	return chain.Run(aerc.cmd)
+}
+
+// CompleteCommand returns the completions for a partial command line.
+func (aerc *Aerc) CompleteCommand(cmd string) []string {
+	return aerc.complete(cmd)
+}
+
 func (aerc *Aerc) PushPrompt(prompt *ExLine) {
 	aerc.prompts.Push(prompt)
 }
 
 func (aerc *Aerc) RegisterPrompt(prompt string, cmd []string) {
+	history := aerc.history(lib.HistoryPrompt)
 	p := NewPrompt(prompt, func(text string) {
 		if text != "" {
+			history.Add(text)
 			cmd = append(cmd, text)
 		}
 		err := aerc.cmd(cmd)
@@ -640,11 +748,12 @@ func (aerc *Aerc) RegisterPrompt(prompt string, cmd []string) {
 	}, func(cmd string) ([]string, string) {
 		return nil, "" // TODO: completions
 	})
+	p.cmdHistory = history
 	aerc.prompts.Push(p)
 }
 
 func (aerc *Aerc) RegisterChoices(choices []Choice) {
-	cmds := make(map[string][]string)
+	cmds := make(map[string]string)
 	texts := []string{}
 	for _, c := range choices {
 		text := fmt.Sprintf("[%s] %s", c.Key, c.Text)
@@ -660,7 +769,7 @@ func (aerc *Aerc) RegisterChoices(choices []Choice) {
 		if !ok {
 			return
 		}
-		err := aerc.cmd(cmd)
+		err := aerc.RunCommandLine(cmd)
 		if err != nil {
 			aerc.PushError(err.Error())
 		}
@@ -792,7 +901,14 @@ func (aerc *Aerc) Mbox(source string) error {
 
 func (aerc *Aerc) CloseBackends() error {
 	var returnErr error
+	var views []*AccountView
 	for _, acct := range aerc.accounts {
+		views = append(views, acct)
+	}
+	for _, pane := range aerc.panes {
+		views = append(views, pane)
+	}
+	for _, acct := range views {
 		var raw interface{} = acct.worker.Backend
 		c, ok := raw.(io.Closer)
 		if !ok {
@@ -814,9 +930,57 @@ func (aerc *Aerc) AddDialog(d ui.DrawableInteractive) {
 
 func (aerc *Aerc) CloseDialog() {
 	aerc.dialog = nil
+	aerc.dialogCtx = nil
 	aerc.Invalidate()
 }
 
+// trackPress counts the mouse button presses. Widgets only receive the events
+// that happen over them and may miss the release of a button. They compare
+// MousePress with the press they saw last to tell a new click from a motion
+// with the button held.
+func (aerc *Aerc) trackPress(event *tcell.EventMouse) {
+	buttons := event.Buttons() & (tcell.Button1 | tcell.Button2 | tcell.Button3)
+	if buttons&^aerc.buttons != 0 {
+		aerc.presses++
+	}
+	aerc.buttons = buttons
+}
+
+// MousePress returns the number of the current or last mouse button press
+func (aerc *Aerc) MousePress() int {
+	return aerc.presses
+}
+
+// ContextMenu opens a dialog which lists the given actions. The command line
+// of the chosen one is executed. If it ends with a space, it is prefilled in
+// the command line instead, so that its arguments can be entered.
+func (aerc *Aerc) ContextMenu(title string, choices []Choice) {
+	texts := make([]string, 0, len(choices))
+	for _, c := range choices {
+		texts = append(texts, c.Text)
+	}
+	aerc.AddDialog(NewSelectorDialog(
+		title, "", texts, 0, aerc.SelectedAccountUiConfig(),
+		func(option string, err error) {
+			aerc.CloseDialog()
+			if err != nil {
+				return
+			}
+			for _, c := range choices {
+				if c.Text != option {
+					continue
+				}
+				if strings.HasSuffix(c.Command, " ") {
+					aerc.BeginExCommand(c.Command)
+				} else if err := aerc.RunCommandLine(c.Command); err != nil {
+					aerc.PushError(err.Error())
+				}
+				return
+			}
+		},
+	))
+}
+
 func (aerc *Aerc) GetPassword(title string, prompt string) (chText chan string, chErr chan error) {
 	chText = make(chan string, 1)
 	chErr = make(chan error, 1)
@@ -60,6 +60,10 @@ type Composer struct {
 
 	onClose []func(ti *Composer)
 
+	// base path of the autosaved draft, without extension
+	recovery     string
+	autosaveDone chan struct{}
+
 	width int
 
 	textParts []*lib.Part
@@ -116,6 +120,7 @@ func NewComposer(
 		return nil, err
 	}
 
+	c.startAutosave()
 	c.ShowTerminal()
 
 	return c, nil
@@ -618,6 +623,7 @@ func (c *Composer) Close() {
 	for _, onClose := range c.onClose {
 		onClose(c)
 	}
+	c.stopAutosave()
 	if c.email != nil {
 		path := c.email.Name()
 		c.email.Close()
@@ -965,6 +971,7 @@ func (c *Composer) termClosed(err error) {
 	if c.focused >= len(c.focusable) {
 		c.focused = len(c.focusable) - 1
 	}
+	c.Autosave()
 }
 
 func (c *Composer) ShowTerminal() {
@@ -1213,7 +1220,9 @@ func (he *headerEditor) MouseEvent(localX int, localY int, event tcell.Event) {
 			he.focused = true
 		}
 
-		width := runewidth.StringWidth(he.name + " ")
+		name := textproto.CanonicalMIMEHeaderKey(he.name)
+		// same width as the label drawn in Draw
+		width := runewidth.StringWidth(name+":") + 1
 		if localX >= width {
 			he.input.MouseEvent(localX-width, localY, event)
 		}
@@ -48,6 +48,7 @@ type DirectoryLister interface {
 
 type DirectoryList struct {
 	Scrollable
+	aerc             *Aerc
 	acctConf         *config.AccountConfig
 	store            *lib.DirStore
 	dirs             []string
@@ -59,12 +60,13 @@ type DirectoryList struct {
 	skipSelectCancel context.CancelFunc
 }
 
-func NewDirectoryList(acctConf *config.AccountConfig,
+func NewDirectoryList(aerc *Aerc, acctConf *config.AccountConfig,
 	worker *types.Worker,
 ) DirectoryLister {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	dirlist := &DirectoryList{
+		aerc:             aerc,
 		acctConf:         acctConf,
 		store:            lib.NewDirStore(),
 		worker:           worker,
@@ -339,6 +341,12 @@ func (dirlist *DirectoryList) MouseEvent(localX int, localY int, event tcell.Eve
 			if ok {
 				dirlist.Select(clickedDir)
 			}
+		case tcell.Button3:
+			clickedDir, ok := dirlist.Clicked(localX, localY)
+			if ok {
+				dirlist.Select(clickedDir)
+				dirlist.contextMenu()
+			}
 		case tcell.WheelDown:
 			dirlist.Next()
 		case tcell.WheelUp:
@@ -347,12 +355,25 @@ func (dirlist *DirectoryList) MouseEvent(localX int, localY int, event tcell.Eve
 	}
 }
 
+// contextMenu opens the actions which can be run on the selected folder
+func (dirlist *DirectoryList) contextMenu() {
+	if dirlist.aerc == nil {
+		return
+	}
+	dirlist.aerc.ContextMenu("Folder", []Choice{
+		{Text: "check mail", Command: "check-mail"},
+		{Text: "new folder...", Command: "mkdir "},
+		{Text: "export to mbox...", Command: "export-mbox "},
+		{Text: "import from mbox...", Command: "import-mbox "},
+	})
+}
+
 func (dirlist *DirectoryList) Clicked(x int, y int) (string, bool) {
 	if dirlist.dirs == nil || len(dirlist.dirs) == 0 {
 		return "", false
 	}
 	for i, name := range dirlist.dirs {
-		if i == y {
+		if i == y+dirlist.Scroll() {
 			return name, true
 		}
 	}
@@ -136,6 +136,12 @@ func (dt *DirectoryTree) MouseEvent(localX int, localY int, event tcell.Event) {
 			if ok {
 				dt.Select(clickedDir)
 			}
+		case tcell.Button3:
+			clickedDir, ok := dt.Clicked(localX, localY)
+			if ok {
+				dt.Select(clickedDir)
+				dt.contextMenu()
+			}
 		case tcell.WheelDown:
 			dt.NextPrev(1)
 		case tcell.WheelUp:
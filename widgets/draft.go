@@ -0,0 +1,186 @@
+package widgets
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/emersion/go-message"
+	"github.com/emersion/go-message/mail"
+	"github.com/emersion/go-message/textproto"
+
+	"git.sr.ht/~rjarry/aerc/lib"
+	"git.sr.ht/~rjarry/aerc/log"
+)
+
+// Draft is the state of a composer saved on disk to restore it after aerc
+// has been restarted. The header and the text body are stored in File, the
+// attachments which are not files, such as forwarded parts, are stored in
+// separate files.
+type Draft struct {
+	Account     string       `json:"account"`
+	File        string       `json:"file"`
+	Attachments []string     `json:"attachments,omitempty"`
+	Parts       []*DraftPart `json:"parts,omitempty"`
+	Sign        bool         `json:"sign,omitempty"`
+	Encrypt     bool         `json:"encrypt,omitempty"`
+	AttachKey   bool         `json:"attach-key,omitempty"`
+}
+
+type DraftPart struct {
+	Name     string            `json:"name"`
+	MimeType string            `json:"mime-type"`
+	Params   map[string]string `json:"params,omitempty"`
+	File     string            `json:"file"`
+}
+
+// SaveDraft writes the header, the body and the attachments of the composer
+// to path. The attachments which are not files are written next to it. The
+// files of a previous save are replaced atomically.
+func (c *Composer) SaveDraft(path string) (*Draft, error) {
+	for _, editor := range c.editors {
+		editor.storeValue()
+	}
+	body, err := os.ReadFile(c.email.Name())
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := textproto.WriteHeader(&buf, c.header.Header.Header); err != nil {
+		return nil, err
+	}
+	buf.Write(body)
+	if err := writeFile(path, buf.Bytes()); err != nil {
+		return nil, err
+	}
+
+	draft := &Draft{
+		Account:   c.acct.Name(),
+		File:      path,
+		Sign:      c.sign,
+		Encrypt:   c.encrypt,
+		AttachKey: c.attachKey,
+	}
+	for i, a := range c.attachments {
+		switch a := a.(type) {
+		case *lib.FileAttachment:
+			draft.Attachments = append(draft.Attachments, a.Name())
+		case *lib.PartAttachment:
+			part := a.Part()
+			file := fmt.Sprintf("%s.%d", path, i)
+			if err := writeFile(file, part.Data); err != nil {
+				return nil, err
+			}
+			draft.Parts = append(draft.Parts, &DraftPart{
+				Name:     a.Name(),
+				MimeType: part.MimeType,
+				Params:   part.Params,
+				File:     file,
+			})
+		}
+	}
+	return draft, nil
+}
+
+// writeFile writes data to a temporary file which is then renamed to path, so
+// that the previous contents of path are kept if aerc crashes while writing.
+func writeFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Remove deletes the files of the draft
+func (d *Draft) Remove() {
+	files := []string{d.File}
+	for _, p := range d.Parts {
+		files = append(files, p.File)
+	}
+	for _, file := range files {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			log.Warnf("failed to remove draft: %v", err)
+		}
+	}
+}
+
+// read returns the header and the body of the draft
+func (d *Draft) read() (*mail.Header, []byte, error) {
+	f, err := os.Open(d.File)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	hdr, err := textproto.ReadHeader(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", d.File, err)
+	}
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", d.File, err)
+	}
+	return &mail.Header{Header: message.Header{Header: hdr}}, body, nil
+}
+
+// RestoreDraft creates a composer from a saved draft
+func RestoreDraft(aerc *Aerc, draft *Draft) (*Composer, error) {
+	acct, err := aerc.Account(draft.Account)
+	if err != nil {
+		return nil, err
+	}
+	h, body, err := draft.read()
+	if err != nil {
+		return nil, err
+	}
+
+	composer, err := NewComposer(aerc, acct,
+		acct.AccountConfig(), acct.Worker(), "", h, nil)
+	if err != nil {
+		return nil, err
+	}
+	// the signature is already part of the saved body
+	if err := composer.email.Truncate(0); err != nil {
+		return nil, err
+	}
+	composer.SetContents(bytes.NewReader(body))
+
+	for _, path := range draft.Attachments {
+		if _, err := os.Stat(path); err != nil {
+			aerc.PushError(fmt.Sprintf("attachment %s: %v", path, err))
+			continue
+		}
+		composer.AddAttachment(path)
+	}
+	for _, p := range draft.Parts {
+		data, err := os.ReadFile(p.File)
+		if err != nil {
+			aerc.PushError(fmt.Sprintf("attachment %s: %v", p.Name, err))
+			continue
+		}
+		err = composer.AddPartAttachment(p.Name, p.MimeType, p.Params,
+			bytes.NewReader(data))
+		if err != nil {
+			aerc.PushError(err.Error())
+		}
+	}
+
+	composer.attachKey = draft.AttachKey
+	if err := composer.SetSign(draft.Sign); err != nil {
+		aerc.PushError(err.Error())
+	}
+	composer.SetEncrypt(draft.Encrypt)
+	return composer, nil
+}
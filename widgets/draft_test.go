@@ -0,0 +1,133 @@
+package widgets
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/emersion/go-message/mail"
+
+	"git.sr.ht/~rjarry/aerc/config"
+	"git.sr.ht/~rjarry/aerc/lib"
+)
+
+func newTestComposer(t *testing.T, account string, body string) *Composer {
+	t.Helper()
+	email, err := os.CreateTemp(t.TempDir(), "*.eml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { email.Close() })
+	if _, err := email.WriteString(body); err != nil {
+		t.Fatal(err)
+	}
+	var h mail.Header
+	h.SetSubject("Meeting notes")
+	h.SetAddressList("To", []*mail.Address{{Address: "bob@example.com"}})
+	return &Composer{
+		acct: &AccountView{
+			acct: &config.AccountConfig{Name: account},
+		},
+		header: &h,
+		email:  email,
+	}
+}
+
+func TestDraftRoundTrip(t *testing.T) {
+	c := newTestComposer(t, "work", "Hello Bob,\r\n\r\nSee attached.\r\n")
+	c.sign = true
+	c.attachKey = true
+	part, err := lib.NewPart("message/rfc822", map[string]string{
+		"name": "forwarded.eml",
+	}, strings.NewReader("Subject: forwarded\r\n\r\nbody\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.attachments = []lib.Attachment{
+		lib.NewFileAttachment("/home/bob/report.pdf"),
+		lib.NewPartAttachment(part, "forwarded.eml"),
+	}
+
+	file := filepath.Join(t.TempDir(), "0.eml")
+	draft, err := c.SaveDraft(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &Draft{
+		Account:     "work",
+		File:        file,
+		Attachments: []string{"/home/bob/report.pdf"},
+		Parts: []*DraftPart{{
+			Name:     "forwarded.eml",
+			MimeType: "message/rfc822",
+			Params:   map[string]string{"name": "forwarded.eml"},
+			File:     file + ".1",
+		}},
+		Sign:      true,
+		AttachKey: true,
+	}
+	if !reflect.DeepEqual(draft, expected) {
+		t.Errorf("expected %+v, got %+v", expected, draft)
+	}
+
+	h, body, err := draft.read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subject, _ := h.Subject(); subject != "Meeting notes" {
+		t.Errorf("expected subject %q, got %q", "Meeting notes", subject)
+	}
+	if to := h.Get("To"); to != "<bob@example.com>" {
+		t.Errorf("expected To %q, got %q", "<bob@example.com>", to)
+	}
+	if string(body) != "Hello Bob,\r\n\r\nSee attached.\r\n" {
+		t.Errorf("unexpected body %q", body)
+	}
+	data, err := os.ReadFile(draft.Parts[0].File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != string(part.Data) {
+		t.Errorf("unexpected part data %q", data)
+	}
+
+	draft.Remove()
+	for _, f := range []string{draft.File, draft.Parts[0].File} {
+		if _, err := os.Stat(f); !os.IsNotExist(err) {
+			t.Errorf("%s: not removed", f)
+		}
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "0.eml")
+	for _, data := range []string{"first version", "second"} {
+		if err := writeFile(file, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+		written, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(written) != data {
+			t.Errorf("expected %q, got %q", data, written)
+		}
+	}
+	st, err := os.Stat(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Mode().Perm() != 0o600 {
+		t.Errorf("expected mode 0600, got %v", st.Mode().Perm())
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("temporary files left in %s: %v", dir, entries)
+	}
+}
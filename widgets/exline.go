@@ -1,6 +1,8 @@
 package widgets
 
 import (
+	"fmt"
+
 	"github.com/gdamore/tcell/v2"
 
 	"git.sr.ht/~rjarry/aerc/config"
@@ -14,6 +16,16 @@ type ExLine struct {
 	tabcomplete func(cmd string) ([]string, string)
 	cmdHistory  lib.History
 	input       *ui.TextInput
+	prompt      string
+
+	// text entered before browsing the history, used as a prefix to
+	// filter entries
+	typed    string
+	browsing bool
+
+	// reverse incremental search state
+	searching bool
+	query     []rune
 }
 
 func NewExLine(cmd string, commit func(cmd string), finish func(),
@@ -34,6 +46,7 @@ func NewExLine(cmd string, commit func(cmd string), finish func(),
 		tabcomplete: tabcomplete,
 		cmdHistory:  cmdHistory,
 		input:       input,
+		prompt:      ":",
 	}
 	return exline
 }
@@ -60,8 +73,9 @@ func NewPrompt(prompt string, commit func(text string),
 	exline := &ExLine{
 		commit:      commit,
 		tabcomplete: tabcomplete,
-		cmdHistory:  &nullHistory{input: input},
+		cmdHistory:  &nullHistory{},
 		input:       input,
+		prompt:      prompt,
 	}
 	return exline
 }
@@ -80,6 +94,9 @@ func (ex *ExLine) Focus(focus bool) {
 
 func (ex *ExLine) Event(event tcell.Event) bool {
 	if event, ok := event.(*tcell.EventKey); ok {
+		if ex.searching && ex.searchEvent(event) {
+			return true
+		}
 		switch event.Key() {
 		case tcell.KeyEnter, tcell.KeyCtrlJ:
 			cmd := ex.input.String()
@@ -87,34 +104,117 @@ func (ex *ExLine) Event(event tcell.Event) bool {
 			ex.commit(cmd)
 			ex.finish()
 		case tcell.KeyUp:
-			ex.input.Set(ex.cmdHistory.Prev())
-			ex.Invalidate()
+			if !ex.browsing {
+				ex.typed = ex.input.String()
+				ex.browsing = true
+				ex.cmdHistory.Reset()
+			}
+			if cmd, ok := ex.cmdHistory.Prev(ex.typed); ok {
+				ex.input.Set(cmd)
+				ex.Invalidate()
+			}
 		case tcell.KeyDown:
-			ex.input.Set(ex.cmdHistory.Next())
+			if !ex.browsing {
+				break
+			}
+			if cmd, ok := ex.cmdHistory.Next(ex.typed); ok {
+				ex.input.Set(cmd)
+			} else {
+				// back to what was entered before browsing
+				ex.input.Set(ex.typed)
+				ex.browsing = false
+			}
 			ex.Invalidate()
+		case tcell.KeyCtrlR:
+			ex.startSearch()
 		case tcell.KeyEsc, tcell.KeyCtrlC:
 			ex.input.Focus(false)
 			ex.cmdHistory.Reset()
 			ex.finish()
 		default:
+			ex.browsing = false
 			return ex.input.Event(event)
 		}
 	}
 	return true
 }
 
-type nullHistory struct {
-	input *ui.TextInput
+func (ex *ExLine) startSearch() {
+	ex.searching = true
+	ex.browsing = false
+	ex.typed = ex.input.String()
+	ex.query = nil
+	ex.cmdHistory.Reset()
+	ex.updateSearch(true)
+}
+
+// updateSearch looks up the query in history. The search continues from the
+// last match, unless restart is true.
+func (ex *ExLine) updateSearch(restart bool) {
+	if restart {
+		ex.cmdHistory.Reset()
+	}
+	failed := ""
+	if len(ex.query) > 0 {
+		if cmd, ok := ex.cmdHistory.Search(string(ex.query)); ok {
+			ex.input.Set(cmd)
+		} else {
+			failed = "failed "
+		}
+	}
+	ex.input.Prompt(fmt.Sprintf("(%sreverse-i-search)`%s': ",
+		failed, string(ex.query)))
+	ex.Invalidate()
+}
+
+func (ex *ExLine) endSearch() {
+	ex.searching = false
+	ex.input.Prompt(ex.prompt)
+	ex.Invalidate()
+}
+
+// searchEvent handles key presses during a reverse incremental search. It
+// returns false when the search is over and the event must be processed as
+// usual.
+func (ex *ExLine) searchEvent(event *tcell.EventKey) bool {
+	switch event.Key() {
+	case tcell.KeyRune:
+		ex.query = append(ex.query, event.Rune())
+		ex.updateSearch(true)
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if len(ex.query) > 0 {
+			ex.query = ex.query[:len(ex.query)-1]
+		}
+		ex.updateSearch(true)
+	case tcell.KeyCtrlR:
+		ex.updateSearch(false)
+	case tcell.KeyEsc, tcell.KeyCtrlC, tcell.KeyCtrlG:
+		// abort the search and restore the original text
+		ex.input.Set(ex.typed)
+		ex.cmdHistory.Reset()
+		ex.endSearch()
+	default:
+		// keep the current match
+		ex.endSearch()
+		return false
+	}
+	return true
 }
 
+type nullHistory struct{}
+
 func (*nullHistory) Add(string) {}
 
-func (h *nullHistory) Next() string {
-	return h.input.String()
+func (*nullHistory) Next(string) (string, bool) {
+	return "", false
+}
+
+func (*nullHistory) Prev(string) (string, bool) {
+	return "", false
 }
 
-func (h *nullHistory) Prev() string {
-	return h.input.String()
+func (*nullHistory) Search(string) (string, bool) {
+	return "", false
 }
 
 func (*nullHistory) Reset() {}
@@ -0,0 +1,330 @@
+package widgets
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gdamore/tcell/v2"
+
+	"git.sr.ht/~rjarry/aerc/config"
+	"git.sr.ht/~rjarry/aerc/lib"
+	"git.sr.ht/~rjarry/aerc/lib/ui"
+	"git.sr.ht/~rjarry/aerc/models"
+	"git.sr.ht/~rjarry/aerc/worker/types"
+)
+
+var _ ProvidesMessages = (*Layout)(nil)
+
+// Layout displays the message lists of several folders, possibly from
+// different accounts, in the same tab. Each pane opens its folder on a
+// connection of its own, so that all of them are kept up to date. Commands
+// act on the focused pane.
+type Layout struct {
+	aerc   *Aerc
+	grid   *ui.Grid
+	panes  [][]*Pane
+	row    int
+	column int
+	// show the account names in the pane titles
+	accounts bool
+}
+
+// Pane is a message list of a layout. Its account is a view of the parent
+// account which is not displayed in a tab.
+type Pane struct {
+	layout *Layout
+	acct   *AccountView
+}
+
+// NewLayout creates the panes of the given rows. Those without an account
+// name display a folder of acct.
+func NewLayout(
+	aerc *Aerc, rows [][]*config.PaneConfig, acct *AccountView,
+) (*Layout, error) {
+	if acct != nil && acct.parent != nil {
+		// opened from another layout
+		acct = acct.parent
+	}
+	layout := &Layout{aerc: aerc}
+	// check the accounts before opening the pane connections
+	parents := make([][]*AccountView, len(rows))
+	for i, row := range rows {
+		for _, conf := range row {
+			paneAcct := acct
+			if conf.Account != "" {
+				var err error
+				paneAcct, err = aerc.Account(conf.Account)
+				if err != nil {
+					return nil, err
+				}
+			}
+			if paneAcct == nil {
+				return nil, errors.New("No account selected")
+			}
+			if paneAcct.Directories() == nil {
+				return nil, fmt.Errorf("account <%s> is not available",
+					paneAcct.Name())
+			}
+			if paneAcct != acct || conf.Account != "" {
+				layout.accounts = true
+			}
+			parents[i] = append(parents[i], paneAcct)
+		}
+	}
+
+	layout.grid = ui.NewGrid().Columns([]ui.GridSpec{
+		{Strategy: ui.SIZE_WEIGHT, Size: ui.Const(1)},
+	})
+	var gridRows []ui.GridSpec
+	for i, row := range rows {
+		gridRows = append(gridRows, ui.GridSpec{
+			Strategy: ui.SIZE_WEIGHT, Size: ui.Const(1),
+		})
+		var columns []ui.GridSpec
+		for range row {
+			columns = append(columns, ui.GridSpec{
+				Strategy: ui.SIZE_WEIGHT, Size: ui.Const(1),
+			})
+		}
+		grid := ui.NewGrid().Rows([]ui.GridSpec{
+			{Strategy: ui.SIZE_WEIGHT, Size: ui.Const(1)},
+		}).Columns(columns)
+
+		var panes []*Pane
+		for j, conf := range row {
+			paneAcct := parents[i][j]
+			view, err := aerc.paneAccount(paneAcct, conf.Folder)
+			if err != nil {
+				layout.panes = append(layout.panes, panes)
+				layout.Close()
+				return nil, err
+			}
+			pane := &Pane{layout: layout, acct: view}
+			panes = append(panes, pane)
+
+			var borders uint
+			if j < len(row)-1 {
+				borders |= ui.BORDER_RIGHT
+			}
+			if i < len(rows)-1 {
+				borders |= ui.BORDER_BOTTOM
+			}
+			if borders != 0 {
+				grid.AddChild(ui.NewBordered(pane, borders,
+					paneAcct.UiConfig())).At(0, j)
+			} else {
+				grid.AddChild(pane).At(0, j)
+			}
+		}
+		layout.panes = append(layout.panes, panes)
+		layout.grid.AddChild(grid).At(i, 0)
+	}
+	if len(layout.panes) == 0 {
+		return nil, errors.New("No panes in layout")
+	}
+	layout.grid.Rows(gridRows)
+	return layout, nil
+}
+
+// Close gives the accounts of the panes back for the next layouts
+func (layout *Layout) Close() error {
+	for _, row := range layout.panes {
+		for _, pane := range row {
+			layout.aerc.releasePaneAccount(pane.acct)
+		}
+	}
+	layout.panes = nil
+	return nil
+}
+
+func (layout *Layout) Draw(ctx *ui.Context) {
+	layout.grid.Draw(ctx)
+}
+
+func (layout *Layout) Invalidate() {
+	ui.Invalidate()
+}
+
+func (layout *Layout) MouseEvent(localX int, localY int, event tcell.Event) {
+	layout.grid.MouseEvent(localX, localY, event)
+}
+
+// Focused returns the pane on which the commands are run
+func (layout *Layout) Focused() *Pane {
+	return layout.panes[layout.row][layout.column]
+}
+
+// FocusPane focuses the pane at the given position
+func (layout *Layout) FocusPane(row, column int) {
+	if row < 0 || row >= len(layout.panes) {
+		return
+	}
+	if column >= len(layout.panes[row]) {
+		column = len(layout.panes[row]) - 1
+	}
+	if column < 0 || (row == layout.row && column == layout.column) {
+		return
+	}
+	layout.row, layout.column = row, column
+	layout.aerc.UpdateStatus()
+	layout.Invalidate()
+}
+
+// NextPane focuses the n-th pane after the focused one, in reading order.
+// A negative n goes backwards.
+func (layout *Layout) NextPane(n int) {
+	var all []*Pane
+	index := 0
+	for _, row := range layout.panes {
+		for _, pane := range row {
+			if pane == layout.Focused() {
+				index = len(all)
+			}
+			all = append(all, pane)
+		}
+	}
+	index = (index + n) % len(all)
+	if index < 0 {
+		index += len(all)
+	}
+	layout.focusPane(all[index])
+}
+
+// MovePane focuses the neighbour of the focused pane in the given direction
+func (layout *Layout) MovePane(rows, columns int) {
+	layout.FocusPane(layout.row+rows, layout.column+columns)
+}
+
+func (layout *Layout) focusPane(pane *Pane) {
+	for i, row := range layout.panes {
+		for j, p := range row {
+			if p == pane {
+				layout.FocusPane(i, j)
+				return
+			}
+		}
+	}
+}
+
+func (layout *Layout) SelectedDirectory() string {
+	return layout.Focused().acct.folder
+}
+
+func (layout *Layout) Store() *lib.MessageStore {
+	return layout.Focused().acct.Store()
+}
+
+func (layout *Layout) SelectedAccount() *AccountView {
+	return layout.Focused().acct
+}
+
+func (layout *Layout) SelectedMessage() (*models.MessageInfo, error) {
+	msglist := layout.Focused().acct.Messages()
+	if msglist.Empty() {
+		return nil, errors.New("no message selected")
+	}
+	msg := msglist.Selected()
+	if msg == nil {
+		return nil, errors.New("message not loaded")
+	}
+	return msg, nil
+}
+
+func (layout *Layout) MarkedMessages() ([]uint32, error) {
+	if store := layout.Store(); store != nil {
+		return store.Marker().Marked(), nil
+	}
+	return nil, errors.New("no store available")
+}
+
+func (layout *Layout) SelectedMessagePart() *PartInfo {
+	return nil
+}
+
+func (pane *Pane) Invalidate() {
+	ui.Invalidate()
+}
+
+func (pane *Pane) Draw(ctx *ui.Context) {
+	msglist := pane.acct.Messages()
+	uiConfig := msglist.uiConfig()
+	style := uiConfig.GetStyle(config.STYLE_TAB)
+	if pane.layout.Focused() == pane {
+		style = uiConfig.GetStyleSelected(config.STYLE_TAB)
+	}
+	title := pane.acct.folder
+	if pane.layout.accounts {
+		title = fmt.Sprintf("%s: %s", pane.acct.Name(), pane.acct.folder)
+	}
+	ctx.Fill(0, 0, ctx.Width(), 1, ' ', style)
+	ctx.Printf(1, 0, style, "%s", title)
+	if ctx.Height() < 2 {
+		return
+	}
+	msglist.Draw(ctx.Subcontext(0, 1, ctx.Width(), ctx.Height()-1))
+}
+
+func (pane *Pane) MouseEvent(localX int, localY int, event tcell.Event) {
+	ev, ok := event.(*tcell.EventMouse)
+	if !ok {
+		return
+	}
+	switch ev.Buttons() {
+	case tcell.Button1, tcell.Button2, tcell.Button3:
+		pane.layout.focusPane(pane)
+	}
+	if localY > 0 {
+		pane.acct.Messages().MouseEvent(localX, localY-1, event)
+	}
+}
+
+// paneAccount returns a view of acct which opens folder on a connection of
+// its own. The views of the closed layouts are reused, their connection is
+// kept open.
+func (aerc *Aerc) paneAccount(
+	acct *AccountView, folder string,
+) (*AccountView, error) {
+	for i, view := range aerc.idlePanes {
+		if view.parent != acct {
+			continue
+		}
+		aerc.idlePanes = append(aerc.idlePanes[:i], aerc.idlePanes[i+1:]...)
+		view.folder = folder
+		if view.state.Connected() {
+			view.dirlist.Select(folder)
+		}
+		return view, nil
+	}
+
+	name := fmt.Sprintf("%s\x00pane%d", acct.Name(), len(aerc.panes))
+	view, err := newAccountView(aerc, acct.acct, name, aerc, nil)
+	if err != nil {
+		return nil, err
+	}
+	view.parent = acct
+	view.folder = folder
+	aerc.panes[name] = view
+	view.worker.PostAction(&types.Configure{Config: paneConfig(acct.acct)}, nil)
+	view.worker.PostAction(&types.Connect{}, nil)
+	return view, nil
+}
+
+// paneConfig returns the configuration of the pane workers of an account. The
+// account worker already caches the headers and watches the folders, the
+// cache databases cannot be opened twice.
+func paneConfig(acct *config.AccountConfig) *config.AccountConfig {
+	conf := *acct
+	conf.Params = make(map[string]string)
+	for key, value := range acct.Params {
+		switch key {
+		case "cache-headers", "watch-folders":
+		default:
+			conf.Params[key] = value
+		}
+	}
+	return &conf
+}
+
+func (aerc *Aerc) releasePaneAccount(view *AccountView) {
+	aerc.idlePanes = append(aerc.idlePanes, view)
+}
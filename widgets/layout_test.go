@@ -0,0 +1,61 @@
+package widgets
+
+import (
+	"reflect"
+	"testing"
+
+	"git.sr.ht/~rjarry/aerc/config"
+	"git.sr.ht/~rjarry/aerc/lib/statusline"
+)
+
+func TestPaneConfig(t *testing.T) {
+	acct := &config.AccountConfig{
+		Name:   "work",
+		Source: "imaps://bob@example.com",
+		Params: map[string]string{
+			"cache-headers": "true",
+			"watch-folders": "Lists",
+			"idle-timeout":  "20s",
+		},
+	}
+	conf := paneConfig(acct)
+	expected := map[string]string{"idle-timeout": "20s"}
+	if !reflect.DeepEqual(conf.Params, expected) {
+		t.Errorf("expected %v, got %v", expected, conf.Params)
+	}
+	if conf.Name != "work" || conf.Source != acct.Source {
+		t.Errorf("unexpected pane config %+v", conf)
+	}
+	if len(acct.Params) != 3 {
+		t.Errorf("account config modified: %v", acct.Params)
+	}
+}
+
+func TestPaneAccountReuse(t *testing.T) {
+	aerc := &Aerc{panes: make(map[string]*AccountView)}
+	work := &AccountView{acct: &config.AccountConfig{Name: "work"}}
+	home := &AccountView{acct: &config.AccountConfig{Name: "home"}}
+	idle := func(parent *AccountView, folder string) *AccountView {
+		return &AccountView{
+			acct:   parent.acct,
+			parent: parent,
+			folder: folder,
+			state:  statusline.NewState(parent.Name(), true),
+		}
+	}
+	homeInbox, workInbox := idle(home, "INBOX"), idle(work, "INBOX")
+	aerc.releasePaneAccount(homeInbox)
+	aerc.releasePaneAccount(workInbox)
+
+	view, err := aerc.paneAccount(work, "Sent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if view != workInbox || view.folder != "Sent" {
+		t.Errorf("the idle view of the account was not reused")
+	}
+	expected := []*AccountView{homeInbox}
+	if !reflect.DeepEqual(aerc.idlePanes, expected) {
+		t.Errorf("expected %v idle, got %v", expected, aerc.idlePanes)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"git.sr.ht/~rjarry/aerc/lib"
 	"git.sr.ht/~rjarry/aerc/lib/iterator"
 	"git.sr.ht/~rjarry/aerc/lib/templates"
+	"git.sr.ht/~rjarry/aerc/lib/threadstate"
 	"git.sr.ht/~rjarry/aerc/lib/ui"
 	"git.sr.ht/~rjarry/aerc/log"
 	"git.sr.ht/~rjarry/aerc/models"
@@ -27,6 +28,12 @@ type MessageList struct {
 	store         *lib.MessageStore
 	isInitalizing bool
 	aerc          *Aerc
+	acct          *AccountView
+	pressed       bool
+	press         int
+	pressedMsg    int
+	dragged       bool
+	dragVisual    bool
 }
 
 func NewMessageList(aerc *Aerc, account *AccountView) *MessageList {
@@ -34,6 +41,7 @@ func NewMessageList(aerc *Aerc, account *AccountView) *MessageList {
 		spinner:       NewSpinner(account.uiConf),
 		isInitalizing: true,
 		aerc:          aerc,
+		acct:          account,
 	}
 	// TODO: stop spinner, probably
 	ml.spinner.Start()
@@ -49,16 +57,17 @@ type messageRowParams struct {
 	needsHeaders bool
 	uiConfig     *config.UIConfig
 	styles       []config.StyleObject
+	msg          *models.MessageInfo
 }
 
 func (ml *MessageList) Draw(ctx *ui.Context) {
 	ml.height = ctx.Height()
 	ml.width = ctx.Width()
-	uiConfig := ml.aerc.SelectedAccountUiConfig()
+	uiConfig := ml.uiConfig()
 	ctx.Fill(0, 0, ctx.Width(), ctx.Height(), ' ',
 		uiConfig.GetStyle(config.STYLE_MSGLIST_DEFAULT))
 
-	acct := ml.aerc.SelectedAccount()
+	acct := ml.acct
 	store := ml.Store()
 	if store == nil || acct == nil || len(store.Uids()) == 0 {
 		if ml.isInitalizing {
@@ -70,7 +79,8 @@ func (ml *MessageList) Draw(ctx *ui.Context) {
 		return
 	}
 
-	ml.UpdateScroller(ml.height, len(store.Uids()))
+	ml.nmsgs = len(store.Uids())
+	ml.UpdateScroller(ml.height, ml.nmsgs)
 	iter := store.UidsIterator()
 	for i := 0; iter.Next(); i++ {
 		if store.SelectedUid() == iter.Value().(uint32) {
@@ -91,7 +101,7 @@ func (ml *MessageList) Draw(ctx *ui.Context) {
 		acct.acct.From,
 		acct.acct.Aliases,
 		acct.Name(),
-		acct.Directories().Selected(),
+		store.DirInfo.Name,
 		uiConfig.TimestampFormat,
 		uiConfig.ThisDayTimeFormat,
 		uiConfig.ThisWeekTimeFormat,
@@ -112,20 +122,26 @@ func (ml *MessageList) Draw(ctx *ui.Context) {
 		return false
 	}
 
-	getRowStyle := func(t *ui.Table, r int) tcell.Style {
+	getStyle := func(t *ui.Table, r int, column string) tcell.Style {
 		var style tcell.Style
 		row := &t.Rows[r]
 		params, _ := row.Priv.(messageRowParams)
 		if params.uid == store.SelectedUid() {
-			style = params.uiConfig.GetComposedStyleSelected(
-				config.STYLE_MSGLIST_DEFAULT, params.styles)
+			style = params.uiConfig.MsgComposedStyleSelected(
+				config.STYLE_MSGLIST_DEFAULT, params.styles, params.msg,
+				column)
 		} else {
-			style = params.uiConfig.GetComposedStyle(
-				config.STYLE_MSGLIST_DEFAULT, params.styles)
+			style = params.uiConfig.MsgComposedStyle(
+				config.STYLE_MSGLIST_DEFAULT, params.styles, params.msg,
+				column)
 		}
 		return style
 	}
 
+	getRowStyle := func(t *ui.Table, r int) tcell.Style {
+		return getStyle(t, r, "")
+	}
+
 	table := ui.NewTable(
 		textWidth, ml.height,
 		uiConfig.IndexColumns,
@@ -133,6 +149,9 @@ func (ml *MessageList) Draw(ctx *ui.Context) {
 		customDraw,
 		getRowStyle,
 	)
+	table.GetCellStyle = func(t *ui.Table, r int, c int) tcell.Style {
+		return getStyle(t, r, t.Columns[c].Def.Name)
+	}
 
 	if store.ThreadedView() {
 		var (
@@ -147,11 +166,15 @@ func (ml *MessageList) Draw(ctx *ui.Context) {
 			err := iter.Value().(*types.Thread).Walk(
 				func(t *types.Thread, _ int, _ error,
 				) error {
+					var err error
+					if t.Folded {
+						err = types.ErrSkipThread
+					}
 					if t.Hidden || t.Deleted {
-						return nil
+						return err
 					}
 					cur = append(cur, t)
-					return nil
+					return err
 				})
 			if err != nil {
 				log.Errorf("thread walk: %v", err)
@@ -172,6 +195,11 @@ func (ml *MessageList) Draw(ctx *ui.Context) {
 					!isParent(thread)
 				data.ThreadPrefix = threadPrefix(thread,
 					store.ReverseThreadOrder())
+				data.ThreadFolded = thread.Folded
+				data.ThreadCount, data.ThreadUnread = 0, 0
+				if thread.Folded {
+					data.ThreadCount, data.ThreadUnread = threadCounts(store, thread)
+				}
 				lastSubject = baseSubject
 				prevThread = thread
 
@@ -210,7 +238,8 @@ func (ml *MessageList) Draw(ctx *ui.Context) {
 		}
 	}
 
-	if len(needsHeaders) != 0 {
+	// the worker only fetches the headers of the selected folder
+	if len(needsHeaders) != 0 && ml.storeSelected() {
 		store.FetchHeaders(needsHeaders, nil)
 		ml.spinner.Start()
 	} else {
@@ -233,6 +262,7 @@ func addMessage(
 		return table.AddRow(cells, params)
 	}
 
+	params.msg = msg
 	if msg.Flags.Has(models.SeenFlag) {
 		params.styles = append(params.styles, config.STYLE_MSGLIST_READ)
 	} else {
@@ -244,6 +274,11 @@ func addMessage(
 	if msg.Flags.Has(models.FlaggedFlag) {
 		params.styles = append(params.styles, config.STYLE_MSGLIST_FLAGGED)
 	}
+	// unread reply of a watched thread
+	if !msg.Flags.Has(models.SeenFlag) &&
+		threadstate.Get(msg) == threadstate.Watched {
+		params.styles = append(params.styles, config.STYLE_MSGLIST_WATCHED)
+	}
 	// deleted message
 	if _, ok := store.Deleted[msg.Uid]; ok {
 		params.styles = append(params.styles, config.STYLE_MSGLIST_DELETED)
@@ -298,28 +333,74 @@ func (ml *MessageList) MouseEvent(localX int, localY int, event tcell.Event) {
 				return
 			}
 			selectedMsg, ok := ml.Clicked(localX, localY)
-			if ok {
+			if !ok {
+				return
+			}
+			store := ml.Store()
+			switch {
+			case event.Modifiers()&tcell.ModShift != 0:
+				// mark everything up to the clicked message
+				prev := store.SelectedUid()
 				ml.Select(selectedMsg)
-				acct := ml.aerc.SelectedAccount()
-				if acct == nil || acct.Messages().Empty() {
-					return
-				}
-				store := acct.Messages().Store()
-				msg := acct.Messages().Selected()
-				if msg == nil {
-					return
+				store.Marker().MarkRange(prev, store.SelectedUid())
+			case !ml.pressed || ml.press != ml.aerc.MousePress():
+				// the release of a previous click may have
+				// happened outside of the message list
+				ml.endDrag()
+				ml.pressed = true
+				ml.press = ml.aerc.MousePress()
+				ml.pressedMsg = selectedMsg
+				ml.Select(selectedMsg)
+			case selectedMsg != ml.pressedMsg || ml.dragged:
+				// the button is held down while moving to
+				// another message, mark the range in visual mode
+				// unless it was already entered with :mark -V
+				if !ml.dragged {
+					ml.dragged = true
+					ml.dragVisual = !store.Marker().IsVisualMark()
+					if ml.dragVisual {
+						store.Marker().ToggleVisualMark(false)
+					}
 				}
-				lib.NewMessageStoreView(msg, acct.UiConfig().AutoMarkRead,
-					store, ml.aerc.Crypto, ml.aerc.DecryptKeys,
-					func(view lib.MessageView, err error) {
-						if err != nil {
-							ml.aerc.PushError(err.Error())
-							return
-						}
-						viewer := NewMessageViewer(acct, view)
-						ml.aerc.NewTab(viewer, msg.Envelope.Subject)
-					})
+				ml.Select(selectedMsg)
 			}
+		case tcell.Button3:
+			if ml.aerc == nil {
+				return
+			}
+			selectedMsg, ok := ml.Clicked(localX, localY)
+			if !ok {
+				return
+			}
+			ml.Select(selectedMsg)
+			ml.aerc.ContextMenu("Message", []Choice{
+				{Text: "view", Command: "view"},
+				{Text: "reply", Command: "reply"},
+				{Text: "reply all", Command: "reply -a"},
+				{Text: "forward", Command: "forward"},
+				{Text: "archive", Command: "archive flat"},
+				{Text: "delete", Command: "delete"},
+				{Text: "toggle read", Command: "read -t"},
+				{Text: "toggle flag", Command: "flag -t"},
+				{Text: "toggle mark", Command: "mark -t"},
+				{Text: "move to...", Command: "move "},
+				{Text: "copy to...", Command: "copy "},
+			})
+		case tcell.ButtonNone:
+			if !ml.pressed || ml.aerc == nil {
+				return
+			}
+			ml.pressed = false
+			if ml.press != ml.aerc.MousePress() {
+				// released after another click elsewhere
+				ml.endDrag()
+				return
+			}
+			if ml.dragged {
+				ml.endDrag()
+				return
+			}
+			ml.openSelected()
 		case tcell.WheelDown:
 			if ml.store != nil {
 				ml.store.Next()
@@ -334,6 +415,44 @@ func (ml *MessageList) MouseEvent(localX int, localY int, event tcell.Event) {
 	}
 }
 
+// endDrag leaves the visual mode entered by a drag, keeping the marked
+// messages
+func (ml *MessageList) endDrag() {
+	if !ml.dragged {
+		return
+	}
+	ml.dragged = false
+	if store := ml.Store(); store != nil && ml.dragVisual &&
+		store.Marker().IsVisualMark() {
+		store.Marker().ToggleVisualMark(false)
+	}
+	ml.dragVisual = false
+	ml.Invalidate()
+}
+
+// openSelected opens the selected message in a new viewer tab
+func (ml *MessageList) openSelected() {
+	acct := ml.acct
+	if ml.Empty() || !ml.storeSelected() {
+		return
+	}
+	store := ml.Store()
+	msg := ml.Selected()
+	if msg == nil {
+		return
+	}
+	lib.NewMessageStoreView(msg, acct.UiConfig().AutoMarkRead,
+		store, ml.aerc.Crypto, ml.aerc.DecryptKeys,
+		func(view lib.MessageView, err error) {
+			if err != nil {
+				ml.aerc.PushError(err.Error())
+				return
+			}
+			viewer := NewMessageViewer(acct, view)
+			ml.aerc.NewTab(viewer, msg.Envelope.Subject)
+		})
+}
+
 func (ml *MessageList) Clicked(x, y int) (int, bool) {
 	store := ml.Store()
 	if store == nil || ml.nmsgs == 0 || y >= ml.nmsgs {
@@ -387,6 +506,21 @@ func (ml *MessageList) Store() *lib.MessageStore {
 	return ml.store
 }
 
+// storeSelected returns true if the store is the one of the folder opened in
+// the account. The message lists of other folders are not kept up to date.
+func (ml *MessageList) storeSelected() bool {
+	store := ml.Store()
+	return store != nil &&
+		store.DirInfo.Name == ml.acct.Directories().Selected()
+}
+
+func (ml *MessageList) uiConfig() *config.UIConfig {
+	if store := ml.Store(); store != nil {
+		return ml.acct.Directories().UiConfig(store.DirInfo.Name)
+	}
+	return ml.acct.UiConfig()
+}
+
 func (ml *MessageList) Empty() bool {
 	store := ml.Store()
 	return store == nil || len(store.Uids()) == 0
@@ -426,7 +560,7 @@ func (ml *MessageList) Select(index int) {
 }
 
 func (ml *MessageList) drawEmptyMessage(ctx *ui.Context) {
-	uiConfig := ml.aerc.SelectedAccountUiConfig()
+	uiConfig := ml.uiConfig()
 	msg := uiConfig.EmptyMessage
 	ctx.Printf((ctx.Width()/2)-(len(msg)/2), 0,
 		uiConfig.GetStyle(config.STYLE_MSGLIST_DEFAULT), "%s", msg)
@@ -466,6 +600,27 @@ func threadPrefix(t *types.Thread, reverse bool) string {
 	return fmt.Sprintf("%v%v", ps, arrow)
 }
 
+// threadCounts returns the number of messages of a thread and how many of
+// them are unread.
+func threadCounts(store *lib.MessageStore, thread *types.Thread) (int, int) {
+	var count, unread int
+	_ = thread.Walk(func(t *types.Thread, _ int, _ error) error {
+		if t.Hidden || t.Deleted {
+			return nil
+		}
+		msg, ok := store.Messages[t.Uid]
+		if !ok {
+			return nil
+		}
+		count++
+		if msg != nil && !msg.Flags.Has(models.SeenFlag) {
+			unread++
+		}
+		return nil
+	})
+	return count, unread
+}
+
 func sameParent(left, right *types.Thread) bool {
 	return left.Root() == right.Root()
 }
@@ -0,0 +1,113 @@
+package widgets
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+
+	"git.sr.ht/~rjarry/aerc/lib"
+	"git.sr.ht/~rjarry/aerc/models"
+	"git.sr.ht/~rjarry/aerc/worker/types"
+)
+
+// newTestMessageList returns a message list showing the uids 1 to 5 from top
+// to bottom
+func newTestMessageList() *MessageList {
+	dirInfo := &models.DirectoryInfo{
+		Name: "INBOX", Caps: &models.Capabilities{},
+	}
+	store := lib.NewMessageStore(nil, dirInfo, nil, false, false, 0, false, false, false, nil, nil, nil)
+	// the newest messages are shown first
+	store.Update(&types.DirectoryContents{Uids: []uint32{5, 4, 3, 2, 1}})
+	ml := &MessageList{aerc: &Aerc{}, store: store, nmsgs: 5}
+	ml.Select(0)
+	return ml
+}
+
+func (ml *MessageList) testMouse(y int, buttons tcell.ButtonMask,
+	mod tcell.ModMask,
+) {
+	event := tcell.NewEventMouse(0, y, buttons, mod)
+	ml.aerc.trackPress(event)
+	ml.MouseEvent(0, y, event)
+}
+
+func marked(ml *MessageList) []uint32 {
+	uids := ml.Store().Marker().Marked()
+	sort.Slice(uids, func(i, j int) bool { return uids[i] < uids[j] })
+	return uids
+}
+
+func TestMessageListDrag(t *testing.T) {
+	ml := newTestMessageList()
+	marker := ml.Store().Marker()
+
+	ml.testMouse(1, tcell.Button1, tcell.ModNone)
+	ml.testMouse(2, tcell.Button1, tcell.ModNone)
+	ml.testMouse(3, tcell.Button1, tcell.ModNone)
+	if !marker.IsVisualMark() {
+		t.Errorf("dragging did not enter visual mode")
+	}
+	ml.testMouse(3, tcell.ButtonNone, tcell.ModNone)
+	if marker.IsVisualMark() {
+		t.Errorf("releasing did not leave visual mode")
+	}
+	if expected := []uint32{2, 3, 4}; !reflect.DeepEqual(marked(ml), expected) {
+		t.Errorf("expected %v marked, got %v", expected, marked(ml))
+	}
+	if uid := ml.Store().SelectedUid(); uid != 4 {
+		t.Errorf("expected uid 4 selected, got %d", uid)
+	}
+}
+
+func TestMessageListDragVisualMode(t *testing.T) {
+	ml := newTestMessageList()
+	marker := ml.Store().Marker()
+	// :mark -V
+	marker.ToggleVisualMark(true)
+
+	ml.testMouse(2, tcell.Button1, tcell.ModNone)
+	ml.testMouse(3, tcell.Button1, tcell.ModNone)
+	ml.testMouse(3, tcell.ButtonNone, tcell.ModNone)
+	if !marker.IsVisualMark() {
+		t.Errorf("the drag ended the visual mode of :mark -V")
+	}
+	if expected := []uint32{1, 2, 3, 4}; !reflect.DeepEqual(marked(ml), expected) {
+		t.Errorf("expected %v marked, got %v", expected, marked(ml))
+	}
+
+	// a drag released outside of the message list
+	ml.testMouse(4, tcell.Button1, tcell.ModNone)
+	ml.testMouse(3, tcell.Button1, tcell.ModNone)
+	ml.aerc.trackPress(tcell.NewEventMouse(
+		50, 3, tcell.ButtonNone, tcell.ModNone))
+	ml.testMouse(3, tcell.Button1, tcell.ModNone)
+	if !marker.IsVisualMark() {
+		t.Errorf("the next click ended the visual mode of :mark -V")
+	}
+}
+
+func TestMessageListShiftClick(t *testing.T) {
+	ml := newTestMessageList()
+	marker := ml.Store().Marker()
+
+	ml.testMouse(3, tcell.Button1, tcell.ModShift)
+	if expected := []uint32{1, 2, 3, 4}; !reflect.DeepEqual(marked(ml), expected) {
+		t.Errorf("expected %v marked, got %v", expected, marked(ml))
+	}
+	if marker.IsVisualMark() {
+		t.Errorf("shift-click entered visual mode")
+	}
+	if uid := ml.Store().SelectedUid(); uid != 4 {
+		t.Errorf("expected uid 4 selected, got %d", uid)
+	}
+
+	// upwards, the marks are added to the previous ones
+	marker.Unmark(2)
+	ml.testMouse(1, tcell.Button1, tcell.ModShift)
+	if expected := []uint32{1, 2, 3, 4}; !reflect.DeepEqual(marked(ml), expected) {
+		t.Errorf("expected %v marked, got %v", expected, marked(ml))
+	}
+}
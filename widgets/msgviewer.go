@@ -6,6 +6,7 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"reflect"
 	"strings"
 	"sync/atomic"
 
@@ -354,6 +355,24 @@ func (mv *MessageViewer) AttachmentParts() []*PartInfo {
 	return attachments
 }
 
+// SelectPart selects the part with the given index in the part switcher.
+func (mv *MessageViewer) SelectPart(index []int) {
+	switcher := mv.switcher
+	for i, p := range switcher.parts {
+		if reflect.DeepEqual(p.index, index) {
+			if term := switcher.parts[switcher.selected].term; term != nil {
+				term.Focus(false)
+			}
+			switcher.selected = i
+			if p.term != nil {
+				p.term.Focus(true)
+			}
+			break
+		}
+	}
+	mv.Invalidate()
+}
+
 func (mv *MessageViewer) PreviousPart() {
 	switcher := mv.switcher
 	for {
@@ -413,12 +432,12 @@ func (ps *PartSwitcher) Event(event tcell.Event) bool {
 
 func (ps *PartSwitcher) Draw(ctx *ui.Context) {
 	height := len(ps.parts)
+	ps.height = ctx.Height()
 	if height == 1 && !config.Viewer.AlwaysShowMime {
 		ps.parts[ps.selected].Draw(ctx)
 		return
 	}
 	// TODO: cap height and add scrolling for messages with many parts
-	ps.height = ctx.Height()
 	y := ctx.Height() - height
 	for i, part := range ps.parts {
 		style := ps.mv.uiConfig.GetStyle(config.STYLE_DEFAULT)
@@ -438,58 +457,71 @@ func (ps *PartSwitcher) Draw(ctx *ui.Context) {
 }
 
 func (ps *PartSwitcher) MouseEvent(localX int, localY int, event tcell.Event) {
-	if event, ok := event.(*tcell.EventMouse); ok {
-		switch event.Buttons() {
-		case tcell.Button1:
-			height := len(ps.parts)
-			y := ps.height - height
-			if localY < y && ps.parts[ps.selected].term != nil {
-				ps.parts[ps.selected].term.MouseEvent(localX, localY, event)
-			}
-			for i := range ps.parts {
-				if localY != y+i {
-					continue
-				}
-				if ps.parts[i].part.MIMEType == "multipart" {
-					continue
-				}
-				if ps.parts[ps.selected].term != nil {
-					ps.parts[ps.selected].term.Focus(false)
-				}
-				ps.selected = i
-				ps.Invalidate()
-				if ps.parts[ps.selected].term != nil {
-					ps.parts[ps.selected].term.Focus(true)
-				}
-			}
-		case tcell.WheelDown:
-			height := len(ps.parts)
-			y := ps.height - height
-			if localY < y && ps.parts[ps.selected].term != nil {
-				ps.parts[ps.selected].term.MouseEvent(localX, localY, event)
-			}
-			if ps.parts[ps.selected].term != nil {
-				ps.parts[ps.selected].term.Focus(false)
-			}
-			ps.mv.NextPart()
-			if ps.parts[ps.selected].term != nil {
-				ps.parts[ps.selected].term.Focus(true)
-			}
-		case tcell.WheelUp:
-			height := len(ps.parts)
-			y := ps.height - height
-			if localY < y && ps.parts[ps.selected].term != nil {
-				ps.parts[ps.selected].term.MouseEvent(localX, localY, event)
-			}
-			if ps.parts[ps.selected].term != nil {
-				ps.parts[ps.selected].term.Focus(false)
-			}
-			ps.mv.PreviousPart()
-			if ps.parts[ps.selected].term != nil {
-				ps.parts[ps.selected].term.Focus(true)
-			}
+	mouse, ok := event.(*tcell.EventMouse)
+	if !ok {
+		return
+	}
+	y := ps.partsStart()
+	if localY < y {
+		// the pager gets all the events drawn over it, including the
+		// wheel ones which would otherwise switch parts
+		if ps.parts[ps.selected].term != nil {
+			ps.parts[ps.selected].term.MouseEvent(localX, localY, event)
 		}
+		return
+	}
+	switch mouse.Buttons() {
+	case tcell.Button1:
+		ps.selectPart(localY - y)
+	case tcell.Button3:
+		if ps.selectPart(localY - y) {
+			ps.contextMenu()
+		}
+	case tcell.WheelDown:
+		ps.Focus(false)
+		ps.mv.NextPart()
+		ps.Focus(true)
+	case tcell.WheelUp:
+		ps.Focus(false)
+		ps.mv.PreviousPart()
+		ps.Focus(true)
+	}
+}
+
+// partsStart returns the row where the list of parts begins
+func (ps *PartSwitcher) partsStart() int {
+	if len(ps.parts) == 1 && !config.Viewer.AlwaysShowMime {
+		return ps.height
+	}
+	return ps.height - len(ps.parts)
+}
+
+// selectPart selects the i-th part unless it is a multipart container
+func (ps *PartSwitcher) selectPart(i int) bool {
+	if i < 0 || i >= len(ps.parts) {
+		return false
 	}
+	if ps.parts[i].part.MIMEType == "multipart" {
+		return false
+	}
+	ps.Focus(false)
+	ps.selected = i
+	ps.Focus(true)
+	ps.Invalidate()
+	return true
+}
+
+// contextMenu opens the actions which can be run on the selected part
+func (ps *PartSwitcher) contextMenu() {
+	save := "save "
+	if config.General.DefaultSavePath != "" {
+		save = "save"
+	}
+	ps.mv.acct.aerc.ContextMenu("Attachment", []Choice{
+		{Text: "open", Command: "open"},
+		{Text: "save", Command: save},
+		{Text: "pipe to...", Command: "pipe -p "},
+	})
 }
 
 func (ps *PartSwitcher) Cleanup() {
@@ -0,0 +1,246 @@
+package widgets
+
+import (
+	"sort"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/lithammer/fuzzysearch/fuzzy"
+	"github.com/mattn/go-runewidth"
+
+	"git.sr.ht/~rjarry/aerc/config"
+	"git.sr.ht/~rjarry/aerc/lib/ui"
+)
+
+// PickerItem is an entry which can be chosen in a Picker.
+type PickerItem struct {
+	Text  string
+	Value interface{}
+}
+
+// Picker is a fuzzy finder dialog. The items are ranked incrementally
+// against the typed query and displayed in a popover below the input field,
+// optionally along with a preview of the item under the cursor.
+type Picker struct {
+	Scrollable
+	title    string
+	items    []*PickerItem
+	matches  []*PickerItem
+	marked   map[*PickerItem]bool
+	cursor   int
+	multi    bool
+	input    *ui.TextInput
+	preview  func(*PickerItem) []string
+	uiConfig *config.UIConfig
+	cb       func([]*PickerItem)
+}
+
+// NewPicker creates a new picker. If multi is true, several items can be
+// marked with <Tab>. The callback receives the marked items, or the item
+// under the cursor if none are marked. It receives nil if the picker was
+// dismissed.
+func NewPicker(
+	title string, items []*PickerItem, multi bool,
+	uiConfig *config.UIConfig, preview func(*PickerItem) []string,
+	cb func([]*PickerItem),
+) *Picker {
+	p := &Picker{
+		title:    title,
+		items:    items,
+		marked:   make(map[*PickerItem]bool),
+		multi:    multi,
+		input:    ui.NewTextInput("", uiConfig).Prompt("> "),
+		preview:  preview,
+		uiConfig: uiConfig,
+		cb:       cb,
+	}
+	p.input.OnChange(func(ti *ui.TextInput) {
+		p.rank()
+		p.Invalidate()
+	})
+	p.input.Focus(true)
+	p.rank()
+	return p
+}
+
+// rank filters and sorts the items according to the query.
+func (p *Picker) rank() {
+	p.cursor = 0
+	p.matches = rankItems(p.input.String(), p.items)
+}
+
+// rankItems returns the items matching query, the closest matches first.
+// Items with the same distance keep their original order.
+func rankItems(query string, items []*PickerItem) []*PickerItem {
+	if query == "" {
+		return items
+	}
+	texts := make([]string, len(items))
+	for i, item := range items {
+		texts[i] = item.Text
+	}
+	ranks := fuzzy.RankFindFold(query, texts)
+	sort.SliceStable(ranks, func(i, j int) bool {
+		if ranks[i].Distance == ranks[j].Distance {
+			return ranks[i].OriginalIndex < ranks[j].OriginalIndex
+		}
+		return ranks[i].Distance < ranks[j].Distance
+	})
+	matches := make([]*PickerItem, 0, len(ranks))
+	for _, r := range ranks {
+		matches = append(matches, items[r.OriginalIndex])
+	}
+	return matches
+}
+
+func (p *Picker) Invalidate() {
+	ui.Invalidate()
+}
+
+func (p *Picker) Draw(ctx *ui.Context) {
+	defaultStyle := p.uiConfig.GetStyle(config.STYLE_DEFAULT)
+	titleStyle := p.uiConfig.GetStyle(config.STYLE_TITLE)
+	w, h := ctx.Width(), ctx.Height()
+	ctx.Fill(0, 0, w, h, ' ', defaultStyle)
+	ctx.Fill(0, 0, w, 1, ' ', titleStyle)
+	ctx.Printf(0, 0, titleStyle, "%s (%d/%d)",
+		p.title, len(p.matches), len(p.items))
+	p.input.Draw(ctx.Subcontext(0, 1, w, 1))
+	if h > 2 {
+		ctx.Popover(0, 1, w, h-2, &pickerResults{picker: p})
+	}
+}
+
+// pickerResults draws the ranked items and the preview pane.
+type pickerResults struct {
+	picker *Picker
+}
+
+func (r *pickerResults) Invalidate() {
+	ui.Invalidate()
+}
+
+func (r *pickerResults) Draw(ctx *ui.Context) {
+	p := r.picker
+	defaultStyle := p.uiConfig.GetStyle(config.STYLE_DEFAULT)
+	selectedStyle := p.uiConfig.GetStyleSelected(config.STYLE_DEFAULT)
+	markedStyle := p.uiConfig.GetStyle(config.STYLE_MSGLIST_MARKED)
+	w, h := ctx.Width(), ctx.Height()
+	ctx.Fill(0, 0, w, h, ' ', defaultStyle)
+
+	listWidth := w
+	if p.preview != nil && w >= 40 {
+		listWidth = w / 2
+		borderStyle := p.uiConfig.GetStyle(config.STYLE_BORDER)
+		ctx.Fill(listWidth, 0, 1, h, '│', borderStyle)
+		if p.cursor < len(p.matches) {
+			lines := p.preview(p.matches[p.cursor])
+			for y, line := range lines {
+				if y >= h {
+					break
+				}
+				line = runewidth.Truncate(line, w-listWidth-2, "…")
+				ctx.Printf(listWidth+2, y, defaultStyle, "%s", line)
+			}
+		}
+	}
+
+	p.UpdateScroller(h, len(p.matches))
+	p.EnsureScroll(p.cursor)
+	for y, i := 0, p.Scroll(); i < len(p.matches) && y < h; i, y = i+1, y+1 {
+		item := p.matches[i]
+		style := defaultStyle
+		if p.marked[item] {
+			style = markedStyle
+		}
+		if i == p.cursor {
+			style = selectedStyle
+		}
+		ctx.Fill(0, y, listWidth, 1, ' ', style)
+		prefix := "  "
+		if p.marked[item] {
+			prefix = "* "
+		}
+		text := runewidth.Truncate(prefix+item.Text, listWidth-1, "…")
+		ctx.Printf(0, y, style, "%s", text)
+	}
+}
+
+func (p *Picker) moveCursor(delta int) {
+	if len(p.matches) == 0 {
+		return
+	}
+	p.cursor += delta
+	if p.cursor < 0 {
+		p.cursor = 0
+	}
+	if p.cursor >= len(p.matches) {
+		p.cursor = len(p.matches) - 1
+	}
+	p.Invalidate()
+}
+
+func (p *Picker) Event(event tcell.Event) bool {
+	if event, ok := event.(*tcell.EventKey); ok {
+		switch event.Key() {
+		case tcell.KeyUp, tcell.KeyCtrlP:
+			p.moveCursor(-1)
+			return true
+		case tcell.KeyDown, tcell.KeyCtrlN:
+			p.moveCursor(+1)
+			return true
+		case tcell.KeyPgUp:
+			p.moveCursor(-p.height)
+			return true
+		case tcell.KeyPgDn:
+			p.moveCursor(+p.height)
+			return true
+		case tcell.KeyTab:
+			if p.multi && p.cursor < len(p.matches) {
+				item := p.matches[p.cursor]
+				if p.marked[item] {
+					delete(p.marked, item)
+				} else {
+					p.marked[item] = true
+				}
+				p.moveCursor(+1)
+			}
+			return true
+		case tcell.KeyEnter:
+			p.quit(p.selection())
+			return true
+		case tcell.KeyEsc, tcell.KeyCtrlC:
+			p.quit(nil)
+			return true
+		}
+	}
+	return p.input.Event(event)
+}
+
+// selection returns the marked items in their original order, or the item
+// under the cursor.
+func (p *Picker) selection() []*PickerItem {
+	var selected []*PickerItem
+	for _, item := range p.items {
+		if p.marked[item] {
+			selected = append(selected, item)
+		}
+	}
+	if len(selected) == 0 && p.cursor < len(p.matches) {
+		selected = append(selected, p.matches[p.cursor])
+	}
+	if selected == nil {
+		selected = []*PickerItem{}
+	}
+	return selected
+}
+
+func (p *Picker) quit(items []*PickerItem) {
+	p.input.Focus(false)
+	if p.cb != nil {
+		p.cb(items)
+	}
+}
+
+func (p *Picker) Focus(f bool) {
+	p.input.Focus(f)
+}
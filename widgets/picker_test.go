@@ -0,0 +1,56 @@
+package widgets
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRankItems(t *testing.T) {
+	var items []*PickerItem
+	for _, text := range []string{
+		"Archive/2022", "INBOX", "Archive", "Sent", "lists/aerc-devel",
+	} {
+		items = append(items, &PickerItem{Text: text})
+	}
+
+	tests := []struct {
+		query   string
+		matches []string
+	}{
+		{
+			query: "",
+			matches: []string{
+				"Archive/2022", "INBOX", "Archive", "Sent",
+				"lists/aerc-devel",
+			},
+		},
+		{
+			// closest first, ties keep the original order
+			query:   "arch",
+			matches: []string{"Archive", "Archive/2022"},
+		},
+		{
+			query:   "inbox",
+			matches: []string{"INBOX"},
+		},
+		{
+			query:   "adev",
+			matches: []string{"lists/aerc-devel"},
+		},
+		{
+			query:   "nomatch",
+			matches: []string{},
+		},
+	}
+
+	for _, test := range tests {
+		matches := []string{}
+		for _, item := range rankItems(test.query, items) {
+			matches = append(matches, item.Text)
+		}
+		if !reflect.DeepEqual(matches, test.matches) {
+			t.Errorf("%q: expected %v, got %v",
+				test.query, test.matches, matches)
+		}
+	}
+}
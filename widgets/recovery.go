@@ -0,0 +1,283 @@
+package widgets
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/kyoh86/xdg"
+
+	"git.sr.ht/~rjarry/aerc/config"
+	"git.sr.ht/~rjarry/aerc/lib/ui"
+	"git.sr.ht/~rjarry/aerc/log"
+)
+
+// recoveryInfo is written next to the draft autosaved by a composer. The
+// autosaved drafts of aerc instances which are not running anymore are
+// offered for recovery on startup.
+type recoveryInfo struct {
+	Pid     int       `json:"pid"`
+	Subject string    `json:"subject"`
+	Saved   time.Time `json:"saved"`
+	Draft   *Draft    `json:"draft"`
+	// path of the recovery info file
+	file string
+}
+
+var ErrNoRecovery = errors.New("No messages to recover.")
+
+func recoveryDir() string {
+	return path.Join(xdg.DataHome(), "aerc", "recovery")
+}
+
+// startAutosave saves the composer every autosave-interval until it is
+// closed.
+func (c *Composer) startAutosave() {
+	name := strings.TrimSuffix(filepath.Base(c.email.Name()), ".eml")
+	c.recovery = filepath.Join(recoveryDir(), name)
+	interval := config.Compose.AutosaveInterval
+	if interval <= 0 {
+		return
+	}
+	c.autosaveDone = make(chan struct{})
+	go func(done chan struct{}) {
+		defer log.PanicHandler()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				ui.QueueFunc(c.Autosave)
+			case <-done:
+				return
+			}
+		}
+	}(c.autosaveDone)
+}
+
+// Autosave saves the header, the body, the attachments and the crypto
+// settings of the composer in the recovery directory so that it can be
+// restored if aerc exits unexpectedly.
+func (c *Composer) Autosave() {
+	if c.email == nil || c.recovery == "" {
+		// the composer has been closed
+		return
+	}
+	err := os.MkdirAll(recoveryDir(), 0o700)
+	if err != nil {
+		log.Errorf("failed to create recovery directory: %v", err)
+		return
+	}
+	draft, err := c.SaveDraft(c.recovery + ".eml")
+	if err != nil {
+		log.Errorf("failed to autosave message: %v", err)
+		return
+	}
+	info := &recoveryInfo{
+		Pid:     os.Getpid(),
+		Subject: c.header.Get("Subject"),
+		Saved:   time.Now(),
+		Draft:   draft,
+	}
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		log.Errorf("failed to autosave message: %v", err)
+		return
+	}
+	if err := writeFile(c.recovery+".json", data); err != nil {
+		log.Errorf("failed to autosave message: %v", err)
+	}
+}
+
+// stopAutosave stops the autosave timer and deletes the autosaved draft
+func (c *Composer) stopAutosave() {
+	if c.autosaveDone != nil {
+		close(c.autosaveDone)
+		c.autosaveDone = nil
+	}
+	if c.recovery != "" {
+		removeRecovery(c.recovery)
+		c.recovery = ""
+	}
+}
+
+// removeRecovery deletes the files of an autosaved draft
+func removeRecovery(base string) {
+	files, err := filepath.Glob(base + ".*")
+	if err != nil {
+		return
+	}
+	for _, file := range files {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			log.Warnf("failed to remove %s: %v", file, err)
+		}
+	}
+}
+
+// discardRecovery deletes a draft autosaved by an aerc instance which is not
+// running anymore.
+func discardRecovery(base string) {
+	info, err := readRecoveryInfo(base + ".json")
+	if err == nil && !isRunning(info.Pid) {
+		removeRecovery(base)
+	}
+}
+
+// newerRecovery returns the draft autosaved by an aerc instance which is not
+// running anymore if it was saved after the given time.
+func newerRecovery(base string, saved time.Time) *recoveryInfo {
+	if base == "" {
+		return nil
+	}
+	info, err := readRecoveryInfo(base + ".json")
+	if err != nil || isRunning(info.Pid) || !info.Saved.After(saved) {
+		return nil
+	}
+	return info
+}
+
+func readRecoveryInfo(file string) (*recoveryInfo, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var info recoveryInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	if info.Draft == nil {
+		return nil, fmt.Errorf("%s: no draft", file)
+	}
+	info.file = file
+	return &info, nil
+}
+
+// isRunning returns true if pid is the current process or if another
+// process with this pid exists.
+func isRunning(pid int) bool {
+	if pid == os.Getpid() {
+		return true
+	}
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return p.Signal(syscall.Signal(0)) == nil
+}
+
+// orphanedDrafts returns the drafts autosaved by aerc instances which are
+// not running anymore, the most recent first.
+func orphanedDrafts() []*recoveryInfo {
+	files, err := filepath.Glob(filepath.Join(recoveryDir(), "*.json"))
+	if err != nil {
+		return nil
+	}
+	var infos []*recoveryInfo
+	for _, file := range files {
+		info, err := readRecoveryInfo(file)
+		if err != nil {
+			log.Warnf("recovery: %v", err)
+			continue
+		}
+		if !isRunning(info.Pid) {
+			infos = append(infos, info)
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Saved.After(infos[j].Saved)
+	})
+	return infos
+}
+
+// InitRecovery offers to restore the drafts which were autosaved by aerc
+// instances which are not running anymore: the composers of the instances
+// which crashed, or which exited without saving them in the session.
+func (aerc *Aerc) InitRecovery() {
+	err := aerc.RecoverDrafts()
+	if err != nil && !errors.Is(err, ErrNoRecovery) {
+		aerc.PushError(err.Error())
+	}
+}
+
+// RecoverDrafts opens a selector listing the orphaned autosaved drafts. The
+// chosen ones are restored in composer tabs, the others are kept.
+func (aerc *Aerc) RecoverDrafts() error {
+	infos := orphanedDrafts()
+	if len(infos) == 0 {
+		return ErrNoRecovery
+	}
+	var items []*PickerItem
+	for _, info := range infos {
+		subject := info.Subject
+		if subject == "" {
+			subject = "(no subject)"
+		}
+		items = append(items, &PickerItem{
+			Text: fmt.Sprintf("%s  %s  %s",
+				info.Saved.Format("2006-01-02 15:04"),
+				info.Draft.Account, subject),
+			Value: info,
+		})
+	}
+	preview := func(item *PickerItem) []string {
+		info, _ := item.Value.(*recoveryInfo)
+		data, err := os.ReadFile(info.Draft.File)
+		if err != nil {
+			return []string{err.Error()}
+		}
+		lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+		for _, path := range info.Draft.Attachments {
+			lines = append(lines, "Attachment: "+path)
+		}
+		for _, part := range info.Draft.Parts {
+			lines = append(lines, "Attachment: "+part.Name)
+		}
+		return lines
+	}
+	picker := NewPicker("Recover messages", items, true,
+		aerc.SelectedAccountUiConfig(), preview,
+		func(selected []*PickerItem) {
+			aerc.CloseDialog()
+			for _, item := range selected {
+				info, _ := item.Value.(*recoveryInfo)
+				if err := aerc.recoverDraft(info); err != nil {
+					aerc.PushError(err.Error())
+				}
+			}
+		},
+	)
+	aerc.AddDialog(NewDialog(picker,
+		func(h int) int { return h / 8 },
+		func(h int) int { return h * 3 / 4 },
+	))
+	return nil
+}
+
+// recoverDraft restores an autosaved draft in a new composer tab. The
+// composer autosaves it again under its own name.
+func (aerc *Aerc) recoverDraft(info *recoveryInfo) error {
+	composer, err := RestoreDraft(aerc, info.Draft)
+	if err != nil {
+		return err
+	}
+	title := info.Subject
+	if title == "" {
+		title = "Recovered"
+	}
+	tab := aerc.NewTab(composer, title)
+	composer.OnHeaderChange("Subject", func(subject string) {
+		tab.Name = subject
+		ui.Invalidate()
+	})
+	composer.Autosave()
+	removeRecovery(strings.TrimSuffix(info.file, ".json"))
+	return nil
+}
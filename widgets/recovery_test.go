@@ -0,0 +1,123 @@
+package widgets
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// deadPid returns the pid of a process which has exited
+func deadPid(t *testing.T) int {
+	t.Helper()
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Skipf("cannot run true: %v", err)
+	}
+	return cmd.Process.Pid
+}
+
+func writeRecovery(t *testing.T, name string, info *recoveryInfo) string {
+	t.Helper()
+	if err := os.MkdirAll(recoveryDir(), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	base := filepath.Join(recoveryDir(), name)
+	data, err := json.Marshal(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(base+".json", data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return base
+}
+
+func TestOrphanedDrafts(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	dead := deadPid(t)
+	now := time.Now()
+
+	writeRecovery(t, "running", &recoveryInfo{
+		Pid: os.Getpid(), Saved: now, Draft: &Draft{File: "running.eml"},
+	})
+	writeRecovery(t, "old", &recoveryInfo{
+		Pid: dead, Saved: now.Add(-time.Hour), Draft: &Draft{File: "old.eml"},
+	})
+	writeRecovery(t, "recent", &recoveryInfo{
+		Pid: dead, Saved: now.Add(-time.Minute), Draft: &Draft{File: "recent.eml"},
+	})
+	writeRecovery(t, "nodraft", &recoveryInfo{Pid: dead, Saved: now})
+	err := os.WriteFile(filepath.Join(recoveryDir(), "invalid.json"),
+		[]byte("{"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var files []string
+	for _, info := range orphanedDrafts() {
+		files = append(files, info.Draft.File)
+	}
+	expected := []string{"recent.eml", "old.eml"}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("expected %v, got %v", expected, files)
+	}
+}
+
+func TestNewerRecovery(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	dead := deadPid(t)
+	session := time.Now()
+
+	tests := []struct {
+		name  string
+		info  *recoveryInfo
+		newer bool
+	}{
+		{
+			name: "before",
+			info: &recoveryInfo{
+				Pid: dead, Saved: session.Add(-time.Minute),
+				Draft: &Draft{File: "before.eml"},
+			},
+		},
+		{
+			name: "after",
+			info: &recoveryInfo{
+				Pid: dead, Saved: session.Add(time.Minute),
+				Draft: &Draft{File: "after.eml"},
+			},
+			newer: true,
+		},
+		{
+			// still autosaved by a running instance
+			name: "running",
+			info: &recoveryInfo{
+				Pid: os.Getpid(), Saved: session.Add(time.Minute),
+				Draft: &Draft{File: "running.eml"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		base := writeRecovery(t, test.name, test.info)
+		info := newerRecovery(base, session)
+		switch {
+		case test.newer && (info == nil || info.Draft.File != test.info.Draft.File):
+			t.Errorf("%s: expected %s, got %+v",
+				test.name, test.info.Draft.File, info)
+		case !test.newer && info != nil:
+			t.Errorf("%s: expected nil, got %+v", test.name, info)
+		}
+	}
+	if info := newerRecovery("", session); info != nil {
+		t.Errorf("no recovery: expected nil, got %+v", info)
+	}
+	missing := filepath.Join(recoveryDir(), "missing")
+	if info := newerRecovery(missing, session); info != nil {
+		t.Errorf("missing: expected nil, got %+v", info)
+	}
+}
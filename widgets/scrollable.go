@@ -38,6 +38,21 @@ func (s *Scrollable) UpdateScroller(height, elems int) {
 	s.elems = elems
 }
 
+// ScrollBy moves the view by delta lines, keeping it within the elements
+func (s *Scrollable) ScrollBy(delta int) {
+	maxScroll := s.elems - s.height
+	if maxScroll < 0 {
+		maxScroll = 0
+	}
+	s.scroll += delta
+	switch {
+	case s.scroll > maxScroll:
+		s.scroll = maxScroll
+	case s.scroll < 0:
+		s.scroll = 0
+	}
+}
+
 func (s *Scrollable) EnsureScroll(selectingIdx int) {
 	if selectingIdx < 0 {
 		return
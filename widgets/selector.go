@@ -17,6 +17,9 @@ type Selector struct {
 	focus    int
 	options  []string
 	uiConfig *config.UIConfig
+	// horizontal position and width of the drawn options
+	offsets []int
+	widths  []int
 
 	onChoose func(option string)
 	onSelect func(option string)
@@ -75,6 +78,8 @@ func (sel *Selector) Draw(ctx *ui.Context) {
 		}
 	}
 
+	sel.offsets = make([]int, len(sel.options))
+	sel.widths = make([]int, len(sel.options))
 	x := 2
 	for i, option := range sel.options {
 		style := defaultSelectorStyle
@@ -102,12 +107,15 @@ func (sel *Selector) Draw(ctx *ui.Context) {
 
 				nextPos := 0
 				nextPos += ctx.Printf(nextPos, y, defaultSelectorStyle, "%c", leftArrow)
-				nextPos += ctx.Printf(nextPos, y, style, format, s)
+				sel.offsets[i] = nextPos
+				sel.widths[i] = ctx.Printf(nextPos, y, style, format, s)
+				nextPos += sel.widths[i]
 				ctx.Printf(nextPos, y, defaultSelectorStyle, "%c", rightArrow)
 			}
 		} else {
-			x += ctx.Printf(x, y, style, format, option)
-			x += space
+			sel.offsets[i] = x
+			sel.widths[i] = ctx.Printf(x, y, style, format, option)
+			x += sel.widths[i] + space
 		}
 	}
 }
@@ -163,6 +171,37 @@ func (sel *Selector) Event(event tcell.Event) bool {
 	return false
 }
 
+// clicked returns the index of the option drawn at x.
+func (sel *Selector) clicked(x int) (int, bool) {
+	for i, offset := range sel.offsets {
+		if sel.widths[i] > 0 && x >= offset && x < offset+sel.widths[i] {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func (sel *Selector) MouseEvent(localX int, localY int, event tcell.Event) {
+	if event, ok := event.(*tcell.EventMouse); ok {
+		switch event.Buttons() {
+		case tcell.Button1:
+			i, ok := sel.clicked(localX)
+			if !ok {
+				return
+			}
+			sel.focus = i
+			sel.Invalidate()
+			if sel.onChoose != nil {
+				sel.onChoose(sel.Selected())
+			}
+		case tcell.WheelUp:
+			sel.Event(tcell.NewEventKey(tcell.KeyLeft, 0, tcell.ModNone))
+		case tcell.WheelDown:
+			sel.Event(tcell.NewEventKey(tcell.KeyRight, 0, tcell.ModNone))
+		}
+	}
+}
+
 var ErrNoOptionSelected = fmt.Errorf("no option selected")
 
 type SelectorDialog struct {
@@ -171,6 +210,7 @@ type SelectorDialog struct {
 	prompt   string
 	uiConfig *config.UIConfig
 	selector *Selector
+	height   int
 }
 
 func NewSelectorDialog(title string, prompt string, options []string, focus int,
@@ -183,6 +223,10 @@ func NewSelectorDialog(title string, prompt string, options []string, focus int,
 		uiConfig: uiConfig,
 		selector: NewSelector(options, focus, uiConfig).Chooser(true),
 	}
+	sd.selector.OnChoose(func(option string) {
+		sd.selector.Focus(false)
+		sd.callback(option, nil)
+	})
 	sd.selector.Focus(true)
 	return sd
 }
@@ -199,6 +243,7 @@ func (gp *SelectorDialog) Draw(ctx *ui.Context) {
 	for i = 0; i < len(lines); i++ {
 		ctx.Printf(1, 2+i, defaultStyle, "%s", lines[i])
 	}
+	gp.height = ctx.Height()
 	gp.selector.Draw(ctx.Subcontext(1, ctx.Height()-1, ctx.Width()-2, 1))
 }
 
@@ -219,9 +264,6 @@ func (gp *SelectorDialog) Event(event tcell.Event) bool {
 	switch event := event.(type) {
 	case *tcell.EventKey:
 		switch event.Key() {
-		case tcell.KeyEnter:
-			gp.selector.Focus(false)
-			gp.callback(gp.selector.Selected(), nil)
 		case tcell.KeyEsc:
 			gp.selector.Focus(false)
 			gp.callback("", ErrNoOptionSelected)
@@ -234,6 +276,22 @@ func (gp *SelectorDialog) Event(event tcell.Event) bool {
 	return true
 }
 
+// MouseEvent chooses the clicked option. A click outside of the dialog
+// cancels it.
+func (gp *SelectorDialog) MouseEvent(localX int, localY int, event tcell.Event) {
+	if event, ok := event.(*tcell.EventMouse); ok {
+		switch {
+		case localY < 0 || localY >= gp.height:
+			if event.Buttons()&(tcell.Button1|tcell.Button2|tcell.Button3) != 0 {
+				gp.selector.Focus(false)
+				gp.callback("", ErrNoOptionSelected)
+			}
+		case localY == gp.height-1:
+			gp.selector.MouseEvent(localX-1, 0, event)
+		}
+	}
+}
+
 func (gp *SelectorDialog) Focus(f bool) {
 	gp.selector.Focus(f)
 }
@@ -0,0 +1,537 @@
+package widgets
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/kyoh86/xdg"
+
+	"git.sr.ht/~rjarry/aerc/config"
+	"git.sr.ht/~rjarry/aerc/lib"
+	"git.sr.ht/~rjarry/aerc/lib/ui"
+	"git.sr.ht/~rjarry/aerc/log"
+	"git.sr.ht/~rjarry/aerc/worker/types"
+)
+
+// Session is the state of the tabs, saved when aerc exits and restored when
+// it starts again. Terminals and the account wizard are not saved.
+type Session struct {
+	Saved time.Time     `json:"saved"`
+	Tabs  []*SessionTab `json:"tabs"`
+}
+
+type SessionTab struct {
+	Kind     string `json:"kind"`
+	Name     string `json:"name"`
+	Pinned   bool   `json:"pinned,omitempty"`
+	Selected bool   `json:"selected,omitempty"`
+	Account  string `json:"account"`
+	Folder   string `json:"folder,omitempty"`
+	// selected message of account tabs, displayed message of viewers
+	Uid       uint32           `json:"uid,omitempty"`
+	MessageId string           `json:"message-id,omitempty"`
+	SplitDir  string           `json:"split-dir,omitempty"`
+	SplitSize int              `json:"split-size,omitempty"`
+	Panes     [][]*SessionPane `json:"panes,omitempty"`
+	Draft     *Draft           `json:"draft,omitempty"`
+	// autosaved draft of the composer, restored instead of Draft if it
+	// was saved after the session
+	Recovery string `json:"recovery,omitempty"`
+}
+
+type SessionPane struct {
+	Account string `json:"account"`
+	Folder  string `json:"folder"`
+}
+
+const (
+	sessionAccount  = "account"
+	sessionViewer   = "viewer"
+	sessionComposer = "composer"
+	sessionLayout   = "layout"
+)
+
+// sessionRestore holds the state of an account restored from a session. The
+// viewers and layouts are opened once the folder has been loaded.
+type sessionRestore struct {
+	folder    string
+	uid       uint32
+	messageId string
+	tabs      []*SessionTab
+}
+
+// SessionFile returns the path of the session saved when aerc exits
+func SessionFile() string {
+	return path.Join(xdg.DataHome(), "aerc", "session.json")
+}
+
+// sessionDrafts returns the prefix of the directories of the composer drafts
+// of a session. Each save writes the drafts in a new directory, the previous
+// ones are removed once the session file refers to the new one.
+func sessionDrafts(file string) string {
+	return file + ".drafts"
+}
+
+// removeSessionDrafts deletes the draft directories of a session except keep
+func removeSessionDrafts(file string, keep string) {
+	dirs, err := filepath.Glob(sessionDrafts(file) + "*")
+	if err != nil {
+		return
+	}
+	for _, dir := range dirs {
+		if dir == keep {
+			continue
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			log.Warnf("failed to remove %s: %v", dir, err)
+		}
+	}
+}
+
+// InitSession restores the session saved when aerc exited and saves it
+// periodically while aerc is running.
+func (aerc *Aerc) InitSession() {
+	if !config.General.RestoreSession {
+		return
+	}
+	file := SessionFile()
+	if _, err := os.Stat(file); err == nil {
+		if err := aerc.LoadSession(file); err != nil {
+			log.Errorf("failed to restore session: %v", err)
+			aerc.PushError(fmt.Sprintf("failed to restore session: %v", err))
+		}
+	}
+	if interval := config.General.SessionSaveInterval; interval > 0 {
+		go func() {
+			defer log.PanicHandler()
+
+			for range time.Tick(interval) {
+				ui.QueueFunc(func() {
+					if err := aerc.SaveSession(file); err != nil {
+						log.Errorf("failed to save session: %v", err)
+					}
+				})
+			}
+		}()
+	}
+}
+
+// SaveSession writes the state of the tabs to file. The composers are saved
+// as drafts in a new directory next to it. The drafts of the previous save
+// are only removed once file has been replaced.
+func (aerc *Aerc) SaveSession(file string) error {
+	_, err := aerc.saveSession(file)
+	return err
+}
+
+// CloseSession saves the session when aerc exits. The autosaved drafts of the
+// composers saved in the session are removed, so that they are not offered
+// for recovery on the next start.
+func (aerc *Aerc) CloseSession(file string) error {
+	composers, err := aerc.saveSession(file)
+	if err != nil {
+		return err
+	}
+	for _, c := range composers {
+		c.stopAutosave()
+	}
+	return nil
+}
+
+// saveSession writes the session to file and returns the composers which
+// have been saved in it.
+func (aerc *Aerc) saveSession(file string) ([]*Composer, error) {
+	if err := os.MkdirAll(filepath.Dir(file), 0o700); err != nil {
+		return nil, err
+	}
+	drafts, err := os.MkdirTemp(filepath.Dir(file),
+		filepath.Base(sessionDrafts(file))+".")
+	if err != nil {
+		return nil, err
+	}
+	var composers []*Composer
+
+	session := &Session{Saved: time.Now()}
+	selected := aerc.SelectedTab()
+	for i := 0; ; i++ {
+		tab := aerc.tabs.Get(i)
+		if tab == nil {
+			break
+		}
+		st := &SessionTab{
+			Name:     tab.Name,
+			Pinned:   tab.Pinned(),
+			Selected: tab == selected,
+		}
+		switch content := tab.Content.(type) {
+		case *AccountView:
+			st.Kind = sessionAccount
+			st.Account = content.Name()
+			if content.Directories() != nil {
+				st.Folder = content.Directories().Selected()
+			}
+			if store := content.Store(); store != nil {
+				if msg := store.Selected(); msg != nil {
+					st.Uid = msg.Uid
+					if msg.Envelope != nil {
+						st.MessageId = msg.Envelope.MessageId
+					}
+				}
+			}
+			st.SplitDir = content.splitDir
+			st.SplitSize = content.SplitSize()
+		case *MessageViewer:
+			store := content.Store()
+			msg, err := content.SelectedMessage()
+			if store == nil || err != nil || content.acct == nil {
+				continue
+			}
+			st.Kind = sessionViewer
+			st.Account = content.acct.Name()
+			st.Folder = store.DirInfo.Name
+			st.Uid = msg.Uid
+			if msg.Envelope != nil {
+				st.MessageId = msg.Envelope.MessageId
+			}
+		case *Composer:
+			draft, err := content.SaveDraft(
+				filepath.Join(drafts, fmt.Sprintf("%d.eml", i)))
+			if err != nil {
+				log.Errorf("failed to save composer %q: %v", tab.Name, err)
+				continue
+			}
+			st.Kind = sessionComposer
+			st.Account = draft.Account
+			st.Draft = draft
+			st.Recovery = content.recovery
+			composers = append(composers, content)
+		case *Layout:
+			st.Kind = sessionLayout
+			st.Account = content.panes[0][0].acct.Name()
+			for _, row := range content.panes {
+				var panes []*SessionPane
+				for _, pane := range row {
+					panes = append(panes, &SessionPane{
+						Account: pane.acct.Name(),
+						Folder:  pane.acct.folder,
+					})
+				}
+				st.Panes = append(st.Panes, panes)
+			}
+		default:
+			continue
+		}
+		session.Tabs = append(session.Tabs, st)
+	}
+
+	data, err := json.MarshalIndent(session, "", "  ")
+	if err == nil {
+		err = writeFile(file, data)
+	}
+	if err != nil {
+		// the previous session is still valid, keep its drafts
+		if err := os.RemoveAll(drafts); err != nil {
+			log.Warnf("failed to remove %s: %v", drafts, err)
+		}
+		return nil, err
+	}
+	removeSessionDrafts(file, drafts)
+	return composers, nil
+}
+
+// LoadSession restores the tabs of a saved session. The account tabs open
+// their saved folder. The viewers and layouts are opened once the folders of
+// their accounts have been loaded.
+func (aerc *Aerc) LoadSession(file string) error {
+	session, err := readSession(file)
+	if err != nil {
+		return err
+	}
+
+	restores := make(map[*AccountView]*sessionRestore)
+	for _, st := range session.Tabs {
+		acct, err := aerc.Account(st.Account)
+		if err != nil || acct.Directories() == nil {
+			log.Warnf("session: tab %q not restored: %v", st.Name, err)
+			continue
+		}
+		r, ok := restores[acct]
+		if !ok {
+			r = &sessionRestore{}
+			restores[acct] = r
+		}
+		switch st.Kind {
+		case sessionAccount:
+			r.folder, r.uid, r.messageId = st.Folder, st.Uid, st.MessageId
+			switch {
+			case st.SplitDir == "split" && st.SplitSize > 0:
+				err = acct.Split(st.SplitSize)
+			case st.SplitDir == "vsplit" && st.SplitSize > 0:
+				err = acct.Vsplit(st.SplitSize)
+			}
+			if err != nil {
+				log.Warnf("session: %s: %v", acct.Name(), err)
+			}
+			aerc.restoreTab(aerc.tabOf(acct), st)
+		case sessionComposer:
+			draft := st.Draft
+			if info := newerRecovery(st.Recovery, session.Saved); info != nil {
+				// aerc did not exit properly after the session was
+				// saved, the autosaved draft has the latest changes
+				log.Debugf("session: %q restored from %s",
+					st.Name, info.file)
+				draft = info.Draft
+			}
+			if draft == nil {
+				continue
+			}
+			composer, err := RestoreDraft(aerc, draft)
+			if err != nil {
+				aerc.PushError(fmt.Sprintf("composer %q: %v", st.Name, err))
+				continue
+			}
+			if st.Recovery != "" {
+				discardRecovery(st.Recovery)
+			}
+			tab := aerc.addSessionTab(composer, st)
+			composer.OnHeaderChange("Subject", func(subject string) {
+				if subject == "" {
+					tab.Name = "New email"
+				} else {
+					tab.Name = subject
+				}
+				ui.Invalidate()
+			})
+		case sessionViewer, sessionLayout:
+			r.tabs = append(r.tabs, st)
+		default:
+			log.Warnf("session: unknown tab kind %q", st.Kind)
+		}
+	}
+	for acct, r := range restores {
+		acct.restoreSession(r)
+	}
+	return nil
+}
+
+func readSession(file string) (*Session, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	if session.Saved.IsZero() {
+		// saved by a previous version
+		if st, err := os.Stat(file); err == nil {
+			session.Saved = st.ModTime()
+		}
+	}
+	return &session, nil
+}
+
+// tabOf returns the tab which displays content
+func (aerc *Aerc) tabOf(content ui.Drawable) *ui.Tab {
+	for i := 0; ; i++ {
+		tab := aerc.tabs.Get(i)
+		if tab == nil || tab.Content == content {
+			return tab
+		}
+	}
+}
+
+func (aerc *Aerc) selectTab(tab *ui.Tab) {
+	for i := 0; ; i++ {
+		t := aerc.tabs.Get(i)
+		if t == nil {
+			return
+		}
+		if t == tab {
+			aerc.SelectTabIndex(i)
+			return
+		}
+	}
+}
+
+// restoreTab pins an existing tab if it was pinned in the session. The
+// selected tab is only changed if tab was the selected one.
+func (aerc *Aerc) restoreTab(tab *ui.Tab, st *SessionTab) {
+	if tab == nil {
+		return
+	}
+	previous := aerc.SelectedTab()
+	if st.Pinned && !tab.Pinned() {
+		aerc.selectTab(tab)
+		aerc.tabs.PinTab()
+	}
+	if st.Selected {
+		aerc.selectTab(tab)
+	} else {
+		aerc.selectTab(previous)
+	}
+}
+
+// addSessionTab adds a restored tab. It is only selected if it was the
+// selected tab of the session.
+func (aerc *Aerc) addSessionTab(content ui.Drawable, st *SessionTab) *ui.Tab {
+	previous := aerc.SelectedTab()
+	tab := aerc.NewTab(content, st.Name)
+	if st.Pinned {
+		aerc.tabs.PinTab()
+	}
+	if !st.Selected && previous != nil {
+		aerc.selectTab(previous)
+	}
+	return tab
+}
+
+// restoreSession opens the folder of a restored session. If the account is
+// not connected yet, it is opened instead of the default folder.
+func (acct *AccountView) restoreSession(r *sessionRestore) {
+	acct.session = r
+	if !acct.state.Connected() {
+		return
+	}
+	selected := acct.dirlist.Selected()
+	if r.folder == "" {
+		r.folder = selected
+	}
+	if r.folder == "" {
+		// no folder opened yet, wait for the first one
+		return
+	}
+	if store, ok := acct.dirlist.MsgStore(r.folder); ok && r.folder == selected {
+		acct.sessionFolderLoaded(store)
+	} else {
+		acct.dirlist.Select(r.folder)
+	}
+}
+
+// sessionFolder returns the folder of the restored session which must be
+// opened once connected, or an empty string if there is none.
+func (acct *AccountView) sessionFolder(dirs []string) string {
+	if acct.session == nil {
+		return ""
+	}
+	for _, dir := range dirs {
+		if dir == acct.session.folder {
+			return dir
+		}
+	}
+	// restore the session in the default folder
+	acct.session.folder = ""
+	return ""
+}
+
+// sessionFolderLoaded selects the message and opens the viewers and layouts
+// of the restored session once the contents of its folder are known.
+func (acct *AccountView) sessionFolderLoaded(store *lib.MessageStore) {
+	r := acct.session
+	if r == nil || (r.folder != "" && r.folder != store.DirInfo.Name) {
+		return
+	}
+	acct.session = nil
+
+	var uids []uint32
+	var viewers, layouts []*SessionTab
+	if r.uid != 0 && store.FindIndexByUid(r.uid) >= 0 {
+		uids = append(uids, r.uid)
+	}
+	for _, st := range r.tabs {
+		switch {
+		case st.Kind == sessionLayout:
+			layouts = append(layouts, st)
+		case st.Folder != store.DirInfo.Name:
+			log.Warnf("session: viewer %q not restored: not in %s",
+				st.Name, store.DirInfo.Name)
+		case store.FindIndexByUid(st.Uid) >= 0:
+			viewers = append(viewers, st)
+			uids = append(uids, st.Uid)
+		}
+	}
+
+	// the layouts open other folders, wait until the viewers are loaded
+	pending := len(viewers)
+	openLayouts := func() {
+		for _, st := range layouts {
+			acct.aerc.restoreLayout(acct, st)
+		}
+	}
+	if len(uids) == 0 {
+		openLayouts()
+		return
+	}
+
+	// the uids may have changed since the session was saved, compare the
+	// message ids before selecting the messages
+	sameMessage := func(uid uint32, messageId string) bool {
+		msg := store.Messages[uid]
+		return msg != nil && msg.Envelope != nil &&
+			(messageId == "" || msg.Envelope.MessageId == messageId)
+	}
+	store.FetchHeaders(uids, func(msg types.WorkerMessage) {
+		switch msg.(type) {
+		case *types.Done:
+		case *types.Error:
+			openLayouts()
+			return
+		default:
+			return
+		}
+		if r.uid != 0 && sameMessage(r.uid, r.messageId) {
+			store.Select(r.uid)
+		}
+		for _, st := range viewers {
+			st := st
+			if !sameMessage(st.Uid, st.MessageId) {
+				log.Warnf("session: viewer %q not restored: "+
+					"message not found", st.Name)
+				pending--
+				continue
+			}
+			lib.NewMessageStoreView(store.Messages[st.Uid], false,
+				store, acct.aerc.Crypto, acct.aerc.DecryptKeys,
+				func(view lib.MessageView, err error) {
+					if err != nil {
+						acct.aerc.PushError(err.Error())
+					} else {
+						acct.aerc.addSessionTab(
+							NewMessageViewer(acct, view), st)
+					}
+					pending--
+					if pending == 0 {
+						openLayouts()
+					}
+				})
+		}
+		if pending == 0 {
+			openLayouts()
+		}
+	})
+}
+
+func (aerc *Aerc) restoreLayout(acct *AccountView, st *SessionTab) {
+	var rows [][]*config.PaneConfig
+	for _, row := range st.Panes {
+		var panes []*config.PaneConfig
+		for _, pane := range row {
+			panes = append(panes, &config.PaneConfig{
+				Account: pane.Account,
+				Folder:  pane.Folder,
+			})
+		}
+		rows = append(rows, panes)
+	}
+	layout, err := NewLayout(aerc, rows, acct)
+	if err != nil {
+		aerc.PushError(fmt.Sprintf("layout %q: %v", st.Name, err))
+		return
+	}
+	aerc.addSessionTab(layout, st)
+}
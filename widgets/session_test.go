@@ -0,0 +1,118 @@
+package widgets
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"git.sr.ht/~rjarry/aerc/config"
+	"git.sr.ht/~rjarry/aerc/lib/ui"
+)
+
+func TestSessionRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "aerc", "session.json")
+	// drafts of a session saved by a previous version
+	legacy := sessionDrafts(file)
+	if err := os.MkdirAll(legacy, 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	aerc := &Aerc{tabs: ui.NewTabs(config.Ui)}
+	acct := &AccountView{
+		acct:      &config.AccountConfig{Name: "work"},
+		splitDir:  "vsplit",
+		splitSize: 40,
+	}
+	aerc.tabs.Add(acct, "work", nil)
+	composer := newTestComposer(t, "work", "Hello\r\n")
+	composer.recovery = filepath.Join(dir, "recovery", "aerc-1234")
+	aerc.tabs.Add(composer, "Meeting notes", nil)
+
+	var previous string
+	for i := 0; i < 2; i++ {
+		if err := aerc.SaveSession(file); err != nil {
+			t.Fatal(err)
+		}
+		session, err := readSession(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(session.Tabs) != 2 {
+			t.Fatalf("expected 2 tabs, got %d", len(session.Tabs))
+		}
+		expected := &SessionTab{
+			Kind:      sessionAccount,
+			Name:      "work",
+			Account:   "work",
+			SplitDir:  "vsplit",
+			SplitSize: 40,
+		}
+		if !reflect.DeepEqual(session.Tabs[0], expected) {
+			t.Errorf("expected %+v, got %+v", expected, session.Tabs[0])
+		}
+
+		st := session.Tabs[1]
+		if st.Kind != sessionComposer || !st.Selected ||
+			st.Name != "Meeting notes" || st.Account != "work" ||
+			st.Recovery != composer.recovery {
+			t.Errorf("unexpected composer tab %+v", st)
+		}
+		if st.Draft == nil {
+			t.Fatal("composer draft not saved")
+		}
+		_, body, err := st.Draft.read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(body) != "Hello\r\n" {
+			t.Errorf("unexpected draft body %q", body)
+		}
+
+		// only the drafts of the last save are kept
+		drafts, _ := filepath.Glob(sessionDrafts(file) + "*")
+		current := filepath.Dir(st.Draft.File)
+		if !reflect.DeepEqual(drafts, []string{current}) {
+			t.Errorf("expected drafts in %s, got %v", current, drafts)
+		}
+		if current == previous {
+			t.Errorf("drafts saved in the same directory twice")
+		}
+		previous = current
+	}
+}
+
+func TestCloseSession(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	file := filepath.Join(t.TempDir(), "aerc", "session.json")
+
+	aerc := &Aerc{tabs: ui.NewTabs(config.Ui)}
+	composer := newTestComposer(t, "work", "Hello\r\n")
+	composer.recovery = filepath.Join(recoveryDir(), "aerc-compose-1234")
+	aerc.tabs.Add(composer, "Meeting notes", nil)
+	composer.Autosave()
+	autosaved, _ := filepath.Glob(composer.recovery + ".*")
+	if len(autosaved) != 2 {
+		t.Fatalf("expected 2 autosaved files, got %v", autosaved)
+	}
+
+	if err := aerc.CloseSession(file); err != nil {
+		t.Fatal(err)
+	}
+	session, err := readSession(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(session.Tabs) != 1 || session.Tabs[0].Draft == nil {
+		t.Fatalf("composer not saved in the session: %+v", session.Tabs)
+	}
+	for _, f := range autosaved {
+		if _, err := os.Stat(f); !os.IsNotExist(err) {
+			t.Errorf("%s: not removed", f)
+		}
+	}
+	if infos := orphanedDrafts(); len(infos) != 0 {
+		t.Errorf("expected no orphaned drafts, got %+v", infos)
+	}
+}
@@ -0,0 +1,490 @@
+package widgets
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+
+	"git.sr.ht/~rjarry/aerc/config"
+	"git.sr.ht/~rjarry/aerc/lib/ui"
+	"git.sr.ht/~rjarry/aerc/worker/types"
+)
+
+// Sidebar lists the folders of all accounts as a tree. It is displayed on
+// the left of all tabs when global-sidebar is enabled.
+type Sidebar struct {
+	Scrollable
+	aerc     *Aerc
+	accounts map[string]*sidebarAccount
+	rows     []sidebarRow
+	// the cursor is on the account line if node is nil
+	cursorAcct *sidebarAccount
+	cursorNode *types.Thread
+	// folder of the selected tab, the cursor follows it when it changes
+	lastAcct *AccountView
+	lastDir  string
+	focus    bool
+	pressed  bool
+	press    int
+	// row of the cursor at the last draw, the view is only scrolled to
+	// the cursor when it moves
+	lastCursor int
+	// called with the folder chosen when picking a target folder
+	onPick func(acct *AccountView, folder string)
+}
+
+type sidebarAccount struct {
+	acct      *AccountView
+	tree      *DirectoryTree
+	collapsed bool
+}
+
+type sidebarRow struct {
+	acct *sidebarAccount
+	// nil for the account line
+	node *types.Thread
+}
+
+func NewSidebar(aerc *Aerc) *Sidebar {
+	return &Sidebar{
+		aerc:     aerc,
+		accounts: make(map[string]*sidebarAccount),
+	}
+}
+
+func (sb *Sidebar) Invalidate() {
+	ui.Invalidate()
+}
+
+// directoryList returns the flat folder list of an account, whether it is
+// displayed as a tree or not.
+func directoryList(acct *AccountView) *DirectoryList {
+	switch dirlist := acct.Directories().(type) {
+	case *DirectoryList:
+		return dirlist
+	case *DirectoryTree:
+		return dirlist.DirectoryList
+	}
+	return nil
+}
+
+// updateRows builds the rows of the accounts in configuration order and
+// refreshes their folder trees when the folder lists have changed.
+func (sb *Sidebar) updateRows() {
+	sb.rows = sb.rows[:0]
+	for _, conf := range config.Accounts {
+		acct, ok := sb.aerc.accounts[conf.Name]
+		if !ok || acct.Directories() == nil {
+			continue
+		}
+		sa, ok := sb.accounts[conf.Name]
+		if !ok || sa.acct != acct {
+			dirlist := directoryList(acct)
+			if dirlist == nil {
+				continue
+			}
+			tree, _ := NewDirectoryTree(dirlist,
+				string(os.PathSeparator)).(*DirectoryTree)
+			sa = &sidebarAccount{acct: acct, tree: tree}
+			sb.accounts[conf.Name] = sa
+		}
+		if !sameStrings(sa.tree.treeDirs, sa.tree.dirs) {
+			sa.tree.buildTree()
+		}
+		sb.rows = append(sb.rows, sidebarRow{acct: sa})
+		if sa.collapsed {
+			continue
+		}
+		for _, node := range sa.tree.list {
+			if isVisible(node) {
+				sb.rows = append(sb.rows, sidebarRow{acct: sa, node: node})
+			}
+		}
+	}
+}
+
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// follow moves the cursor to the folder of the selected tab when it has
+// changed since the last draw.
+func (sb *Sidebar) follow() {
+	acct := sb.aerc.SelectedAccount()
+	if acct == nil || acct.Directories() == nil {
+		return
+	}
+	dir := acct.Directories().Selected()
+	if acct == sb.lastAcct && dir == sb.lastDir {
+		return
+	}
+	sb.lastAcct, sb.lastDir = acct, dir
+	for _, row := range sb.rows {
+		if row.acct.acct == acct && row.node != nil &&
+			row.acct.tree.getDirectory(row.node) == dir {
+			sb.cursorAcct, sb.cursorNode = row.acct, row.node
+			return
+		}
+	}
+	// the folder is hidden in a collapsed account or tree node
+	if sa, ok := sb.accounts[acct.Name()]; ok {
+		sb.cursorAcct, sb.cursorNode = sa, nil
+	}
+}
+
+func (sb *Sidebar) cursor() int {
+	for i, row := range sb.rows {
+		if row.acct == sb.cursorAcct && row.node == sb.cursorNode {
+			return i
+		}
+	}
+	return -1
+}
+
+func (sb *Sidebar) Draw(ctx *ui.Context) {
+	if !config.Ui.GlobalSidebar || ctx.Width() < 2 {
+		return
+	}
+	uiConfig := config.Ui
+	defaultStyle := uiConfig.GetStyle(config.STYLE_DIRLIST_DEFAULT)
+	ctx.Fill(0, 0, ctx.Width(), ctx.Height(), ' ', defaultStyle)
+
+	sb.updateRows()
+	if sb.onPick == nil {
+		sb.follow()
+	}
+	if len(sb.rows) == 0 {
+		ctx.Printf(0, 0, defaultStyle, "%s", uiConfig.EmptyDirlist)
+		return
+	}
+	cursor := sb.cursor()
+	if cursor < 0 {
+		cursor = 0
+		sb.cursorAcct, sb.cursorNode = sb.rows[0].acct, sb.rows[0].node
+	}
+
+	// keep the right border of the sidebar
+	width := ctx.Width() - 1
+	sb.UpdateScroller(ctx.Height(), len(sb.rows))
+	if cursor != sb.lastCursor {
+		sb.lastCursor = cursor
+		sb.EnsureScroll(cursor)
+	}
+	// the rows may have changed since the view was scrolled
+	sb.ScrollBy(0)
+	if sb.NeedScrollbar() {
+		width--
+	}
+	if width <= 0 {
+		return
+	}
+
+	for i, row := range sb.rows {
+		if i < sb.Scroll() {
+			continue
+		}
+		y := i - sb.Scroll()
+		if y >= ctx.Height() {
+			break
+		}
+		if row.node == nil {
+			sb.drawAccount(ctx, y, width, row.acct, i == cursor)
+		} else {
+			sb.drawFolder(ctx, y, width, row.acct, row.node, i == cursor)
+		}
+	}
+
+	if sb.NeedScrollbar() {
+		sb.drawScrollbar(ctx.Subcontext(width, 0, 1, ctx.Height()))
+	}
+	borderStyle := uiConfig.GetStyle(config.STYLE_BORDER)
+	ctx.Fill(ctx.Width()-1, 0, 1, ctx.Height(),
+		uiConfig.BorderCharVertical, borderStyle)
+}
+
+func (sb *Sidebar) drawAccount(
+	ctx *ui.Context, y, width int, sa *sidebarAccount, selected bool,
+) {
+	uiConfig := sa.acct.UiConfig()
+	style := uiConfig.GetStyle(config.STYLE_TITLE)
+	if selected {
+		style = uiConfig.GetStyleSelected(config.STYLE_TITLE)
+	}
+	ctx.Fill(0, y, width, 1, ' ', style)
+	flag := "▾"
+	if sa.collapsed {
+		flag = "▸"
+	}
+	name := runewidth.Truncate(fmt.Sprintf("%s %s", flag, sa.acct.Name()),
+		width, "…")
+	ctx.Printf(0, y, style, "%s", name)
+
+	// unread messages of all the folders which have been checked
+	unread := 0
+	for _, dir := range sa.tree.dirs {
+		store, ok := sa.tree.MsgStore(dir)
+		if !ok {
+			continue
+		}
+		if !store.DirInfo.AccurateCounts {
+			store.DirInfo.Recent, store.DirInfo.Unseen = countRUE(store)
+		}
+		unread += store.DirInfo.Unseen
+	}
+	if unread > 0 {
+		count := fmt.Sprintf(" %d", unread)
+		if w := runewidth.StringWidth(count); w+runewidth.StringWidth(name) <= width {
+			ctx.Printf(width-w, y, style, "%s", count)
+		}
+	}
+}
+
+func (sb *Sidebar) drawFolder(
+	ctx *ui.Context, y, width int, sa *sidebarAccount,
+	node *types.Thread, selected bool,
+) {
+	const indent = 2
+	tree := sa.tree
+	path := tree.getDirectory(node)
+	rue := tree.getRUEString(path)
+
+	var dirStyle []config.StyleObject
+	switch strings.Count(rue, "/") {
+	case 1:
+		dirStyle = append(dirStyle, config.STYLE_DIRLIST_UNREAD)
+	case 2:
+		dirStyle = append(dirStyle, config.STYLE_DIRLIST_RECENT)
+	}
+	uiConfig := tree.UiConfig(path)
+	style := uiConfig.GetComposedStyle(config.STYLE_DIRLIST_DEFAULT, dirStyle)
+	if selected {
+		style = uiConfig.GetComposedStyleSelected(
+			config.STYLE_DIRLIST_DEFAULT, dirStyle)
+	}
+	ctx.Fill(0, y, width, 1, ' ', style)
+	if width <= indent {
+		return
+	}
+	text := tree.getDirString(tree.displayText(node), width-indent,
+		func() string {
+			if path != "" {
+				return rue
+			}
+			return ""
+		})
+	ctx.Printf(indent, y, style, "%s", text)
+}
+
+func (sb *Sidebar) drawScrollbar(ctx *ui.Context) {
+	gutterStyle := tcell.StyleDefault
+	pillStyle := tcell.StyleDefault.Reverse(true)
+
+	ctx.Fill(0, 0, 1, ctx.Height(), ' ', gutterStyle)
+
+	pillSize := int(math.Ceil(float64(ctx.Height()) * sb.PercentVisible()))
+	pillOffset := int(math.Floor(float64(ctx.Height()) * sb.PercentScrolled()))
+	ctx.Fill(0, pillOffset, 1, pillSize, ' ', pillStyle)
+}
+
+// NextPrev moves the cursor by delta rows, across accounts. The folder under
+// the cursor is opened unless a target folder is being picked.
+func (sb *Sidebar) NextPrev(delta int) {
+	sb.updateRows()
+	if len(sb.rows) == 0 {
+		return
+	}
+	cursor := sb.cursor() + delta
+	switch {
+	case cursor < 0:
+		cursor = 0
+	case cursor >= len(sb.rows):
+		cursor = len(sb.rows) - 1
+	}
+	sb.moveCursor(cursor)
+}
+
+func (sb *Sidebar) moveCursor(i int) {
+	row := sb.rows[i]
+	sb.cursorAcct, sb.cursorNode = row.acct, row.node
+	sb.Invalidate()
+	if sb.onPick != nil {
+		return
+	}
+	sb.open(row)
+}
+
+// open switches to the account of the row and opens its folder
+func (sb *Sidebar) open(row sidebarRow) {
+	acct := row.acct.acct
+	if sb.aerc.SelectedAccount() != acct {
+		sb.aerc.SelectTab(acct.Name())
+	}
+	if row.node == nil {
+		return
+	}
+	if path := row.acct.tree.getDirectory(row.node); path != "" &&
+		path != acct.Directories().Selected() {
+		acct.Directories().Select(path)
+	}
+	sb.lastAcct, sb.lastDir = nil, ""
+}
+
+// Toggle collapses or expands the account or the folder under the cursor
+func (sb *Sidebar) Toggle() {
+	if sb.cursorAcct == nil {
+		return
+	}
+	if sb.cursorNode == nil {
+		sb.cursorAcct.collapsed = !sb.cursorAcct.collapsed
+	} else if sb.cursorNode.FirstChild != nil {
+		sb.cursorNode.Hidden = !sb.cursorNode.Hidden
+	}
+	sb.Invalidate()
+}
+
+// Pick lets the user choose a folder of acct in the sidebar. The cursor is
+// moved with the arrow keys, <Enter> confirms the choice and <Esc> cancels
+// it.
+func (sb *Sidebar) Pick(acct *AccountView, onPick func(string)) error {
+	if acct == nil {
+		return errors.New("No account selected")
+	}
+	sb.onPick = func(picked *AccountView, folder string) {
+		sb.onPick = nil
+		sb.aerc.focus(nil)
+		sb.lastAcct, sb.lastDir = nil, ""
+		if picked == nil {
+			sb.aerc.PushStatus("Cancelled", 5*time.Second)
+			return
+		}
+		if picked != acct {
+			sb.aerc.PushError(fmt.Sprintf(
+				"%s is not a folder of %s", folder, acct.Name()))
+			return
+		}
+		onPick(folder)
+	}
+	sb.aerc.focus(sb)
+	sb.aerc.PushStatus("Select a folder and press <Enter>", 10*time.Second)
+	return nil
+}
+
+func (sb *Sidebar) pickCursor() {
+	if sb.cursorAcct == nil || sb.cursorNode == nil {
+		return
+	}
+	path := sb.cursorAcct.tree.getDirectory(sb.cursorNode)
+	if path == "" {
+		return
+	}
+	sb.onPick(sb.cursorAcct.acct, path)
+}
+
+func (sb *Sidebar) Focus(focus bool) {
+	sb.focus = focus
+	sb.Invalidate()
+}
+
+func (sb *Sidebar) Event(event tcell.Event) bool {
+	if sb.onPick == nil {
+		return false
+	}
+	if mouse, ok := event.(*tcell.EventMouse); ok {
+		// the sidebar is below the tab strip, ignore clicks elsewhere
+		x, y := mouse.Position()
+		if x < config.Ui.SidebarWidth && y > 0 {
+			sb.MouseEvent(x, y-1, event)
+		}
+		return true
+	}
+	key, ok := event.(*tcell.EventKey)
+	if !ok {
+		return false
+	}
+	switch key.Key() {
+	case tcell.KeyUp, tcell.KeyCtrlP:
+		sb.NextPrev(-1)
+	case tcell.KeyDown, tcell.KeyCtrlN:
+		sb.NextPrev(1)
+	case tcell.KeyLeft, tcell.KeyRight:
+		sb.Toggle()
+	case tcell.KeyEnter:
+		sb.pickCursor()
+	case tcell.KeyEsc:
+		sb.onPick(nil, "")
+	case tcell.KeyRune:
+		switch key.Rune() {
+		case 'k':
+			sb.NextPrev(-1)
+		case 'j':
+			sb.NextPrev(1)
+		case 'h', 'l', ' ':
+			sb.Toggle()
+		}
+	}
+	return true
+}
+
+func (sb *Sidebar) MouseEvent(localX int, localY int, event tcell.Event) {
+	ev, ok := event.(*tcell.EventMouse)
+	if !ok {
+		return
+	}
+	switch ev.Buttons() {
+	case tcell.Button1, tcell.Button3:
+		// ignore the motion events while the button is held, the
+		// release may have happened outside of the sidebar
+		if sb.pressed && sb.press == sb.aerc.MousePress() {
+			return
+		}
+		sb.pressed = true
+		sb.press = sb.aerc.MousePress()
+		i := localY + sb.Scroll()
+		if i < 0 || i >= len(sb.rows) {
+			return
+		}
+		row := sb.rows[i]
+		sb.cursorAcct, sb.cursorNode = row.acct, row.node
+		sb.Invalidate()
+		path := ""
+		if row.node != nil {
+			path = row.acct.tree.getDirectory(row.node)
+		}
+		switch {
+		case path == "":
+			sb.Toggle()
+		case sb.onPick != nil:
+			sb.pickCursor()
+			return
+		}
+		if sb.onPick != nil {
+			return
+		}
+		sb.open(row)
+		if ev.Buttons() == tcell.Button3 && path != "" {
+			directoryList(row.acct.acct).contextMenu()
+		}
+	case tcell.ButtonNone:
+		sb.pressed = false
+	case tcell.WheelDown:
+		sb.ScrollBy(1)
+		sb.Invalidate()
+	case tcell.WheelUp:
+		sb.ScrollBy(-1)
+		sb.Invalidate()
+	}
+}
@@ -0,0 +1,95 @@
+package widgets
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+
+	"git.sr.ht/~rjarry/aerc/worker/types"
+)
+
+func newTestSidebar(folders ...string) *Sidebar {
+	sa := &sidebarAccount{
+		tree: &DirectoryTree{treeDirs: folders},
+	}
+	sb := &Sidebar{aerc: &Aerc{}}
+	sb.rows = append(sb.rows, sidebarRow{acct: sa})
+	for i := range folders {
+		node := &types.Thread{Uid: uint32(i)}
+		sb.rows = append(sb.rows, sidebarRow{acct: sa, node: node})
+	}
+	sb.cursorAcct, sb.cursorNode = sa, nil
+	return sb
+}
+
+func (sb *Sidebar) testMouse(y int, buttons tcell.ButtonMask) {
+	event := tcell.NewEventMouse(0, y, buttons, tcell.ModNone)
+	sb.aerc.trackPress(event)
+	sb.MouseEvent(0, y, event)
+}
+
+func TestSidebarWheel(t *testing.T) {
+	sb := newTestSidebar("INBOX", "Archive", "Drafts", "Sent", "Trash")
+	sb.UpdateScroller(3, len(sb.rows))
+
+	tests := []struct {
+		button tcell.ButtonMask
+		scroll int
+	}{
+		{tcell.WheelDown, 1},
+		{tcell.WheelDown, 2},
+		{tcell.WheelDown, 3},
+		// the last row is at the bottom of the view
+		{tcell.WheelDown, 3},
+		{tcell.WheelUp, 2},
+		{tcell.WheelUp, 1},
+		{tcell.WheelUp, 0},
+		{tcell.WheelUp, 0},
+	}
+
+	for i, test := range tests {
+		sb.testMouse(0, test.button)
+		if sb.Scroll() != test.scroll {
+			t.Errorf("#%d: expected scroll %d, got %d",
+				i, test.scroll, sb.Scroll())
+		}
+		if sb.cursorNode != nil {
+			t.Errorf("#%d: the cursor has moved", i)
+		}
+	}
+}
+
+func TestSidebarPick(t *testing.T) {
+	sb := newTestSidebar("INBOX", "Archive", "Drafts", "Sent", "Trash")
+	sb.UpdateScroller(3, len(sb.rows))
+	var picked []string
+	var onPick func(*AccountView, string)
+	onPick = func(_ *AccountView, folder string) {
+		picked = append(picked, folder)
+		// picking stops after a folder is chosen, keep going
+		sb.onPick = onPick
+	}
+	sb.onPick = onPick
+
+	sb.testMouse(1, tcell.Button1)
+	// motion with the button held
+	sb.testMouse(2, tcell.Button1)
+	sb.testMouse(2, tcell.ButtonNone)
+	// the release of this click happens outside of the sidebar
+	sb.testMouse(2, tcell.Button1)
+	sb.aerc.trackPress(tcell.NewEventMouse(
+		50, 2, tcell.ButtonNone, tcell.ModNone))
+	sb.testMouse(1, tcell.Button1)
+	sb.testMouse(1, tcell.ButtonNone)
+	// rows are offset by the scrolled lines
+	sb.testMouse(0, tcell.WheelDown)
+	sb.testMouse(0, tcell.WheelDown)
+	sb.testMouse(2, tcell.Button1)
+	sb.testMouse(2, tcell.ButtonNone)
+
+	expected := []string{"INBOX", "Archive", "INBOX", "Sent"}
+	if !reflect.DeepEqual(picked, expected) {
+		t.Errorf("expected %v, got %v", expected, picked)
+	}
+}
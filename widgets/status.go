@@ -1,6 +1,7 @@
 package widgets
 
 import (
+	"bytes"
 	"time"
 
 	"github.com/gdamore/tcell/v2"
@@ -47,8 +48,62 @@ func (status *StatusLine) Draw(ctx *ui.Context) {
 			pendingKeys += string(pendingKey.Rune)
 		}
 	}
-	message := runewidth.FillRight(line.message, ctx.Width()-len(pendingKeys)-5)
-	ctx.Printf(0, 0, line.style, "%s%s", message, pendingKeys)
+	width := ctx.Width() - runewidth.StringWidth(pendingKeys)
+	if line == &status.fallback && line.message == "" {
+		// no status message, show the state of the selected account
+		acct := status.selectedAccount()
+		if acct != nil && width > 0 &&
+			len(config.Statusline.StatusColumns) > 0 {
+			status.drawColumns(ctx.Subcontext(0, 0, width, 1), acct)
+		}
+	} else {
+		message := runewidth.FillRight(line.message, width-5)
+		ctx.Printf(0, 0, line.style, "%s", message)
+	}
+	ctx.Printf(width, 0, line.style, "%s", pendingKeys)
+}
+
+func (status *StatusLine) selectedAccount() *AccountView {
+	if status.aerc == nil {
+		return nil
+	}
+	return status.aerc.SelectedAccount()
+}
+
+// drawColumns renders the status-columns templates with the state of acct.
+func (status *StatusLine) drawColumns(ctx *ui.Context, acct *AccountView) {
+	uiConfig := acct.UiConfig()
+	data := acct.StatusData()
+	if pm, ok := status.aerc.SelectedTabContent().(ProvidesMessage); ok {
+		if msg, err := pm.SelectedMessage(); err == nil && msg != nil {
+			data.SetInfo(msg, 0, false)
+		}
+	}
+
+	table := ui.NewTable(
+		ctx.Width(), 1,
+		config.Statusline.StatusColumns,
+		config.Statusline.ColumnSeparator,
+		nil,
+		func(*ui.Table, int) tcell.Style {
+			return uiConfig.GetStyle(config.STYLE_STATUSLINE_DEFAULT)
+		},
+	)
+	table.GetCellStyle = func(t *ui.Table, _ int, c int) tcell.Style {
+		return uiConfig.GetStatuslineColumnStyle(t.Columns[c].Def.Name)
+	}
+	cells := make([]string, len(table.Columns))
+	for c, col := range table.Columns {
+		var buf bytes.Buffer
+		err := col.Def.Template.Execute(&buf, data)
+		if err != nil {
+			cells[c] = err.Error()
+		} else {
+			cells[c] = buf.String()
+		}
+	}
+	table.AddRow(cells, nil)
+	table.Draw(ctx)
 }
 
 func (status *StatusLine) Set(text string) *StatusMessage {
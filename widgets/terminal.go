@@ -18,6 +18,7 @@ type Terminal struct {
 	ctx       *ui.Context
 	destroyed bool
 	focus     bool
+	mouse     bool
 	vterm     *tcellterm.Terminal
 	running   bool
 
@@ -117,6 +118,23 @@ func (term *Terminal) MouseEvent(localX int, localY int, event tcell.Event) {
 	if term.closed {
 		return
 	}
+	if !term.mouse {
+		// the child program did not enable mouse reporting, scroll it
+		// with the arrow keys instead of sending escape sequences it
+		// does not understand
+		key := tcell.KeyDown
+		switch ev.Buttons() {
+		case tcell.WheelUp:
+			key = tcell.KeyUp
+		case tcell.WheelDown:
+		default:
+			return
+		}
+		for i := 0; i < 3; i++ {
+			term.vterm.HandleEvent(tcell.NewEventKey(key, 0, tcell.ModNone))
+		}
+		return
+	}
 	e := tcell.NewEventMouse(localX, localY, ev.Buttons(), ev.Modifiers())
 	term.vterm.HandleEvent(e)
 }
@@ -147,6 +165,8 @@ func (term *Terminal) HandleEvent(ev tcell.Event) bool {
 	case *views.EventWidgetContent:
 		ui.QueueRedraw()
 		return true
+	case *tcellterm.EventMouseMode:
+		term.mouse = len(ev.Flags()) > 0
 	case *tcellterm.EventTitle:
 		if term.OnTitle != nil {
 			term.OnTitle(ev.Title())
@@ -0,0 +1,256 @@
+package archive
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"git.sr.ht/~rjarry/aerc/models"
+	"git.sr.ht/~rjarry/aerc/worker/lib"
+	"github.com/emersion/go-maildir"
+)
+
+var errReadOnly = fmt.Errorf("archive is read-only")
+
+// archive holds the folders found in a tarball of a maildir tree.
+type archive struct {
+	file    *os.File
+	folders map[string]*folder
+}
+
+type folder struct {
+	messages []*message
+}
+
+// message is a maildir message stored in the tarball. Its content is read
+// directly from the archive file when needed.
+type message struct {
+	uid    uint32
+	flags  models.Flags
+	src    io.ReaderAt
+	offset int64
+	size   int64
+}
+
+// openArchive scans a tarball, which may be compressed. Compressed archives
+// are decompressed once to an anonymous temporary file so that messages can
+// be read at random.
+func openArchive(filename string) (*archive, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	head := make([]byte, 8)
+	n, err := file.ReadAt(head, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		file.Close()
+		return nil, err
+	}
+	if codec := lib.DetectCodec(head[:n]); codec != nil {
+		tmp, err := codec.DecompressToTemp(file)
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+		file = tmp
+	}
+	a := &archive{file: file, folders: make(map[string]*folder)}
+	if err := a.scan(file); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return a, nil
+}
+
+// countingReader keeps track of the number of bytes read.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// scan records the location of all messages found in cur/ and new/
+// directories of the tarball.
+func (a *archive) scan(file *os.File) error {
+	cr := &countingReader{r: io.NewSectionReader(file, 0, 1<<62)}
+	tr := tar.NewReader(cr)
+	dirs := make(map[string]*folder)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := path.Clean(strings.TrimPrefix(hdr.Name, "/"))
+		dir, base := path.Split(name)
+		dir = path.Clean(dir)
+		sub := path.Base(dir)
+		if sub != "cur" && sub != "new" {
+			continue
+		}
+		key := path.Dir(dir)
+		f, ok := dirs[key]
+		if !ok {
+			f = &folder{}
+			dirs[key] = f
+		}
+		flags := maildirFlags(base)
+		if sub == "new" {
+			flags |= models.RecentFlag
+		}
+		f.messages = append(f.messages, &message{
+			uid:    uint32(len(f.messages)),
+			flags:  flags,
+			src:    file,
+			offset: cr.n,
+			size:   hdr.Size,
+		})
+	}
+	root := commonParent(dirs)
+	for key, f := range dirs {
+		name := strings.TrimPrefix(strings.TrimPrefix(key, root), "/")
+		if root == "." {
+			name = key
+		}
+		if name == "" || name == "." {
+			// a maildir at the root of the tree
+			name = "INBOX"
+		}
+		a.folders[name] = f
+	}
+	return nil
+}
+
+// commonParent returns the deepest directory which contains all maildir
+// folders of the archive.
+func commonParent(dirs map[string]*folder) string {
+	var root []string
+	first := true
+	for key := range dirs {
+		if key == "." {
+			return "."
+		}
+		parts := strings.Split(path.Dir(key), "/")
+		if first {
+			root = parts
+			first = false
+			continue
+		}
+		i := 0
+		for i < len(root) && i < len(parts) && root[i] == parts[i] {
+			i++
+		}
+		root = root[:i]
+	}
+	if len(root) == 0 {
+		return "."
+	}
+	return strings.Join(root, "/")
+}
+
+// maildirFlags parses the info part of a maildir file name.
+func maildirFlags(filename string) models.Flags {
+	i := strings.LastIndex(filename, ":2,")
+	if i < 0 {
+		return 0
+	}
+	var flags []maildir.Flag
+	for _, r := range filename[i+3:] {
+		flags = append(flags, maildir.Flag(r))
+	}
+	return lib.FromMaildirFlags(flags)
+}
+
+func (a *archive) Names() []string {
+	names := make([]string, 0, len(a.folders))
+	for name := range a.folders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (a *archive) Folder(name string) (*folder, bool) {
+	f, ok := a.folders[name]
+	return f, ok
+}
+
+func (a *archive) Close() error {
+	if a.file == nil {
+		return nil
+	}
+	return a.file.Close()
+}
+
+func (a *archive) DirectoryInfo(name string) *models.DirectoryInfo {
+	var exists, recent, unseen int
+	if f, ok := a.Folder(name); ok {
+		exists = len(f.messages)
+		for _, m := range f.messages {
+			if m.flags.Has(models.RecentFlag) {
+				recent++
+			}
+			if !m.flags.Has(models.SeenFlag) {
+				unseen++
+			}
+		}
+	}
+	return &models.DirectoryInfo{
+		Name:           name,
+		Flags:          []string{},
+		ReadOnly:       true,
+		Exists:         exists,
+		Recent:         recent,
+		Unseen:         unseen,
+		AccurateCounts: true,
+		Caps: &models.Capabilities{
+			Sort:   true,
+			Thread: false,
+		},
+	}
+}
+
+func (f *folder) Uids() []uint32 {
+	uids := make([]uint32, len(f.messages))
+	for i, m := range f.messages {
+		uids[i] = m.uid
+	}
+	return uids
+}
+
+func (f *folder) Message(uid uint32) (*message, error) {
+	if int(uid) < len(f.messages) {
+		return f.messages[uid], nil
+	}
+	return nil, fmt.Errorf("uid [%d] not found", uid)
+}
+
+func (m *message) NewReader() (io.ReadCloser, error) {
+	return io.NopCloser(io.NewSectionReader(m.src, m.offset, m.size)), nil
+}
+
+func (m *message) ModelFlags() (models.Flags, error) {
+	return m.flags, nil
+}
+
+func (m *message) Labels() ([]string, error) {
+	return nil, nil
+}
+
+func (m *message) UID() uint32 {
+	return m.uid
+}
@@ -0,0 +1,109 @@
+package archive
+
+import (
+	"archive/tar"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"git.sr.ht/~rjarry/aerc/models"
+	"git.sr.ht/~rjarry/aerc/worker/lib"
+)
+
+const testMessage = "From: Alice <alice@example.com>\nSubject: hello\n\nHi\n"
+
+func writeTar(t *testing.T, w io.Writer, names ...string) {
+	t.Helper()
+	tw := tar.NewWriter(w)
+	for _, name := range names {
+		err := tw.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     0o600,
+			Size:     int64(len(testMessage)),
+			Typeflag: tar.TypeReg,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(tw, testMessage); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOpenArchive(t *testing.T) {
+	for _, ext := range []string{"", ".gz", ".zst", ".xz"} {
+		t.Run("tar"+ext, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "mail.tar"+ext)
+			w, err := lib.CreateFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			writeTar(t, w,
+				"mail/INBOX/cur/1:2,S",
+				"mail/INBOX/new/2",
+				"mail/INBOX/tmp/3",
+				"mail/Lists/aerc/cur/4:2,FR",
+				"mail/README",
+			)
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			a, err := openArchive(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer a.Close()
+			names := a.Names()
+			if !reflect.DeepEqual(names, []string{"INBOX", "Lists/aerc"}) {
+				t.Fatalf("unexpected folders: %v", names)
+			}
+			inbox, _ := a.Folder("INBOX")
+			if len(inbox.messages) != 2 {
+				t.Fatalf("expected 2 messages, got %d", len(inbox.messages))
+			}
+			if inbox.messages[0].flags != models.SeenFlag {
+				t.Errorf("unexpected flags %v", inbox.messages[0].flags)
+			}
+			if inbox.messages[1].flags != models.RecentFlag {
+				t.Errorf("unexpected flags %v", inbox.messages[1].flags)
+			}
+			lists, _ := a.Folder("Lists/aerc")
+			if lists.messages[0].flags != models.FlaggedFlag|models.AnsweredFlag {
+				t.Errorf("unexpected flags %v", lists.messages[0].flags)
+			}
+			r, _ := lists.messages[0].NewReader()
+			content, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != testMessage {
+				t.Errorf("unexpected content %q", content)
+			}
+		})
+	}
+}
+
+func TestRootMaildir(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mail.tar")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTar(t, f, "./cur/1:2,S", "./.Sent/cur/2:2,S")
+	f.Close()
+	a, err := openArchive(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	if names := a.Names(); !reflect.DeepEqual(names, []string{".Sent", "INBOX"}) {
+		t.Errorf("unexpected folders: %v", names)
+	}
+}
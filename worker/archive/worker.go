@@ -0,0 +1,306 @@
+package archive
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"git.sr.ht/~rjarry/aerc/log"
+	"git.sr.ht/~rjarry/aerc/models"
+	"git.sr.ht/~rjarry/aerc/worker/handlers"
+	"git.sr.ht/~rjarry/aerc/worker/lib"
+	"git.sr.ht/~rjarry/aerc/worker/types"
+)
+
+func init() {
+	handlers.RegisterWorkerFactory("tar", NewWorker)
+}
+
+var errUnsupported = fmt.Errorf("unsupported command")
+
+// archiveWorker browses a tarball of a maildir tree without extracting it.
+// The archive is read-only, flag changes are only kept in memory.
+type archiveWorker struct {
+	data   *archive
+	name   string
+	folder *folder
+	worker *types.Worker
+}
+
+func NewWorker(worker *types.Worker) (types.Backend, error) {
+	return &archiveWorker{
+		worker: worker,
+	}, nil
+}
+
+func (w *archiveWorker) handleMessage(msg types.WorkerMessage) error {
+	var reterr error
+
+	switch msg := msg.(type) {
+
+	case *types.Unsupported:
+		// No-op
+
+	case *types.Configure:
+		u, err := url.Parse(msg.Config.Source)
+		if err != nil {
+			reterr = err
+			break
+		}
+		var file string
+		if u.Host == "~" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				reterr = err
+				break
+			}
+			file = filepath.Join(home, u.Path)
+		} else {
+			file = filepath.Join(u.Host, u.Path)
+		}
+		if w.data != nil {
+			w.data.Close()
+		}
+		w.data, err = openArchive(file)
+		if err != nil {
+			w.data = &archive{folders: make(map[string]*folder)}
+			reterr = err
+			break
+		}
+		log.Debugf("configured with archive %s", file)
+
+	case *types.Connect, *types.Reconnect, *types.Disconnect:
+		w.worker.PostMessage(&types.Done{Message: types.RespondTo(msg)}, nil)
+
+	case *types.ListDirectories:
+		for _, name := range w.data.Names() {
+			w.worker.PostMessage(&types.Directory{
+				Message: types.RespondTo(msg),
+				Dir: &models.Directory{
+					Name:       name,
+					Attributes: nil,
+				},
+			}, nil)
+			w.worker.PostMessage(&types.DirectoryInfo{
+				Info: w.data.DirectoryInfo(name),
+			}, nil)
+		}
+		w.worker.PostMessage(&types.Done{Message: types.RespondTo(msg)}, nil)
+
+	case *types.OpenDirectory:
+		f, ok := w.data.Folder(msg.Directory)
+		if !ok {
+			reterr = fmt.Errorf("%s: no such folder in archive", msg.Directory)
+			break
+		}
+		w.name = msg.Directory
+		w.folder = f
+		w.worker.PostMessage(&types.DirectoryInfo{
+			Info: w.data.DirectoryInfo(msg.Directory),
+		}, nil)
+		w.worker.PostMessage(&types.Done{Message: types.RespondTo(msg)}, nil)
+
+	case *types.FetchDirectoryContents:
+		if w.folder == nil {
+			reterr = fmt.Errorf("no folder opened")
+			break
+		}
+		uids, err := filterUids(w.folder, w.folder.Uids(), msg.FilterCriteria)
+		if err != nil {
+			reterr = err
+			break
+		}
+		uids, err = sortUids(w.folder, uids, msg.SortCriteria)
+		if err != nil {
+			reterr = err
+			break
+		}
+		w.worker.PostMessage(&types.DirectoryContents{
+			Message: types.RespondTo(msg),
+			Uids:    uids,
+		}, nil)
+		w.worker.PostMessage(&types.Done{Message: types.RespondTo(msg)}, nil)
+
+	case *types.FetchMessageHeaders:
+		for _, uid := range msg.Uids {
+			m, err := w.folder.Message(uid)
+			if err != nil {
+				w.worker.PostMessageInfoError(msg, uid, err)
+				continue
+			}
+			info, err := lib.MessageInfo(m)
+			if err != nil {
+				w.worker.PostMessageInfoError(msg, uid, err)
+				continue
+			}
+			info.Size = uint32(m.size)
+			w.worker.PostMessage(&types.MessageInfo{
+				Message: types.RespondTo(msg),
+				Info:    info,
+			}, nil)
+		}
+		w.worker.PostMessage(&types.Done{Message: types.RespondTo(msg)}, nil)
+
+	case *types.FetchMessageBodyPart:
+		m, err := w.folder.Message(msg.Uid)
+		if err != nil {
+			reterr = err
+			break
+		}
+		r, err := m.NewReader()
+		if err != nil {
+			reterr = fmt.Errorf("could not get message reader: %w", err)
+			break
+		}
+		fullMsg, err := lib.ReadMessage(r)
+		if err != nil {
+			reterr = fmt.Errorf("could not read message: %w", err)
+			break
+		}
+		part, err := lib.FetchEntityPartReader(fullMsg, msg.Part)
+		if err != nil {
+			reterr = err
+			break
+		}
+		w.worker.PostMessage(&types.MessageBodyPart{
+			Message: types.RespondTo(msg),
+			Part: &models.MessageBodyPart{
+				Reader: part,
+				Uid:    msg.Uid,
+			},
+		}, nil)
+
+	case *types.FetchFullMessages:
+		for _, uid := range msg.Uids {
+			m, err := w.folder.Message(uid)
+			if err != nil {
+				log.Errorf("could not get message for uid %d: %v", uid, err)
+				continue
+			}
+			r, _ := m.NewReader()
+			b, err := io.ReadAll(r)
+			if err != nil {
+				log.Errorf("could not read message %d: %v", uid, err)
+				continue
+			}
+			w.worker.PostMessage(&types.FullMessage{
+				Message: types.RespondTo(msg),
+				Content: &models.FullMessage{
+					Uid:    uid,
+					Reader: bytes.NewReader(b),
+				},
+			}, nil)
+		}
+		w.worker.PostMessage(&types.Done{Message: types.RespondTo(msg)}, nil)
+
+	case *types.FlagMessages:
+		// allow marking messages as read while browsing, the changes
+		// are not written to the archive
+		for _, uid := range msg.Uids {
+			m, err := w.folder.Message(uid)
+			if err != nil {
+				log.Errorf("could not get message: %v", err)
+				continue
+			}
+			if msg.Enable {
+				m.flags |= msg.Flags
+			} else {
+				m.flags &^= msg.Flags
+			}
+			info, err := lib.MessageInfo(m)
+			if err != nil {
+				log.Errorf("could not get message info: %v", err)
+				continue
+			}
+			info.Size = uint32(m.size)
+			w.worker.PostMessage(&types.MessageInfo{
+				Message: types.RespondTo(msg),
+				Info:    info,
+			}, nil)
+		}
+		w.worker.PostMessage(&types.DirectoryInfo{
+			Info: w.data.DirectoryInfo(w.name),
+		}, nil)
+		w.worker.PostMessage(&types.Done{Message: types.RespondTo(msg)}, nil)
+
+	case *types.SearchDirectory:
+		uids, err := filterUids(w.folder, w.folder.Uids(), msg.Argv)
+		if err != nil {
+			reterr = err
+			break
+		}
+		w.worker.PostMessage(&types.SearchResults{
+			Message: types.RespondTo(msg),
+			Uids:    uids,
+		}, nil)
+
+	case *types.CheckMail:
+		w.worker.PostMessage(&types.Done{Message: types.RespondTo(msg)}, nil)
+
+	case *types.CreateDirectory, *types.RemoveDirectory,
+		*types.DeleteMessages, *types.CopyMessages, *types.MoveMessages,
+		*types.AppendMessage, *types.AnsweredMessages:
+		reterr = errReadOnly
+
+	default:
+		reterr = errUnsupported
+	}
+
+	return reterr
+}
+
+func (w *archiveWorker) Run() {
+	for msg := range w.worker.Actions {
+		msg = w.worker.ProcessAction(msg)
+		if err := w.handleMessage(msg); errors.Is(err, errUnsupported) {
+			w.worker.PostMessage(&types.Unsupported{
+				Message: types.RespondTo(msg),
+			}, nil)
+		} else if err != nil {
+			w.worker.PostMessage(&types.Error{
+				Message: types.RespondTo(msg),
+				Error:   err,
+			}, nil)
+		}
+	}
+}
+
+func filterUids(f *folder, uids []uint32, args []string) ([]uint32, error) {
+	criteria, err := lib.GetSearchCriteria(args)
+	if err != nil {
+		return nil, err
+	}
+	messages := make([]lib.RawMessage, 0, len(uids))
+	for _, uid := range uids {
+		m, err := f.Message(uid)
+		if err != nil {
+			continue
+		}
+		messages = append(messages, m)
+	}
+	return lib.Search(messages, criteria)
+}
+
+func sortUids(f *folder, uids []uint32,
+	criteria []*types.SortCriterion,
+) ([]uint32, error) {
+	var infos []*models.MessageInfo
+	for _, uid := range uids {
+		m, err := f.Message(uid)
+		if err != nil {
+			continue
+		}
+		info, err := lib.MessageHeaders(m)
+		if err != nil {
+			log.Errorf("could not get message info %v", err)
+			continue
+		}
+		info.Size = uint32(m.size)
+		infos = append(infos, info)
+	}
+	return lib.Sort(infos, criteria)
+}
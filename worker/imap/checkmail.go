@@ -8,6 +8,10 @@ import (
 )
 
 func (w *IMAPWorker) handleCheckMailMessage(msg *types.CheckMail) {
+	w.checkMail(w.client, msg)
+}
+
+func (w *IMAPWorker) checkMail(c *imapClient, msg *types.CheckMail) {
 	items := []imap.StatusItem{
 		imap.StatusMessages,
 		imap.StatusRecent,
@@ -21,7 +25,7 @@ func (w *IMAPWorker) handleCheckMailMessage(msg *types.CheckMail) {
 	switch {
 	case w.liststatus:
 		log.Tracef("Checking mail with LIST-STATUS")
-		statuses, err = w.client.liststatus.ListStatus("", "*", items, nil)
+		statuses, err = c.liststatus.ListStatus("", "*", items, nil)
 		if err != nil {
 			w.worker.PostMessage(&types.Error{
 				Message: types.RespondTo(msg),
@@ -31,12 +35,13 @@ func (w *IMAPWorker) handleCheckMailMessage(msg *types.CheckMail) {
 		}
 	default:
 		for _, dir := range msg.Directories {
-			if len(w.worker.Actions) > 0 {
+			if c == w.client && len(w.worker.Actions) > 0 {
+				// let the pending actions run first
 				remaining = append(remaining, dir)
 				continue
 			}
 			log.Tracef("Getting status of directory %s", dir)
-			status, err := w.client.Status(dir, items)
+			status, err := c.Status(dir, items)
 			if err != nil {
 				w.worker.PostMessage(&types.Error{
 					Message: types.RespondTo(msg),
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"git.sr.ht/~rjarry/aerc/worker/types"
+	"github.com/emersion/go-imap/client"
 	"golang.org/x/oauth2"
 )
 
@@ -60,6 +61,7 @@ func (w *IMAPWorker) handleConfigure(msg *types.Configure) error {
 
 	w.config.user = u.User
 	w.config.folders = msg.Config.Folders
+	w.config.archive = msg.Config.Archive
 
 	w.config.idle_timeout = 10 * time.Second
 	w.config.idle_debounce = 10 * time.Millisecond
@@ -71,6 +73,7 @@ func (w *IMAPWorker) handleConfigure(msg *types.Configure) error {
 
 	w.config.reconnect_maxwait = 30 * time.Second
 
+	w.config.poolSize = 0
 	w.config.cacheEnabled = false
 	w.config.cacheMaxAge = 30 * 24 * time.Hour // 30 days
 
@@ -141,6 +144,29 @@ func (w *IMAPWorker) handleConfigure(msg *types.Configure) error {
 				return fmt.Errorf("invalid cache-headers value %v: %w", value, err)
 			}
 			w.config.cacheEnabled = cache
+		case "watch-folders":
+			w.config.watchFolders = nil
+			for _, folder := range strings.Split(value, ",") {
+				if folder = strings.TrimSpace(folder); folder != "" {
+					w.config.watchFolders = append(w.config.watchFolders, folder)
+				}
+			}
+		case "connection-pool-size":
+			val, err := strconv.Atoi(value)
+			if err != nil || val < 0 {
+				return fmt.Errorf(
+					"invalid connection-pool-size value %v: %w",
+					value, err)
+			}
+			w.config.poolSize = val
+		case "gmail-search":
+			val, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf(
+					"invalid gmail-search value %v: %w",
+					value, err)
+			}
+			w.config.gmailSearch = val
 		case "cache-max-age":
 			val, err := time.ParseDuration(value)
 			if err != nil || val < 0 {
@@ -152,6 +178,19 @@ func (w *IMAPWorker) handleConfigure(msg *types.Configure) error {
 	if w.config.cacheEnabled {
 		w.initCacheDb(msg.Config.Name)
 	}
+	if w.pool != nil {
+		w.pool.Close()
+		w.pool = nil
+	}
+	if w.config.poolSize > 0 {
+		w.pool = newConnPool(func() (*client.Client, error) {
+			c, err := w.connect()
+			if err == nil {
+				c.Timeout = w.config.connection_timeout
+			}
+			return c, err
+		}, w.config.poolSize)
+	}
 	w.idler = newIdler(w.config, w.worker)
 	w.observer = newObserver(w.config, w.worker)
 
@@ -5,6 +5,11 @@ import (
 )
 
 func (imapw *IMAPWorker) handleCreateDirectory(msg *types.CreateDirectory) {
+	if imapw.gmail && imapw.isGmailArchive(msg.Directory) {
+		// archived messages go to All Mail, do not create a label
+		imapw.worker.PostMessage(&types.Done{Message: types.RespondTo(msg)}, nil)
+		return
+	}
 	if err := imapw.client.Create(msg.Directory); err != nil {
 		if msg.Quiet {
 			return
@@ -0,0 +1,160 @@
+package extensions
+
+import (
+	"time"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+	"github.com/emersion/go-imap/commands"
+	"github.com/emersion/go-imap/responses"
+	"github.com/emersion/go-imap/utf7"
+)
+
+// A NOTIFY client (RFC 5465)
+type NotifyClient struct {
+	c *client.Client
+}
+
+func NewNotifyClient(c *client.Client) *NotifyClient {
+	return &NotifyClient{c}
+}
+
+// SupportNotify checks if the server supports the NOTIFY extension.
+func (c *NotifyClient) SupportNotify() (bool, error) {
+	return c.c.Support("NOTIFY")
+}
+
+// Notify asks the server to report new and expunged messages as well as flag
+// changes for the selected mailbox and for the given mailboxes. The current
+// status of the monitored mailboxes is returned.
+func (c *NotifyClient) Notify(mailboxes []string) ([]*imap.MailboxStatus, error) {
+	if c.c.State() != imap.AuthenticatedState && c.c.State() != imap.SelectedState {
+		return nil, client.ErrNotLoggedIn
+	}
+	cmd := &NotifyCommand{Mailboxes: mailboxes}
+	res := &NotifyResponse{}
+	status, err := c.c.Execute(cmd, res)
+	if err != nil {
+		return nil, err
+	}
+	return res.Statuses, status.Err()
+}
+
+// Idle is similar to client.Client.Idle but the STATUS responses sent by the
+// server for the monitored mailboxes are delivered to ch. The IDLE command is
+// restarted every 25 minutes to avoid being logged out by the server.
+func (c *NotifyClient) Idle(
+	stop <-chan struct{}, ch chan<- *imap.MailboxStatus,
+) error {
+	t := time.NewTicker(25 * time.Minute)
+	defer t.Stop()
+
+	for {
+		stopOrRestart := make(chan struct{})
+		done := make(chan error, 1)
+		go func() {
+			done <- c.idle(stopOrRestart, ch)
+		}()
+
+		select {
+		case <-t.C:
+			close(stopOrRestart)
+			if err := <-done; err != nil {
+				return err
+			}
+		case <-stop:
+			close(stopOrRestart)
+			return <-done
+		case err := <-done:
+			close(stopOrRestart)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (c *NotifyClient) idle(
+	stop <-chan struct{}, ch chan<- *imap.MailboxStatus,
+) error {
+	res := &NotifyIdleResponse{
+		Idle: responses.Idle{
+			Stop:      stop,
+			RepliesCh: make(chan []byte, 10),
+		},
+		Statuses: ch,
+	}
+	status, err := c.c.Execute(&commands.Idle{}, res)
+	if err != nil {
+		return err
+	}
+	return status.Err()
+}
+
+var notifyEvents = []interface{}{
+	imap.RawString("MessageNew"),
+	imap.RawString("MessageExpunge"),
+	imap.RawString("FlagChange"),
+}
+
+// NotifyCommand is a NOTIFY SET command, as defined in RFC 5465 section 3.
+// The STATUS indicator is always set so that the server reports the current
+// status of the monitored mailboxes.
+type NotifyCommand struct {
+	Mailboxes []string
+}
+
+func (cmd *NotifyCommand) Command() *imap.Command {
+	args := []interface{}{
+		imap.RawString("SET"),
+		imap.RawString("STATUS"),
+		[]interface{}{imap.RawString("SELECTED"), notifyEvents},
+	}
+	if len(cmd.Mailboxes) > 0 {
+		enc := utf7.Encoding.NewEncoder()
+		names := make([]interface{}, 0, len(cmd.Mailboxes))
+		for _, name := range cmd.Mailboxes {
+			name, _ = enc.String(name)
+			names = append(names, imap.FormatMailboxName(name))
+		}
+		args = append(args, []interface{}{
+			imap.RawString("MAILBOXES"), names, notifyEvents,
+		})
+	}
+	return &imap.Command{Name: "NOTIFY", Arguments: args}
+}
+
+// A NOTIFY response
+type NotifyResponse struct {
+	Statuses []*imap.MailboxStatus
+}
+
+func (r *NotifyResponse) Handle(resp imap.Resp) error {
+	res := responses.Status{Mailbox: new(imap.MailboxStatus)}
+	if err := res.Handle(resp); err != nil {
+		return err
+	}
+	r.Statuses = append(r.Statuses, res.Mailbox)
+	return nil
+}
+
+// An IDLE response which also handles the STATUS responses sent by the
+// server because of a previous NOTIFY command.
+type NotifyIdleResponse struct {
+	responses.Idle
+	Statuses chan<- *imap.MailboxStatus
+}
+
+func (r *NotifyIdleResponse) Handle(resp imap.Resp) error {
+	res := responses.Status{Mailbox: new(imap.MailboxStatus)}
+	if err := res.Handle(resp); err == nil {
+		select {
+		case r.Statuses <- res.Mailbox:
+		default:
+			// do not block the client, the next check-mail will
+			// catch up
+		}
+		return nil
+	}
+	return r.Idle.Handle(resp)
+}
@@ -0,0 +1,51 @@
+package extensions
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/emersion/go-imap"
+)
+
+func TestNotifyCommand(t *testing.T) {
+	tests := []struct {
+		mailboxes []string
+		expected  string
+	}{
+		{
+			mailboxes: nil,
+			expected: "NOTIFY SET STATUS " +
+				"(SELECTED (MessageNew MessageExpunge FlagChange))",
+		},
+		{
+			mailboxes: []string{"INBOX", "Lists/aerc"},
+			expected: "NOTIFY SET STATUS " +
+				"(SELECTED (MessageNew MessageExpunge FlagChange)) " +
+				`(MAILBOXES (INBOX "Lists/aerc") ` +
+				"(MessageNew MessageExpunge FlagChange))",
+		},
+		{
+			mailboxes: []string{"Entwürfe", "My Folder"},
+			expected: "NOTIFY SET STATUS " +
+				"(SELECTED (MessageNew MessageExpunge FlagChange)) " +
+				`(MAILBOXES ("Entw&APw-rfe" "My Folder") ` +
+				"(MessageNew MessageExpunge FlagChange))",
+		},
+	}
+
+	for _, test := range tests {
+		cmd := &NotifyCommand{Mailboxes: test.mailboxes}
+		var buf bytes.Buffer
+		w := imap.NewWriter(&buf)
+		if err := cmd.Command().WriteTo(w); err != nil {
+			t.Fatal(err)
+		}
+		// strip the command tag and the final CRLF
+		line := buf.String()
+		line = line[bytes.IndexByte(buf.Bytes(), ' ')+1 : len(line)-2]
+		if line != test.expected {
+			t.Errorf("%v:\nexpected %q\ngot      %q",
+				test.mailboxes, test.expected, line)
+		}
+	}
+}
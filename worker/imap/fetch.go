@@ -28,47 +28,15 @@ func (imapw *IMAPWorker) handleFetchMessageHeaders(
 		return
 	}
 	log.Tracef("Fetching message headers: %v", toFetch)
-	section := &imap.BodySectionName{
-		BodyPartName: imap.BodyPartName{
-			Specifier: imap.HeaderSpecifier,
-		},
-		Peek: true,
-	}
-
-	items := []imap.FetchItem{
-		imap.FetchBodyStructure,
-		imap.FetchEnvelope,
-		imap.FetchInternalDate,
-		imap.FetchFlags,
-		imap.FetchUid,
-		section.FetchItem(),
-	}
-	imapw.handleFetchMessages(msg, toFetch, items,
+	imapw.handleFetchMessages(msg, toFetch, imapw.headerItems(),
 		func(_msg *imap.Message) error {
 			if len(_msg.Body) == 0 {
 				// ignore duplicate messages with only flag updates
 				return nil
 			}
-			reader := _msg.GetBody(section)
-			if reader == nil {
-				return fmt.Errorf("failed to find part: %v", section)
-			}
-			textprotoHeader, err := textproto.ReadHeader(bufio.NewReader(reader))
-			if err != nil {
-				return fmt.Errorf("failed to read part header: %w", err)
-			}
-			header := &mail.Header{Header: message.Header{Header: textprotoHeader}}
-			info := &models.MessageInfo{
-				BodyStructure: translateBodyStructure(_msg.BodyStructure),
-				Envelope:      translateEnvelope(_msg.Envelope),
-				Flags:         translateImapFlags(_msg.Flags),
-				InternalDate:  _msg.InternalDate,
-				RFC822Headers: header,
-				Uid:           _msg.Uid,
-			}
-			refs, err := header.MsgIDList("references")
+			info, err := imapw.headerInfo(_msg)
 			if err != nil {
-				info.Refs = refs
+				return err
 			}
 			imapw.worker.PostMessage(&types.MessageInfo{
 				Message: types.RespondTo(msg),
@@ -81,8 +49,61 @@ func (imapw *IMAPWorker) handleFetchMessageHeaders(
 		})
 }
 
+var headerSection = &imap.BodySectionName{
+	BodyPartName: imap.BodyPartName{
+		Specifier: imap.HeaderSpecifier,
+	},
+	Peek: true,
+}
+
+// headerItems returns the fetch items needed by headerInfo.
+func (imapw *IMAPWorker) headerItems() []imap.FetchItem {
+	items := []imap.FetchItem{
+		imap.FetchBodyStructure,
+		imap.FetchEnvelope,
+		imap.FetchInternalDate,
+		imap.FetchFlags,
+		imap.FetchUid,
+		headerSection.FetchItem(),
+	}
+	return append(items, imapw.labelItems()...)
+}
+
+// headerInfo translates a message fetched with the headerItems.
+func (imapw *IMAPWorker) headerInfo(_msg *imap.Message) (*models.MessageInfo, error) {
+	reader := _msg.GetBody(headerSection)
+	if reader == nil {
+		return nil, fmt.Errorf("failed to find part: %v", headerSection)
+	}
+	textprotoHeader, err := textproto.ReadHeader(bufio.NewReader(reader))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read part header: %w", err)
+	}
+	header := &mail.Header{Header: message.Header{Header: textprotoHeader}}
+	info := &models.MessageInfo{
+		BodyStructure: translateBodyStructure(_msg.BodyStructure),
+		Envelope:      translateEnvelope(_msg.Envelope),
+		Flags:         translateImapFlags(_msg.Flags),
+		Labels:        imapw.messageLabels(_msg),
+		InternalDate:  _msg.InternalDate,
+		RFC822Headers: header,
+		Uid:           _msg.Uid,
+	}
+	refs, err := header.MsgIDList("references")
+	if err != nil {
+		info.Refs = refs
+	}
+	return info, nil
+}
+
 func (imapw *IMAPWorker) handleFetchMessageBodyPart(
 	msg *types.FetchMessageBodyPart,
+) {
+	imapw.fetchBodyPart(imapw.client, msg)
+}
+
+func (imapw *IMAPWorker) fetchBodyPart(
+	c *imapClient, msg *types.FetchMessageBodyPart,
 ) {
 	log.Tracef("Fetching message %d part: %v", msg.Uid, msg.Part)
 
@@ -112,7 +133,7 @@ func (imapw *IMAPWorker) handleFetchMessageBodyPart(
 		partHeaderSection.FetchItem(),
 		partBodySection.FetchItem(),
 	}
-	imapw.handleFetchMessages(msg, []uint32{msg.Uid}, items,
+	imapw.fetchMessages(c, msg, []uint32{msg.Uid}, items,
 		func(_msg *imap.Message) error {
 			if len(_msg.Body) == 0 {
 				// ignore duplicate messages with only flag updates
@@ -147,8 +168,9 @@ func (imapw *IMAPWorker) handleFetchMessageBodyPart(
 			imapw.worker.PostMessage(&types.MessageInfo{
 				Message: types.RespondTo(msg),
 				Info: &models.MessageInfo{
-					Flags: translateImapFlags(_msg.Flags),
-					Uid:   _msg.Uid,
+					Flags:  translateImapFlags(_msg.Flags),
+					Labels: imapw.messageLabels(_msg),
+					Uid:    _msg.Uid,
 				},
 			}, nil)
 			return nil
@@ -157,6 +179,12 @@ func (imapw *IMAPWorker) handleFetchMessageBodyPart(
 
 func (imapw *IMAPWorker) handleFetchFullMessages(
 	msg *types.FetchFullMessages,
+) {
+	imapw.fetchFullMessages(imapw.client, msg)
+}
+
+func (imapw *IMAPWorker) fetchFullMessages(
+	c *imapClient, msg *types.FetchFullMessages,
 ) {
 	log.Tracef("Fetching full messages: %v", msg.Uids)
 	section := &imap.BodySectionName{
@@ -168,7 +196,7 @@ func (imapw *IMAPWorker) handleFetchFullMessages(
 		imap.FetchUid,
 		section.FetchItem(),
 	}
-	imapw.handleFetchMessages(msg, msg.Uids, items,
+	imapw.fetchMessages(c, msg, msg.Uids, items,
 		func(_msg *imap.Message) error {
 			if len(_msg.Body) == 0 {
 				// ignore duplicate messages with only flag updates
@@ -189,8 +217,9 @@ func (imapw *IMAPWorker) handleFetchFullMessages(
 			imapw.worker.PostMessage(&types.MessageInfo{
 				Message: types.RespondTo(msg),
 				Info: &models.MessageInfo{
-					Flags: translateImapFlags(_msg.Flags),
-					Uid:   _msg.Uid,
+					Flags:  translateImapFlags(_msg.Flags),
+					Labels: imapw.messageLabels(_msg),
+					Uid:    _msg.Uid,
 				},
 			}, nil)
 			return nil
@@ -198,17 +227,24 @@ func (imapw *IMAPWorker) handleFetchFullMessages(
 }
 
 func (imapw *IMAPWorker) handleFetchMessageFlags(msg *types.FetchMessageFlags) {
+	imapw.fetchFlags(msg, msg.Uids)
+}
+
+// fetchFlags sends the flags and labels of the given messages.
+func (imapw *IMAPWorker) fetchFlags(msg types.WorkerMessage, uids []uint32) {
 	items := []imap.FetchItem{
 		imap.FetchFlags,
 		imap.FetchUid,
 	}
-	imapw.handleFetchMessages(msg, msg.Uids, items,
+	items = append(items, imapw.labelItems()...)
+	imapw.handleFetchMessages(msg, uids, items,
 		func(_msg *imap.Message) error {
 			imapw.worker.PostMessage(&types.MessageInfo{
 				Message: types.RespondTo(msg),
 				Info: &models.MessageInfo{
-					Flags: translateImapFlags(_msg.Flags),
-					Uid:   _msg.Uid,
+					Flags:  translateImapFlags(_msg.Flags),
+					Labels: imapw.messageLabels(_msg),
+					Uid:    _msg.Uid,
 				},
 			}, nil)
 			return nil
@@ -218,6 +254,13 @@ func (imapw *IMAPWorker) handleFetchMessageFlags(msg *types.FetchMessageFlags) {
 func (imapw *IMAPWorker) handleFetchMessages(
 	msg types.WorkerMessage, uids []uint32, items []imap.FetchItem,
 	procFunc func(*imap.Message) error,
+) {
+	imapw.fetchMessages(imapw.client, msg, uids, items, procFunc)
+}
+
+func (imapw *IMAPWorker) fetchMessages(
+	c *imapClient, msg types.WorkerMessage, uids []uint32,
+	items []imap.FetchItem, procFunc func(*imap.Message) error,
 ) {
 	messages := make(chan *imap.Message)
 	done := make(chan error)
@@ -248,7 +291,7 @@ func (imapw *IMAPWorker) handleFetchMessages(
 	}
 
 	set := toSeqSet(uids)
-	if err := imapw.client.UidFetch(set, items, messages); err != nil {
+	if err := c.UidFetch(set, items, messages); err != nil {
 		emitErr(err)
 		return
 	}
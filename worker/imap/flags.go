@@ -9,9 +9,22 @@ import (
 )
 
 func (imapw *IMAPWorker) handleDeleteMessages(msg *types.DeleteMessages) {
+	uids := toSeqSet(msg.Uids)
+	if trash := imapw.gmailTrash(); trash != "" {
+		// Expunging a message from a Gmail label only removes the
+		// label. Move it to the trash instead.
+		if err := imapw.client.UidMove(uids, trash); err != nil {
+			imapw.worker.PostMessage(&types.Error{
+				Message: types.RespondTo(msg),
+				Error:   err,
+			}, nil)
+		} else {
+			imapw.worker.PostMessage(&types.Done{Message: types.RespondTo(msg)}, nil)
+		}
+		return
+	}
 	item := imap.FormatFlagsOp(imap.AddFlags, true)
 	flags := []interface{}{imap.DeletedFlag}
-	uids := toSeqSet(msg.Uids)
 	if err := imapw.client.UidStore(uids, item, flags, nil); err != nil {
 		imapw.worker.PostMessage(&types.Error{
 			Message: types.RespondTo(msg),
@@ -40,8 +53,9 @@ func (imapw *IMAPWorker) handleAnsweredMessages(msg *types.AnsweredMessages) {
 			imapw.worker.PostMessage(&types.MessageInfo{
 				Message: types.RespondTo(msg),
 				Info: &models.MessageInfo{
-					Flags: translateImapFlags(_msg.Flags),
-					Uid:   _msg.Uid,
+					Flags:  translateImapFlags(_msg.Flags),
+					Labels: imapw.messageLabels(_msg),
+					Uid:    _msg.Uid,
 				},
 			}, nil)
 			return nil
@@ -59,14 +73,46 @@ func (imapw *IMAPWorker) handleFlagMessages(msg *types.FlagMessages) {
 			imapw.worker.PostMessage(&types.MessageInfo{
 				Message: types.RespondTo(msg),
 				Info: &models.MessageInfo{
-					Flags: translateImapFlags(_msg.Flags),
-					Uid:   _msg.Uid,
+					Flags:  translateImapFlags(_msg.Flags),
+					Labels: imapw.messageLabels(_msg),
+					Uid:    _msg.Uid,
 				},
 			}, nil)
 			return nil
 		})
 }
 
+func (imapw *IMAPWorker) handleModifyLabels(msg *types.ModifyLabels) {
+	// keywords are stored as flags, Gmail labels in their own attribute
+	attr := "FLAGS"
+	if imapw.gmail {
+		attr = string(gmailLabelsItem)
+	}
+	set := toSeqSet(msg.Uids)
+	for _, op := range []struct {
+		prefix string
+		labels []string
+	}{
+		{"+", msg.Add},
+		{"-", msg.Remove},
+	} {
+		if len(op.labels) == 0 {
+			continue
+		}
+		item := imap.StoreItem(op.prefix + attr + ".SILENT")
+		err := imapw.client.UidStore(set, item, formatLabels(op.labels), nil)
+		if err != nil {
+			imapw.worker.PostMessage(&types.Error{
+				Message: types.RespondTo(msg),
+				Error:   err,
+			}, nil)
+			return
+		}
+	}
+	// the responses of silent stores do not contain the new values
+	imapw.fetchFlags(msg, msg.Uids)
+}
+
 func (imapw *IMAPWorker) handleStoreOps(
 	msg types.WorkerMessage, uids []uint32, item imap.StoreItem, flag interface{},
 	procFunc func(*imap.Message) error,
@@ -0,0 +1,192 @@
+package imap
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/commands"
+	"github.com/emersion/go-imap/responses"
+
+	"git.sr.ht/~rjarry/aerc/log"
+	"git.sr.ht/~rjarry/aerc/worker/types"
+)
+
+// With Gmail, folders are views of labels. Every message is in All Mail and
+// removing a message from a folder only removes the corresponding label. The
+// helpers below translate aerc operations to their Gmail equivalent.
+
+const gmailThreadItem imap.FetchItem = "X-GM-THRID"
+
+// gmailFolder returns the name of the Gmail folder with the given special-use
+// attribute (e.g. \All or \Trash), as found by the last LIST command.
+func (imapw *IMAPWorker) gmailFolder(attr string) string {
+	return imapw.gmailFolders[attr]
+}
+
+// isGmailArchive returns true if dest is All Mail or the configured archive
+// folder (and its sub folders when archiving with a year or month layout).
+func (imapw *IMAPWorker) isGmailArchive(dest string) bool {
+	if dest == imapw.gmailFolder(imap.AllAttr) {
+		return true
+	}
+	archive := imapw.config.archive
+	return archive != "" &&
+		(dest == archive || strings.HasPrefix(dest, archive+"/"))
+}
+
+// gmailSearchCmd is a SEARCH command which accepts a raw Gmail query through
+// the X-GM-RAW search key, in addition to the standard criteria.
+type gmailSearchCmd struct {
+	commands.Search
+	raw string
+}
+
+func (cmd *gmailSearchCmd) Command() *imap.Command {
+	c := cmd.Search.Command()
+	c.Arguments = append(c.Arguments, imap.RawString("X-GM-RAW"), cmd.raw)
+	return c
+}
+
+// uidSearch runs a UID SEARCH. If raw is not empty, it is passed as a Gmail
+// query and combined with the criteria.
+func (imapw *IMAPWorker) uidSearch(
+	criteria *imap.SearchCriteria, raw string,
+) ([]uint32, error) {
+	if raw == "" {
+		return imapw.client.UidSearch(criteria)
+	}
+	search := &gmailSearchCmd{
+		Search: commands.Search{Criteria: criteria},
+		raw:    raw,
+	}
+	for _, r := range raw {
+		if r > 0x7f {
+			search.Charset = "UTF-8"
+			break
+		}
+	}
+	res := new(responses.Search)
+	status, err := imapw.client.Execute(&commands.Uid{Cmd: search}, res)
+	if err != nil {
+		return nil, err
+	}
+	if err := status.Err(); err != nil {
+		return nil, err
+	}
+	return res.Ids, nil
+}
+
+// handleGmailThreaded builds the threads of the selected folder from the
+// Gmail conversation IDs.
+func (imapw *IMAPWorker) handleGmailThreaded(
+	msg *types.FetchDirectoryThreaded,
+) {
+	emitError := func(err error) {
+		imapw.worker.PostMessage(&types.Error{
+			Message: types.RespondTo(msg),
+			Error:   err,
+		}, nil)
+	}
+
+	criteria, raw, err := parseSearch(msg.FilterCriteria, true)
+	if err != nil {
+		emitError(err)
+		return
+	}
+	uids, err := imapw.uidSearch(criteria, raw)
+	if err != nil {
+		emitError(err)
+		return
+	}
+
+	thrids := make(map[uint32]uint64, len(uids))
+	if len(uids) > 0 {
+		messages := make(chan *imap.Message)
+		done := make(chan struct{})
+		go func() {
+			defer log.PanicHandler()
+			for m := range messages {
+				id, err := strconv.ParseUint(
+					fmt.Sprint(m.Items[gmailThreadItem]), 10, 64)
+				if err != nil {
+					log.Warnf("invalid %s for uid %d: %v",
+						gmailThreadItem, m.Uid, err)
+					continue
+				}
+				thrids[m.Uid] = id
+			}
+			close(done)
+		}()
+		items := []imap.FetchItem{imap.FetchUid, gmailThreadItem}
+		err = imapw.client.UidFetch(toSeqSet(uids), items, messages)
+		<-done
+		if err != nil {
+			emitError(err)
+			return
+		}
+	}
+
+	threads := gmailThreads(uids, thrids)
+	sort.Sort(types.ByUID(threads))
+	log.Tracef("Found %d threaded messages", len(uids))
+	if len(msg.FilterCriteria) == 1 {
+		// Only initialize if we are not filtering
+		sorted := make([]uint32, 0, len(uids))
+		for i := len(threads) - 1; i >= 0; i-- {
+			threads[i].Walk(func(t *types.Thread, level int, currentErr error) error { //nolint:errcheck // error indicates skipped threads
+				sorted = append(sorted, t.Uid)
+				return nil
+			})
+		}
+		imapw.seqMap.Initialize(sorted)
+	}
+	imapw.worker.PostMessage(&types.DirectoryThreaded{
+		Message: types.RespondTo(msg),
+		Threads: threads,
+	}, nil)
+	imapw.worker.PostMessage(&types.Done{Message: types.RespondTo(msg)}, nil)
+}
+
+// gmailThreads groups messages by conversation. The oldest message of a
+// conversation is the root of the thread, the other ones are its children.
+// Messages without a conversation ID are threads of their own.
+func gmailThreads(uids []uint32, thrids map[uint32]uint64) []*types.Thread {
+	sorted := append([]uint32(nil), uids...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var threads []*types.Thread
+	roots := make(map[uint64]*types.Thread)
+	for _, uid := range sorted {
+		node := &types.Thread{Uid: uid}
+		id, ok := thrids[uid]
+		if !ok {
+			threads = append(threads, node)
+			continue
+		}
+		if root, ok := roots[id]; ok {
+			root.AddChild(node)
+			continue
+		}
+		roots[id] = node
+		threads = append(threads, node)
+	}
+	return threads
+}
+
+// gmailTrash returns the folder where deleted messages must be moved to, or
+// an empty string if they can be expunged from the selected folder. Messages
+// in the trash and spam folders are deleted permanently.
+func (imapw *IMAPWorker) gmailTrash() string {
+	if !imapw.gmail {
+		return ""
+	}
+	trash := imapw.gmailFolder(imap.TrashAttr)
+	switch imapw.selected.Name {
+	case trash, imapw.gmailFolder(imap.JunkAttr):
+		return ""
+	}
+	return trash
+}
@@ -24,6 +24,7 @@ type idler struct {
 	sync.Mutex
 	config  imapConfig
 	client  *imapClient
+	notify  chan<- *imap.MailboxStatus
 	worker  *types.Worker
 	stop    chan struct{}
 	done    chan error
@@ -41,6 +42,14 @@ func (i *idler) SetClient(c *imapClient) {
 	i.Unlock()
 }
 
+// SetNotify makes the idler report the STATUS responses sent by the server
+// after a NOTIFY command to ch. Plain IDLE is used when ch is nil.
+func (i *idler) SetNotify(ch chan<- *imap.MailboxStatus) {
+	i.Lock()
+	i.notify = ch
+	i.Unlock()
+}
+
 func (i *idler) setWaiting(wait bool) {
 	i.Lock()
 	i.waiting = wait
@@ -89,11 +98,19 @@ func (i *idler) Start() {
 				i.setIdleing(true)
 				i.log("=>(idle)")
 				now := time.Now()
-				err := i.client.Idle(i.stop,
-					&client.IdleOptions{
-						LogoutTimeout: 0,
-						PollInterval:  0,
-					})
+				var err error
+				i.Lock()
+				notify := i.notify
+				i.Unlock()
+				if notify != nil {
+					err = i.client.notify.Idle(i.stop, notify)
+				} else {
+					err = i.client.Idle(i.stop,
+						&client.IdleOptions{
+							LogoutTimeout: 0,
+							PollInterval:  0,
+						})
+				}
 				i.setIdleing(false)
 				i.done <- err
 				i.log("elapsed idle time: %v", time.Since(now))
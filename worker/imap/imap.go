@@ -42,6 +42,7 @@ func translateBodyStructure(bs *imap.BodyStructure) *models.BodyStructure {
 		Parts:             parts,
 		Disposition:       bs.Disposition,
 		DispositionParams: bs.DispositionParams,
+		Size:              bs.Size,
 	}
 }
 
@@ -114,3 +115,50 @@ func translateFlags(flags models.Flags) []string {
 	}
 	return imapFlags
 }
+
+// translateImapKeywords returns the keywords (i.e. non system flags) found in
+// a list of IMAP flags.
+func translateImapKeywords(imapFlags []string) []string {
+	keywords := []string{}
+	for _, imapFlag := range imapFlags {
+		if imapFlag == "" || strings.HasPrefix(imapFlag, "\\") {
+			continue
+		}
+		keywords = append(keywords, imapFlag)
+	}
+	return keywords
+}
+
+const gmailLabelsItem imap.FetchItem = "X-GM-LABELS"
+
+// translateGmailLabels parses the value of an X-GM-LABELS fetch item.
+func translateGmailLabels(item interface{}) []string {
+	labels := []string{}
+	list, ok := item.([]interface{})
+	if !ok {
+		return labels
+	}
+	for _, l := range list {
+		if s, err := imap.ParseString(l); err == nil && s != "" {
+			labels = append(labels, s)
+		}
+	}
+	return labels
+}
+
+// formatLabels converts labels to STORE arguments. The go-imap client sends
+// all arguments verbatim, labels which are not valid atoms must be quoted.
+// Gmail system labels such as \Inbox must not be quoted.
+func formatLabels(labels []string) []interface{} {
+	args := make([]interface{}, 0, len(labels))
+	for _, l := range labels {
+		if strings.HasPrefix(l, "\\") || !strings.ContainsAny(l, " ()[]{}%*\"\\") {
+			args = append(args, imap.RawString(l))
+		} else {
+			l = strings.ReplaceAll(l, "\\", "\\\\")
+			l = strings.ReplaceAll(l, "\"", "\\\"")
+			args = append(args, imap.RawString("\""+l+"\""))
+		}
+	}
+	return args
+}
@@ -0,0 +1,88 @@
+package imap
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/emersion/go-imap"
+)
+
+func TestTranslateImapKeywords(t *testing.T) {
+	flags := []string{imap.SeenFlag, "$label1", "\\*", "work", imap.FlaggedFlag}
+	keywords := translateImapKeywords(flags)
+	if !reflect.DeepEqual(keywords, []string{"$label1", "work"}) {
+		t.Errorf("unexpected keywords %v", keywords)
+	}
+	if keywords := translateImapKeywords(nil); keywords == nil {
+		t.Errorf("keywords must not be nil")
+	}
+}
+
+func TestFormatLabels(t *testing.T) {
+	args := formatLabels([]string{"\\Inbox", "work", "my label", `a"b`})
+	expected := []interface{}{
+		imap.RawString(`\Inbox`),
+		imap.RawString(`work`),
+		imap.RawString(`"my label"`),
+		imap.RawString(`"a\"b"`),
+	}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("unexpected arguments %#v", args)
+	}
+}
+
+func TestTranslateGmailLabels(t *testing.T) {
+	item := []interface{}{"\\Inbox", "Work/Projects", imap.RawString("Later")}
+	labels := translateGmailLabels(item)
+	if !reflect.DeepEqual(labels, []string{"\\Inbox", "Work/Projects", "Later"}) {
+		t.Errorf("unexpected labels %v", labels)
+	}
+}
+
+func TestGmailThreads(t *testing.T) {
+	thrids := map[uint32]uint64{
+		1: 100,
+		2: 200,
+		3: 100,
+		5: 100,
+	}
+	threads := gmailThreads([]uint32{5, 4, 3, 2, 1}, thrids)
+	var roots []uint32
+	for _, th := range threads {
+		roots = append(roots, th.Uid)
+	}
+	if !reflect.DeepEqual(roots, []uint32{1, 2, 4}) {
+		t.Fatalf("unexpected thread roots %v", roots)
+	}
+	var children []uint32
+	for c := threads[0].FirstChild; c != nil; c = c.NextSibling {
+		if c.Parent != threads[0] {
+			t.Errorf("wrong parent for uid %d", c.Uid)
+		}
+		children = append(children, c.Uid)
+	}
+	if !reflect.DeepEqual(children, []uint32{3, 5}) {
+		t.Errorf("unexpected children %v", children)
+	}
+}
+
+func TestParseGmailSearch(t *testing.T) {
+	args := []string{"search", "-u", "from:alice", "has:attachment"}
+	criteria, raw, err := parseSearch(args, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw != "from:alice has:attachment" {
+		t.Errorf("unexpected raw query %q", raw)
+	}
+	if !reflect.DeepEqual(criteria.WithoutFlags, []string{imap.SeenFlag}) {
+		t.Errorf("unexpected flags %v", criteria.WithoutFlags)
+	}
+	_, raw, err = parseSearch(args, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw != "" {
+		t.Errorf("raw query must be empty without gmail: %q", raw)
+	}
+}
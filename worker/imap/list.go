@@ -1,6 +1,8 @@
 package imap
 
 import (
+	"sort"
+
 	"github.com/emersion/go-imap"
 
 	"git.sr.ht/~rjarry/aerc/log"
@@ -12,6 +14,9 @@ func (imapw *IMAPWorker) handleListDirectories(msg *types.ListDirectories) {
 	mailboxes := make(chan *imap.MailboxInfo)
 	log.Tracef("Listing mailboxes")
 	done := make(chan interface{})
+	// with Gmail, all folders except the system ones are labels
+	labels := []string{}
+	specials := make(map[string]string)
 
 	go func() {
 		defer log.PanicHandler()
@@ -21,6 +26,14 @@ func (imapw *IMAPWorker) handleListDirectories(msg *types.ListDirectories) {
 				// no need to pass this to handlers if it can't be opened
 				continue
 			}
+			if imapw.gmail {
+				if isGmailLabel(mbox) {
+					labels = append(labels, mbox.Name)
+				}
+				for _, attr := range mbox.Attributes {
+					specials[attr] = mbox.Name
+				}
+			}
 			imapw.worker.PostMessage(&types.Directory{
 				Message: types.RespondTo(msg),
 				Dir: &models.Directory{
@@ -82,10 +95,33 @@ func (imapw *IMAPWorker) handleListDirectories(msg *types.ListDirectories) {
 		}
 	}
 	<-done
+	if imapw.gmail {
+		imapw.gmailFolders = specials
+		sort.Strings(labels)
+		imapw.worker.PostMessage(&types.LabelList{
+			Labels: append([]string{"\\Inbox", "\\Important"}, labels...),
+		}, nil)
+	}
 	imapw.worker.PostMessage(
 		&types.Done{Message: types.RespondTo(msg)}, nil)
 }
 
+// isGmailLabel returns true if a Gmail folder is a user defined label.
+func isGmailLabel(mbox *imap.MailboxInfo) bool {
+	if mbox.Name == "INBOX" {
+		return false
+	}
+	for _, attr := range mbox.Attributes {
+		switch attr {
+		case imap.AllAttr, imap.ArchiveAttr, imap.DraftsAttr,
+			imap.FlaggedAttr, imap.JunkAttr, imap.SentAttr,
+			imap.TrashAttr, imap.ImportantAttr:
+			return false
+		}
+	}
+	return true
+}
+
 func canOpen(mbox *imap.MailboxInfo) bool {
 	for _, attr := range mbox.Attributes {
 		if attr == imap.NoSelectAttr {
@@ -104,13 +140,14 @@ func (imapw *IMAPWorker) handleSearchDirectory(msg *types.SearchDirectory) {
 	}
 
 	log.Tracef("Executing search")
-	criteria, err := parseSearch(msg.Argv)
+	criteria, raw, err := parseSearch(msg.Argv,
+		imapw.gmail && imapw.config.gmailSearch)
 	if err != nil {
 		emitError(err)
 		return
 	}
 
-	uids, err := imapw.client.UidSearch(criteria)
+	uids, err := imapw.uidSearch(criteria, raw)
 	if err != nil {
 		emitError(err)
 		return
@@ -1,8 +1,11 @@
 package imap
 
 import (
+	"fmt"
 	"io"
 
+	"github.com/emersion/go-imap"
+
 	"git.sr.ht/~rjarry/aerc/worker/types"
 )
 
@@ -48,8 +51,23 @@ func (imapw *IMAPWorker) handleAppendMessage(msg *types.AppendMessage) {
 }
 
 func (imapw *IMAPWorker) handleMoveMessages(msg *types.MoveMessages) {
+	dest := msg.Destination
+	if imapw.gmail && imapw.isGmailArchive(dest) {
+		// Moving a message to All Mail removes the label of the current
+		// folder, which is how Gmail archives messages.
+		if all := imapw.gmailFolder(imap.AllAttr); all != "" {
+			if imapw.selected.Name == all {
+				imapw.worker.PostMessage(&types.Error{
+					Message: types.RespondTo(msg),
+					Error:   fmt.Errorf("messages are already archived"),
+				}, nil)
+				return
+			}
+			dest = all
+		}
+	}
 	uids := toSeqSet(msg.Uids)
-	if err := imapw.client.UidMove(uids, msg.Destination); err != nil {
+	if err := imapw.client.UidMove(uids, dest); err != nil {
 		imapw.worker.PostMessage(&types.Error{
 			Message: types.RespondTo(msg),
 			Error:   err,
@@ -57,7 +75,7 @@ func (imapw *IMAPWorker) handleMoveMessages(msg *types.MoveMessages) {
 	} else {
 		imapw.worker.PostMessage(&types.MessagesMoved{
 			Message:     types.RespondTo(msg),
-			Destination: msg.Destination,
+			Destination: dest,
 			Uids:        msg.Uids,
 		}, nil)
 		imapw.worker.PostMessage(&types.Done{Message: types.RespondTo(msg)}, nil)
@@ -3,6 +3,7 @@ package imap
 import (
 	"sort"
 
+	"github.com/emersion/go-imap"
 	sortthread "github.com/emersion/go-imap-sortthread"
 
 	"git.sr.ht/~rjarry/aerc/log"
@@ -20,6 +21,9 @@ func (imapw *IMAPWorker) handleOpenDirectory(msg *types.OpenDirectory) {
 		}, nil)
 	} else {
 		imapw.selected = sel
+		if !imapw.gmail {
+			imapw.emitLabelList(sel)
+		}
 		imapw.worker.PostMessage(&types.Done{Message: types.RespondTo(msg)}, nil)
 	}
 }
@@ -29,7 +33,7 @@ func (imapw *IMAPWorker) handleFetchDirectoryContents(
 ) {
 	log.Tracef("Fetching UID list")
 
-	searchCriteria, err := parseSearch(msg.FilterCriteria)
+	searchCriteria, raw, err := parseSearch(msg.FilterCriteria, imapw.gmail)
 	if err != nil {
 		imapw.worker.PostMessage(&types.Error{
 			Message: types.RespondTo(msg),
@@ -43,7 +47,7 @@ func (imapw *IMAPWorker) handleFetchDirectoryContents(
 
 	// If the server supports the SORT extension, do the sorting server side
 	ok, err := imapw.client.sort.SupportSort()
-	if err == nil && ok && len(sortCriteria) > 0 {
+	if err == nil && ok && len(sortCriteria) > 0 && raw == "" {
 		uids, err = imapw.client.sort.UidSort(sortCriteria, searchCriteria)
 		// copy in reverse as msgList displays backwards
 		for i, j := 0, len(uids)-1; i < j; i, j = i+1, j-1 {
@@ -56,7 +60,7 @@ func (imapw *IMAPWorker) handleFetchDirectoryContents(
 		} else if len(sortCriteria) > 0 {
 			log.Warnf("SORT is not supported but requested: list messages by UID")
 		}
-		uids, err = imapw.client.UidSearch(searchCriteria)
+		uids, err = imapw.uidSearch(searchCriteria, raw)
 	}
 	if err != nil {
 		imapw.worker.PostMessage(&types.Error{
@@ -105,9 +109,14 @@ func translateSortCriterions(
 func (imapw *IMAPWorker) handleDirectoryThreaded(
 	msg *types.FetchDirectoryThreaded,
 ) {
+	if imapw.gmail {
+		imapw.handleGmailThreaded(msg)
+		return
+	}
+
 	log.Tracef("Fetching threaded UID list")
 
-	searchCriteria, err := parseSearch(msg.FilterCriteria)
+	searchCriteria, _, err := parseSearch(msg.FilterCriteria, false)
 	if err != nil {
 		imapw.worker.PostMessage(&types.Error{
 			Message: types.RespondTo(msg),
@@ -179,3 +188,17 @@ func convertThreads(threads []*sortthread.Thread, parent *types.Thread) ([]*type
 	}
 	return conv, count
 }
+
+// emitLabelList sends the keywords defined in the selected folder.
+func (imapw *IMAPWorker) emitLabelList(sel *imap.MailboxStatus) {
+	seen := make(map[string]bool)
+	var labels []string
+	for _, kw := range translateImapKeywords(append(sel.Flags, sel.PermanentFlags...)) {
+		if !seen[kw] {
+			seen[kw] = true
+			labels = append(labels, kw)
+		}
+	}
+	sort.Strings(labels)
+	imapw.worker.PostMessage(&types.LabelList{Labels: labels}, nil)
+}
@@ -0,0 +1,124 @@
+package imap
+
+import (
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+
+	"git.sr.ht/~rjarry/aerc/log"
+	"git.sr.ht/~rjarry/aerc/worker/types"
+)
+
+// connPool holds secondary connections to the server. Bulk operations run
+// on them in the background so that the main connection remains available
+// for interactive commands. Connections are opened on demand and reused.
+type connPool struct {
+	// opens a new authenticated connection
+	dial  func() (*client.Client, error)
+	slots chan struct{}
+	free  chan *imapClient
+}
+
+func newConnPool(dial func() (*client.Client, error), size int) *connPool {
+	return &connPool{
+		dial:  dial,
+		slots: make(chan struct{}, size),
+		free:  make(chan *imapClient, size),
+	}
+}
+
+// get returns an idle connection or opens a new one. It blocks while all
+// connections of the pool are in use.
+func (p *connPool) get() (*imapClient, error) {
+	p.slots <- struct{}{}
+loop:
+	for {
+		select {
+		case c := <-p.free:
+			if c.State() != imap.LogoutState {
+				return c, nil
+			}
+		default:
+			break loop
+		}
+	}
+	c, err := p.dial()
+	if err != nil {
+		<-p.slots
+		return nil, err
+	}
+	log.Debugf("opened pool connection (%d in use)", len(p.slots))
+	return newImapClient(c), nil
+}
+
+// put gives a connection back to the pool. Broken connections are closed.
+func (p *connPool) put(c *imapClient, broken bool) {
+	if broken {
+		_ = c.Logout()
+	} else {
+		p.free <- c
+	}
+	<-p.slots
+}
+
+// Close logs out of the idle connections.
+func (p *connPool) Close() {
+	for {
+		select {
+		case c := <-p.free:
+			_ = c.Logout()
+		default:
+			return
+		}
+	}
+}
+
+// background runs msg on a pool connection if it is a bulk operation. The
+// folder selected on the main connection is examined on the pool connection
+// beforehand. It returns false if msg must be handled by the main connection.
+func (w *IMAPWorker) background(msg types.WorkerMessage) bool {
+	if w.pool == nil || w.client == nil {
+		return false
+	}
+	var run func(c *imapClient)
+	switch msg := msg.(type) {
+	case *types.FetchFullMessages:
+		if !msg.Background {
+			return false
+		}
+		run = func(c *imapClient) { w.fetchFullMessages(c, msg) }
+	case *types.FetchMessageBodyPart:
+		if !msg.Background {
+			return false
+		}
+		run = func(c *imapClient) { w.fetchBodyPart(c, msg) }
+	case *types.CheckMail:
+		run = func(c *imapClient) { w.checkMail(c, msg) }
+	default:
+		return false
+	}
+	folder := w.selected.Name
+	go func() {
+		defer log.PanicHandler()
+		c, err := w.pool.get()
+		if err != nil {
+			w.worker.PostMessage(&types.Error{
+				Message: types.RespondTo(msg),
+				Error:   err,
+			}, nil)
+			return
+		}
+		if mbox := c.Mailbox(); folder != "" && (mbox == nil || mbox.Name != folder) {
+			if _, err := c.Select(folder, true); err != nil {
+				w.pool.put(c, true)
+				w.worker.PostMessage(&types.Error{
+					Message: types.RespondTo(msg),
+					Error:   err,
+				}, nil)
+				return
+			}
+		}
+		run(c)
+		w.pool.put(c, c.State() == imap.LogoutState)
+	}()
+	return true
+}
@@ -0,0 +1,158 @@
+package imap
+
+import (
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/backend/memory"
+	"github.com/emersion/go-imap/client"
+	"github.com/emersion/go-imap/server"
+)
+
+// newTestPool returns a pool of connections to an in-memory IMAP server and
+// the number of connections opened so far.
+func newTestPool(t *testing.T, size int) (*connPool, *int32) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen: %v", err)
+	}
+	s := server.New(memory.New())
+	s.AllowInsecureAuth = true
+	go func() { _ = s.Serve(l) }()
+	t.Cleanup(func() { s.Close() })
+
+	var dialed int32
+	pool := newConnPool(func() (*client.Client, error) {
+		c, err := client.Dial(l.Addr().String())
+		if err != nil {
+			return nil, err
+		}
+		if err := c.Login("username", "password"); err != nil {
+			return nil, err
+		}
+		atomic.AddInt32(&dialed, 1)
+		return c, nil
+	}, size)
+	t.Cleanup(pool.Close)
+	return pool, &dialed
+}
+
+func TestConnPool(t *testing.T) {
+	pool, dialed := newTestPool(t, 2)
+
+	tests := []struct {
+		name   string
+		run    func() error
+		dialed int32
+	}{
+		{
+			name: "connections are reused",
+			run: func() error {
+				c, err := pool.get()
+				if err != nil {
+					return err
+				}
+				pool.put(c, false)
+				c, err = pool.get()
+				if err != nil {
+					return err
+				}
+				pool.put(c, false)
+				return nil
+			},
+			dialed: 1,
+		},
+		{
+			name: "broken connections are replaced",
+			run: func() error {
+				c, err := pool.get()
+				if err != nil {
+					return err
+				}
+				pool.put(c, true)
+				c, err = pool.get()
+				if err != nil {
+					return err
+				}
+				pool.put(c, false)
+				return nil
+			},
+			dialed: 2,
+		},
+		{
+			name: "logged out connections are replaced",
+			run: func() error {
+				c, err := pool.get()
+				if err != nil {
+					return err
+				}
+				_ = c.Logout()
+				pool.put(c, false)
+				c, err = pool.get()
+				if err != nil {
+					return err
+				}
+				if c.State() == imap.LogoutState {
+					t.Errorf("got a logged out connection")
+				}
+				pool.put(c, false)
+				return nil
+			},
+			dialed: 3,
+		},
+	}
+
+	for _, test := range tests {
+		if err := test.run(); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if n := atomic.LoadInt32(dialed); n != test.dialed {
+			t.Errorf("%s: %d connections opened, expected %d",
+				test.name, n, test.dialed)
+		}
+	}
+}
+
+func TestConnPoolSize(t *testing.T) {
+	pool, dialed := newTestPool(t, 1)
+
+	c, err := pool.get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(chan *imapClient)
+	go func() {
+		c, err := pool.get()
+		if err != nil {
+			t.Error(err)
+		}
+		got <- c
+	}()
+	select {
+	case <-got:
+		t.Fatal("get did not block while the pool was full")
+	case <-time.After(100 * time.Millisecond):
+	}
+	pool.put(c, false)
+	select {
+	case c2 := <-got:
+		if c2 != c {
+			t.Errorf("the released connection was not reused")
+		}
+		pool.put(c2, false)
+	case <-time.After(5 * time.Second):
+		t.Fatal("get still blocked after put")
+	}
+	if n := atomic.LoadInt32(dialed); n != 1 {
+		t.Errorf("%d connections opened, expected 1", n)
+	}
+
+	pool.Close()
+	if c.State() != imap.LogoutState {
+		t.Errorf("idle connection not closed")
+	}
+}
@@ -11,15 +11,20 @@ import (
 	"git.sr.ht/~sircmpwn/getopt"
 )
 
-func parseSearch(args []string) (*imap.SearchCriteria, error) {
+// parseSearch translates search arguments to IMAP search criteria. If gmail
+// is set (Gmail server and gmail-search enabled), the search terms are
+// returned as a raw Gmail query, unless -a or -b is specified.
+func parseSearch(
+	args []string, gmail bool,
+) (*imap.SearchCriteria, string, error) {
 	criteria := imap.NewSearchCriteria()
 	if len(args) == 0 {
-		return criteria, nil
+		return criteria, "", nil
 	}
 
 	opts, optind, err := getopt.Getopts(args, "rubax:X:t:H:f:c:d:")
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	body := false
 	text := false
@@ -63,17 +68,20 @@ func parseSearch(args []string) (*imap.SearchCriteria, error) {
 			}
 		}
 	}
+	var raw string
 	switch {
 	case text:
 		criteria.Text = args[optind:]
 	case body:
 		criteria.Body = args[optind:]
+	case gmail:
+		raw = strings.Join(args[optind:], " ")
 	default:
 		for _, arg := range args[optind:] {
 			criteria.Header.Add("Subject", arg)
 		}
 	}
-	return criteria, nil
+	return criteria, raw, nil
 }
 
 func getParsedFlag(name string) (string, error) {
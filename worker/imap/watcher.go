@@ -0,0 +1,320 @@
+package imap
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+
+	"git.sr.ht/~rjarry/aerc/log"
+	"git.sr.ht/~rjarry/aerc/models"
+	"git.sr.ht/~rjarry/aerc/worker/types"
+)
+
+// Only the selected folder is idling on the main connection. The other
+// watched folders are followed either with the NOTIFY extension on the main
+// connection, or with a pool of watchers which each examine a folder on an
+// additional connection and idle on it.
+
+var watchItems = []imap.StatusItem{
+	imap.StatusMessages,
+	imap.StatusRecent,
+	imap.StatusUnseen,
+	imap.StatusUidNext,
+}
+
+// startNotify enables the NOTIFY extension for the watched folders. If the
+// server does not support it, a watcher is started for each watched folder.
+// Nothing is watched if no folders are configured.
+func (w *IMAPWorker) startNotify() {
+	w.stopWatchers()
+	w.idler.SetNotify(nil)
+	if len(w.config.watchFolders) == 0 {
+		return
+	}
+	ok, err := w.client.notify.SupportNotify()
+	if err == nil && ok {
+		statuses, err := w.client.notify.Notify(w.config.watchFolders)
+		if err == nil {
+			log.Debugf("Server Capability found: NOTIFY")
+			w.idler.SetNotify(w.notifications)
+			for _, status := range statuses {
+				if status.UidNext != 0 {
+					w.uidNext[status.Name] = status.UidNext
+				}
+			}
+			return
+		}
+		log.Errorf("NOTIFY failed: %v", err)
+	}
+	for _, folder := range w.config.watchFolders {
+		wt := newWatcher(w, folder)
+		w.watchers = append(w.watchers, wt)
+		go wt.run()
+	}
+}
+
+func (w *IMAPWorker) stopWatchers() {
+	for _, wt := range w.watchers {
+		wt.Stop()
+	}
+	w.watchers = nil
+	w.auxLock.Lock()
+	if w.aux != nil {
+		_ = w.aux.Logout()
+		w.aux = nil
+	}
+	w.auxLock.Unlock()
+}
+
+// handleNotification updates the counts of a folder after the server
+// notified a change. If new messages arrived, they are reported on the
+// auxiliary connection.
+func (w *IMAPWorker) handleNotification(update *imap.MailboxStatus) {
+	name := update.Name
+	if w.client == nil || name == w.selected.Name {
+		return
+	}
+	if err := w.idler.Stop(); err != nil {
+		log.Errorf("cannot handle notification: %v", err)
+		return
+	}
+	defer w.idler.Start()
+
+	status, err := w.client.Status(name, watchItems)
+	if err != nil {
+		log.Errorf("cannot get status of %s: %v", name, err)
+		return
+	}
+	w.worker.PostMessage(&types.DirectoryInfo{
+		Info: &models.DirectoryInfo{
+			Flags:          status.Flags,
+			Name:           status.Name,
+			ReadOnly:       status.ReadOnly,
+			AccurateCounts: true,
+
+			Exists: int(status.Messages),
+			Recent: int(status.Recent),
+			Unseen: int(status.Unseen),
+			Caps:   w.caps,
+		},
+		SkipSort: true,
+	}, nil)
+
+	prev, ok := w.uidNext[status.Name]
+	w.uidNext[status.Name] = status.UidNext
+	if ok && status.UidNext > prev {
+		go w.fetchNewAux(status.Name, prev)
+	}
+}
+
+// fetchNewAux reports the new messages of a folder using the auxiliary
+// connection, which is opened on demand.
+func (w *IMAPWorker) fetchNewAux(folder string, since uint32) {
+	defer log.PanicHandler()
+	w.auxLock.Lock()
+	defer w.auxLock.Unlock()
+
+	if w.aux == nil || w.aux.State() == imap.LogoutState {
+		c, err := w.connect()
+		if err != nil {
+			log.Errorf("cannot open auxiliary connection: %v", err)
+			return
+		}
+		w.aux = c
+	}
+	_, err := w.aux.Select(folder, true)
+	if err == nil {
+		_, err = w.postNewMessages(w.aux, folder, since)
+	}
+	if err != nil {
+		log.Errorf("cannot fetch new messages of %s: %v", folder, err)
+		_ = w.aux.Logout()
+		w.aux = nil
+	}
+}
+
+// postNewMessages reports the messages of the examined folder whose uid is
+// greater or equal to since. It returns the uid following the last message.
+func (w *IMAPWorker) postNewMessages(
+	c *client.Client, folder string, since uint32,
+) (uint32, error) {
+	set := new(imap.SeqSet)
+	set.AddRange(since, 0)
+	messages := make(chan *imap.Message)
+	done := make(chan uint32)
+	go func() {
+		defer log.PanicHandler()
+		next := since
+		for m := range messages {
+			// since:* always matches the last message
+			if m.Uid < since {
+				continue
+			}
+			if m.Uid >= next {
+				next = m.Uid + 1
+			}
+			info, err := w.headerInfo(m)
+			if err != nil {
+				log.Errorf("cannot read new message of %s: %v", folder, err)
+				continue
+			}
+			if info.Flags.Has(models.SeenFlag) {
+				continue
+			}
+			w.worker.PostMessage(&types.NewMessage{
+				Directory: folder,
+				Info:      info,
+			}, nil)
+		}
+		done <- next
+	}()
+	err := c.UidFetch(set, w.headerItems(), messages)
+	next := <-done
+	return next, err
+}
+
+// watcher follows a folder on its own connection. It reconnects until it is
+// stopped.
+type watcher struct {
+	w       *IMAPWorker
+	folder  string
+	uidNext uint32
+	stop    chan struct{}
+	once    sync.Once
+}
+
+func newWatcher(w *IMAPWorker, folder string) *watcher {
+	return &watcher{w: w, folder: folder, stop: make(chan struct{})}
+}
+
+func (wt *watcher) Stop() {
+	wt.once.Do(func() { close(wt.stop) })
+}
+
+func (wt *watcher) run() {
+	defer log.PanicHandler()
+	backoff := time.Second
+	for {
+		err := wt.watch()
+		select {
+		case <-wt.stop:
+			return
+		default:
+		}
+		log.Warnf("watcher %s: %v; retrying in %s", wt.folder, err, backoff)
+		select {
+		case <-wt.stop:
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > wt.w.config.reconnect_maxwait {
+			backoff = wt.w.config.reconnect_maxwait
+		}
+	}
+}
+
+// watch examines the folder and idles until a change is reported.
+func (wt *watcher) watch() error {
+	c, err := wt.w.connect()
+	if err != nil {
+		return err
+	}
+	defer c.Logout() //nolint:errcheck // nothing to do on failure
+	c.Timeout = wt.w.config.connection_timeout
+	updates := make(chan client.Update, 50)
+	c.Updates = updates
+
+	mbox, err := c.Select(wt.folder, true)
+	if err != nil {
+		return err
+	}
+	if wt.uidNext == 0 {
+		wt.uidNext = mbox.UidNext
+	}
+	log.Debugf("watcher %s: started", wt.folder)
+	if err := wt.refresh(c); err != nil {
+		return err
+	}
+
+	for {
+		stop := make(chan struct{})
+		done := make(chan error, 1)
+		// no timeout while idling
+		c.Timeout = 0
+		go func() {
+			defer log.PanicHandler()
+			done <- c.Idle(stop, &client.IdleOptions{
+				LogoutTimeout: 0,
+				PollInterval:  0,
+			})
+		}()
+		select {
+		case <-wt.stop:
+			close(stop)
+			<-done
+			return nil
+		case err := <-done:
+			close(stop)
+			if err == nil {
+				err = fmt.Errorf("idle interrupted")
+			}
+			return err
+		case <-updates:
+			close(stop)
+			if err := <-done; err != nil {
+				return err
+			}
+		}
+		c.Timeout = wt.w.config.connection_timeout
+		// coalesce the pending updates
+	drain:
+		for {
+			select {
+			case <-updates:
+			default:
+				break drain
+			}
+		}
+		if err := wt.refresh(c); err != nil {
+			return err
+		}
+	}
+}
+
+// refresh reports the counts of the folder and its new messages.
+func (wt *watcher) refresh(c *client.Client) error {
+	criteria := imap.NewSearchCriteria()
+	criteria.WithoutFlags = []string{imap.SeenFlag}
+	unseen, err := c.UidSearch(criteria)
+	if err != nil {
+		return err
+	}
+	mbox := c.Mailbox()
+	wt.w.worker.PostMessage(&types.DirectoryInfo{
+		Info: &models.DirectoryInfo{
+			Flags:          mbox.Flags,
+			Name:           wt.folder,
+			ReadOnly:       mbox.ReadOnly,
+			AccurateCounts: true,
+
+			Exists: int(mbox.Messages),
+			Recent: int(mbox.Recent),
+			Unseen: len(unseen),
+			Caps:   wt.w.caps,
+		},
+		SkipSort: true,
+	}, nil)
+	if mbox.Messages == 0 {
+		return nil
+	}
+	next, err := wt.w.postNewMessages(c, wt.folder, wt.uidNext)
+	if err != nil {
+		return err
+	}
+	wt.uidNext = next
+	return nil
+}
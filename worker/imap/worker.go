@@ -3,6 +3,7 @@ package imap
 import (
 	"fmt"
 	"net/url"
+	"sync"
 	"time"
 
 	"github.com/emersion/go-imap"
@@ -36,6 +37,7 @@ type imapClient struct {
 	thread     *sortthread.ThreadClient
 	sort       *sortthread.SortClient
 	liststatus *extensions.ListStatusClient
+	notify     *extensions.NotifyClient
 }
 
 type imapConfig struct {
@@ -56,6 +58,10 @@ type imapConfig struct {
 	keepalive_interval int
 	cacheEnabled       bool
 	cacheMaxAge        time.Duration
+	archive            string
+	watchFolders       []string
+	poolSize           int
+	gmailSearch        bool
 }
 
 type IMAPWorker struct {
@@ -75,27 +81,48 @@ type IMAPWorker struct {
 
 	threadAlgorithm sortthread.ThreadAlgorithm
 	liststatus      bool
+	gmail           bool // X-GM-EXT-1 is supported
+	// special-use attribute -> Gmail folder name
+	gmailFolders map[string]string
+
+	// changes of the watched folders reported with NOTIFY
+	notifications chan *imap.MailboxStatus
+	uidNext       map[string]uint32
+	watchers      []*watcher
+	// auxiliary connection to fetch new messages of other folders
+	aux     *client.Client
+	auxLock sync.Mutex
+	// secondary connections for background operations
+	pool *connPool
 }
 
 func NewIMAPWorker(worker *types.Worker) (types.Backend, error) {
 	return &IMAPWorker{
-		updates:  make(chan client.Update, 50),
-		worker:   worker,
-		selected: &imap.MailboxStatus{},
-		idler:    newIdler(imapConfig{}, worker),
-		observer: newObserver(imapConfig{}, worker),
-		caps:     &models.Capabilities{},
+		updates: make(chan client.Update, 50),
+		worker:  worker,
+		uidNext: make(map[string]uint32),
+		// buffered so that the client never blocks on notifications
+		notifications: make(chan *imap.MailboxStatus, 50),
+		selected:      &imap.MailboxStatus{},
+		idler:         newIdler(imapConfig{}, worker),
+		observer:      newObserver(imapConfig{}, worker),
+		caps:          &models.Capabilities{},
 	}, nil
 }
 
-func (w *IMAPWorker) newClient(c *client.Client) {
-	c.Updates = w.updates
-	w.client = &imapClient{
+func newImapClient(c *client.Client) *imapClient {
+	return &imapClient{
 		c,
 		sortthread.NewThreadClient(c),
 		sortthread.NewSortClient(c),
 		extensions.NewListStatusClient(c),
+		extensions.NewNotifyClient(c),
 	}
+}
+
+func (w *IMAPWorker) newClient(c *client.Client) {
+	c.Updates = w.updates
+	w.client = newImapClient(c)
 	w.idler.SetClient(w.client)
 	w.observer.SetClient(w.client)
 	sort, err := w.client.sort.SupportSort()
@@ -117,6 +144,14 @@ func (w *IMAPWorker) newClient(c *client.Client) {
 		w.liststatus = true
 		log.Debugf("Server Capability found: LIST-STATUS")
 	}
+	gmail, err := w.client.Support("X-GM-EXT-1")
+	if err == nil && gmail {
+		w.gmail = true
+		// conversations are threaded with X-GM-THRID
+		w.caps.Thread = true
+		log.Debugf("Server Capability found: X-GM-EXT-1")
+	}
+	w.startNotify()
 }
 
 func (w *IMAPWorker) handleMessage(msg types.WorkerMessage) error {
@@ -187,6 +222,10 @@ func (w *IMAPWorker) handleMessage(msg types.WorkerMessage) error {
 	case *types.Disconnect:
 		w.observer.SetAutoReconnect(false)
 		w.observer.Stop()
+		w.stopWatchers()
+		if w.pool != nil {
+			w.pool.Close()
+		}
 		if w.client == nil || w.client.State() != imap.SelectedState {
 			reterr = errNotConnected
 			break
@@ -231,6 +270,8 @@ func (w *IMAPWorker) handleMessage(msg types.WorkerMessage) error {
 		w.handleAppendMessage(msg)
 	case *types.SearchDirectory:
 		w.handleSearchDirectory(msg)
+	case *types.ModifyLabels:
+		w.handleModifyLabels(msg)
 	case *types.CheckMail:
 		w.handleCheckMailMessage(msg)
 	default:
@@ -283,6 +324,7 @@ func (w *IMAPWorker) handleImapUpdate(update client.Update) {
 				BodyStructure: translateBodyStructure(msg.BodyStructure),
 				Envelope:      translateEnvelope(msg.Envelope),
 				Flags:         translateImapFlags(msg.Flags),
+				Labels:        w.messageLabels(msg),
 				InternalDate:  msg.InternalDate,
 				Uid:           msg.Uid,
 			},
@@ -303,6 +345,9 @@ func (w *IMAPWorker) Run() {
 		select {
 		case msg := <-w.worker.Actions:
 			msg = w.worker.ProcessAction(msg)
+			if w.background(msg) {
+				continue
+			}
 
 			if err := w.handleMessage(msg); errors.Is(err, errUnsupported) {
 				w.worker.PostMessage(&types.Unsupported{
@@ -317,6 +362,34 @@ func (w *IMAPWorker) Run() {
 
 		case update := <-w.updates:
 			w.handleImapUpdate(update)
+
+		case status := <-w.notifications:
+			w.handleNotification(status)
+		}
+	}
+}
+
+// messageLabels returns the labels of a fetched message. With Gmail, these
+// are the X-GM-LABELS, otherwise the keywords found in the message flags. nil
+// is returned if the labels were not part of the response.
+func (w *IMAPWorker) messageLabels(msg *imap.Message) []string {
+	if w.gmail {
+		if item, ok := msg.Items[gmailLabelsItem]; ok {
+			return translateGmailLabels(item)
 		}
+		return nil
+	}
+	if _, ok := msg.Items[imap.FetchFlags]; ok {
+		return translateImapKeywords(msg.Flags)
+	}
+	return nil
+}
+
+// labelItems returns the fetch items needed to get the message labels in
+// addition to the flags.
+func (w *IMAPWorker) labelItems() []imap.FetchItem {
+	if w.gmail {
+		return []imap.FetchItem{gmailLabelsItem}
 	}
+	return nil
 }
@@ -0,0 +1,215 @@
+package lib
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// Codec describes a compression format.
+type Codec struct {
+	Name      string
+	Extension string
+	magic     []byte
+	reader    func(io.Reader) (io.ReadCloser, error)
+	writer    func(io.Writer) (io.WriteCloser, error)
+}
+
+// Codecs lists the supported compression formats.
+var Codecs = []*Codec{
+	{
+		Name:      "gzip",
+		Extension: ".gz",
+		magic:     []byte{0x1f, 0x8b},
+		reader: func(r io.Reader) (io.ReadCloser, error) {
+			return gzip.NewReader(r)
+		},
+		writer: func(w io.Writer) (io.WriteCloser, error) {
+			return gzip.NewWriter(w), nil
+		},
+	},
+	{
+		Name:      "zstd",
+		Extension: ".zst",
+		magic:     []byte{0x28, 0xb5, 0x2f, 0xfd},
+		reader: func(r io.Reader) (io.ReadCloser, error) {
+			d, err := zstd.NewReader(r)
+			if err != nil {
+				return nil, err
+			}
+			return d.IOReadCloser(), nil
+		},
+		writer: func(w io.Writer) (io.WriteCloser, error) {
+			return zstd.NewWriter(w)
+		},
+	},
+	{
+		Name:      "xz",
+		Extension: ".xz",
+		magic:     []byte{0xfd, '7', 'z', 'X', 'Z', 0x00},
+		reader: func(r io.Reader) (io.ReadCloser, error) {
+			x, err := xz.NewReader(r)
+			if err != nil {
+				return nil, err
+			}
+			return io.NopCloser(x), nil
+		},
+		writer: func(w io.Writer) (io.WriteCloser, error) {
+			return xz.NewWriter(w)
+		},
+	},
+}
+
+// CodecFor returns the compression format matching the file extension, or
+// nil if the file is not compressed.
+func CodecFor(filename string) *Codec {
+	for _, c := range Codecs {
+		if strings.HasSuffix(filename, c.Extension) {
+			return c
+		}
+	}
+	return nil
+}
+
+// TrimExtension removes the compression extension from a file name.
+func TrimExtension(filename string) string {
+	if c := CodecFor(filename); c != nil {
+		return strings.TrimSuffix(filename, c.Extension)
+	}
+	return filename
+}
+
+// NewReader returns a reader which decompresses data from r.
+func (c *Codec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return c.reader(r)
+}
+
+// NewWriter returns a writer which compresses data to w. It must be closed
+// to flush all data.
+func (c *Codec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return c.writer(w)
+}
+
+// DecompressToTemp extracts the data compressed in r to an anonymous
+// temporary file, positioned at its beginning, so that it can be read at
+// random.
+func (c *Codec) DecompressToTemp(r io.Reader) (*os.File, error) {
+	tmp, err := CreateTemp()
+	if err != nil {
+		return nil, err
+	}
+	dr, err := c.NewReader(r)
+	if err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	defer dr.Close()
+	if _, err := io.Copy(tmp, dr); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	return tmp, nil
+}
+
+// CreateTemp creates a temporary file which is removed as soon as it is
+// closed.
+func CreateTemp() (*os.File, error) {
+	tmp, err := os.CreateTemp("", "aerc-*")
+	if err != nil {
+		return nil, err
+	}
+	// the file remains accessible until closed
+	_ = os.Remove(tmp.Name())
+	return tmp, nil
+}
+
+// Decompress returns a reader which decompresses r if its content starts with
+// the magic bytes of a known compression format. Otherwise, the data is
+// returned unchanged.
+func Decompress(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(maxMagic)
+	if c := DetectCodec(head); c != nil {
+		return c.NewReader(br)
+	}
+	return io.NopCloser(br), nil
+}
+
+const maxMagic = 6
+
+// DetectCodec returns the compression format whose magic bytes match the
+// start of the data, or nil if the data is not compressed.
+func DetectCodec(head []byte) *Codec {
+	for _, c := range Codecs {
+		if bytes.HasPrefix(head, c.magic) {
+			return c
+		}
+	}
+	return nil
+}
+
+type compressedFile struct {
+	io.ReadCloser
+	file *os.File
+}
+
+func (f *compressedFile) Close() error {
+	f.ReadCloser.Close()
+	return f.file.Close()
+}
+
+// OpenFile opens a file for reading and transparently decompresses it.
+func OpenFile(filename string) (io.ReadCloser, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	r, err := Decompress(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &compressedFile{ReadCloser: r, file: file}, nil
+}
+
+type compressingFile struct {
+	io.WriteCloser
+	file *os.File
+}
+
+func (f *compressingFile) Close() error {
+	if err := f.WriteCloser.Close(); err != nil {
+		f.file.Close()
+		return err
+	}
+	return f.file.Close()
+}
+
+// CreateFile creates a file for writing. The data is compressed according to
+// the file extension.
+func CreateFile(filename string) (io.WriteCloser, error) {
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+	c := CodecFor(filename)
+	if c == nil {
+		return file, nil
+	}
+	w, err := c.NewWriter(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &compressingFile{WriteCloser: w, file: file}, nil
+}
@@ -0,0 +1,74 @@
+package lib
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestCompressRoundTrip(t *testing.T) {
+	const data = "From: alice@example.com\nSubject: hello\n\nHello\n"
+	for _, c := range Codecs {
+		var buf bytes.Buffer
+		w, err := c.NewWriter(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(w, data); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		r, err := Decompress(&buf)
+		if err != nil {
+			t.Fatalf("%s: %v", c.Name, err)
+		}
+		out, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("%s: %v", c.Name, err)
+		}
+		if string(out) != data {
+			t.Errorf("%s: got %q", c.Name, out)
+		}
+	}
+
+	r, err := Decompress(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, _ := io.ReadAll(r)
+	if string(out) != data {
+		t.Errorf("plain data was modified: %q", out)
+	}
+}
+
+func TestDecompressToTemp(t *testing.T) {
+	const data = "From: alice@example.com\nSubject: hello\n\nHello\n"
+	for _, c := range Codecs {
+		var buf bytes.Buffer
+		w, err := c.NewWriter(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(w, data); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		tmp, err := c.DecompressToTemp(&buf)
+		if err != nil {
+			t.Fatalf("%s: %v", c.Name, err)
+		}
+		out, err := io.ReadAll(tmp)
+		tmp.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", c.Name, err)
+		}
+		if string(out) != data {
+			t.Errorf("%s: got %q", c.Name, out)
+		}
+	}
+}
@@ -301,6 +301,10 @@ func MessageInfo(raw RawMessage) (*models.MessageInfo, error) {
 	if err != nil {
 		return nil, err
 	}
+	if labels == nil {
+		// no labels, not unknown labels
+		labels = []string{}
+	}
 	return &models.MessageInfo{
 		BodyStructure: bs,
 		Envelope:      env,
@@ -351,6 +355,10 @@ func MessageHeaders(raw RawMessage) (*models.MessageInfo, error) {
 	if err != nil {
 		return nil, err
 	}
+	if labels == nil {
+		// no labels, not unknown labels
+		labels = []string{}
+	}
 	refs, err := h.MsgIDList("references")
 	if err != nil {
 		return nil, err
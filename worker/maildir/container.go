@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"sync"
 
 	"github.com/emersion/go-maildir"
 
@@ -18,6 +19,9 @@ type Container struct {
 	Store      *lib.MaildirStore
 	uids       *uidstore.Store
 	recentUIDS map[uint32]struct{} // used to set the recent flag
+
+	keywordsLock sync.Mutex
+	keywords     map[maildir.Dir]*keywords
 }
 
 // NewContainer creates a new container at the specified directory
@@ -29,6 +33,7 @@ func NewContainer(dir string, maildirpp bool) (*Container, error) {
 	return &Container{
 		Store: store, uids: uidstore.NewStore(),
 		recentUIDS: make(map[uint32]struct{}),
+		keywords:   make(map[maildir.Dir]*keywords),
 	}, nil
 }
 
@@ -80,13 +85,26 @@ func (c *Container) UIDs(d maildir.Dir) ([]uint32, error) {
 	return uids, nil
 }
 
+// Keywords returns the Dovecot keywords of a maildir
+func (c *Container) Keywords(d maildir.Dir) *keywords {
+	c.keywordsLock.Lock()
+	defer c.keywordsLock.Unlock()
+	k, ok := c.keywords[d]
+	if !ok {
+		k = newKeywords(d)
+		c.keywords[d] = k
+	}
+	return k
+}
+
 // Message returns a Message struct for the given UID and maildir
 func (c *Container) Message(d maildir.Dir, uid uint32) (*Message, error) {
 	if key, ok := c.uids.GetKey(uid); ok {
 		return &Message{
-			dir: d,
-			uid: uid,
-			key: key,
+			dir:      d,
+			uid:      uid,
+			key:      key,
+			keywords: c.Keywords(d),
 		}, nil
 	}
 	return nil, fmt.Errorf("could not find message with uid %d in maildir %s",
@@ -128,8 +146,20 @@ func (c *Container) copyMessage(
 	if !ok {
 		return fmt.Errorf("could not find key for message id %d", uid)
 	}
-	_, err := src.Copy(dest, key)
-	return err
+	newKey, err := src.Copy(dest, key)
+	if err != nil {
+		return err
+	}
+	// keyword letters are specific to each maildir
+	flags, err := src.Flags(key)
+	if err != nil {
+		return err
+	}
+	remapped, err := remapKeywords(c.Keywords(src), c.Keywords(dest), flags)
+	if err != nil {
+		return err
+	}
+	return dest.SetFlags(newKey, remapped)
 }
 
 func (c *Container) MoveAll(dest maildir.Dir, src maildir.Dir, uids []uint32) ([]uint32, error) {
@@ -154,6 +184,14 @@ func (c *Container) moveMessage(dest maildir.Dir, src maildir.Dir, uid uint32) e
 	}
 	// Remove encoded UID information from the key to prevent sync issues
 	name := lib.StripUIDFromMessageFilename(filepath.Base(path))
+	// keyword letters are specific to each maildir
+	if uniq, flags, err := splitMaildirFile(name); err == nil {
+		flags, err = remapKeywords(c.Keywords(src), c.Keywords(dest), flags)
+		if err != nil {
+			return err
+		}
+		name = uniq + ":" + formatInfo(flags)
+	}
 	destPath := filepath.Join(string(dest), "cur", name)
 	return os.Rename(path, destPath)
 }
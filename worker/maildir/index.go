@@ -0,0 +1,156 @@
+package maildir
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/gob"
+	"os"
+	"path/filepath"
+	"time"
+
+	"git.sr.ht/~rjarry/aerc/log"
+	"git.sr.ht/~rjarry/aerc/models"
+	"github.com/emersion/go-message"
+	"github.com/emersion/go-message/mail"
+	"github.com/emersion/go-message/textproto"
+	"github.com/kyoh86/xdg"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// headerIndex caches the parsed headers of maildir messages so that folders
+// can be listed and sorted without reading every file. Entries are keyed by
+// folder name and maildir unique key. They are only used as long as the size
+// and modification time of the message file are unchanged.
+type headerIndex struct {
+	db *leveldb.DB
+}
+
+type indexEntry struct {
+	ModTime       time.Time
+	Size          int64
+	BodyStructure models.BodyStructure
+	Envelope      models.Envelope
+	InternalDate  time.Time
+	Header        []byte
+	Refs          []string
+}
+
+// openHeaderIndex opens (or creates) the index database. One database is
+// created per account.
+func openHeaderIndex(account string) (*headerIndex, error) {
+	p := filepath.Join(xdg.CacheHome(), "aerc", "maildir", account)
+	db, err := leveldb.OpenFile(p, nil)
+	if err != nil {
+		return nil, err
+	}
+	log.Debugf("maildir index opened: %s", p)
+	return &headerIndex{db: db}, nil
+}
+
+func indexKey(folder, key string) []byte {
+	return []byte(folder + "\x00" + key)
+}
+
+// get returns the message info stored for a file, or nil if there is none or
+// if the file was modified since it was indexed. The flags and uid are not
+// part of the index and must be set by the caller.
+func (i *headerIndex) get(folder, key string, st os.FileInfo) *models.MessageInfo {
+	data, err := i.db.Get(indexKey(folder, key), nil)
+	if err != nil {
+		return nil
+	}
+	var e indexEntry
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&e); err != nil {
+		log.Errorf("cannot decode index entry %s/%s: %v", folder, key, err)
+		return nil
+	}
+	if e.Size != st.Size() || !e.ModTime.Equal(st.ModTime()) {
+		return nil
+	}
+	h, err := textproto.ReadHeader(bufio.NewReader(bytes.NewReader(e.Header)))
+	if err != nil {
+		log.Errorf("cannot read indexed header %s/%s: %v", folder, key, err)
+		return nil
+	}
+	return &models.MessageInfo{
+		BodyStructure: &e.BodyStructure,
+		Envelope:      &e.Envelope,
+		InternalDate:  e.InternalDate,
+		RFC822Headers: &mail.Header{Header: message.Header{Header: h}},
+		Refs:          e.Refs,
+	}
+}
+
+// put stores the message info of a file.
+func (i *headerIndex) put(folder, key string, st os.FileInfo, info *models.MessageInfo) {
+	if info.Error != nil || info.BodyStructure == nil ||
+		info.Envelope == nil || info.RFC822Headers == nil {
+		// incomplete, parse the file again next time
+		return
+	}
+	var hdr bytes.Buffer
+	if err := textproto.WriteHeader(&hdr, info.RFC822Headers.Header.Header); err != nil {
+		log.Errorf("cannot write header %s/%s: %v", folder, key, err)
+		return
+	}
+	refs, _ := info.RFC822Headers.MsgIDList("references")
+	e := &indexEntry{
+		ModTime:       st.ModTime(),
+		Size:          st.Size(),
+		BodyStructure: *info.BodyStructure,
+		Envelope:      *info.Envelope,
+		InternalDate:  info.InternalDate,
+		Header:        hdr.Bytes(),
+		Refs:          refs,
+	}
+	var data bytes.Buffer
+	if err := gob.NewEncoder(&data).Encode(e); err != nil {
+		log.Errorf("cannot encode index entry %s/%s: %v", folder, key, err)
+		return
+	}
+	if err := i.db.Put(indexKey(folder, key), data.Bytes(), nil); err != nil {
+		log.Errorf("cannot write index entry %s/%s: %v", folder, key, err)
+	}
+}
+
+// remove deletes the entry of a file.
+func (i *headerIndex) remove(folder, key string) {
+	if err := i.db.Delete(indexKey(folder, key), nil); err != nil {
+		log.Errorf("cannot remove index entry %s/%s: %v", folder, key, err)
+	}
+}
+
+// prune deletes the entries of a folder whose key is not in keys.
+func (i *headerIndex) prune(folder string, keys []string) {
+	defer log.PanicHandler()
+	start := time.Now()
+	existing := make(map[string]struct{}, len(keys))
+	for _, k := range keys {
+		existing[k] = struct{}{}
+	}
+	prefix := indexKey(folder, "")
+	batch := new(leveldb.Batch)
+	iter := i.db.NewIterator(util.BytesPrefix(prefix), nil)
+	for iter.Next() {
+		key := string(iter.Key()[len(prefix):])
+		if _, ok := existing[key]; !ok {
+			batch.Delete(append([]byte(nil), iter.Key()...))
+		}
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		log.Errorf("cannot prune index of %s: %v", folder, err)
+		return
+	}
+	if err := i.db.Write(batch, nil); err != nil {
+		log.Errorf("cannot prune index of %s: %v", folder, err)
+		return
+	}
+	log.Debugf("%s: removed %d stale index entries in %s",
+		folder, batch.Len(), time.Since(start))
+}
+
+func (i *headerIndex) Close() error {
+	return i.db.Close()
+}
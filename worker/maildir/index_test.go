@@ -0,0 +1,84 @@
+package maildir
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/emersion/go-maildir"
+
+	"git.sr.ht/~rjarry/aerc/models"
+)
+
+const testMessage = `From: Alice <alice@example.com>
+Subject: hello
+Message-Id: <1@example.com>
+References: <0@example.com>
+
+Hi
+`
+
+func TestHeaderIndex(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	dir := maildir.Dir(filepath.Join(t.TempDir(), "INBOX"))
+	if err := dir.Init(); err != nil {
+		t.Fatal(err)
+	}
+	key, wc, err := dir.Create([]maildir.Flag{maildir.FlagSeen})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(wc, testMessage); err != nil {
+		t.Fatal(err)
+	}
+	wc.Close()
+
+	index, err := openHeaderIndex("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer index.Close()
+	w := &Worker{selected: &dir, selectedName: "INBOX", index: index}
+	m := &Message{dir: dir, uid: 1, key: key}
+
+	info, err := w.messageInfo(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path, _ := dir.Filename(key)
+	st, _ := os.Stat(path)
+	if index.get("INBOX", key, st) == nil {
+		t.Fatal("message was not indexed")
+	}
+
+	if err := m.SetOneFlag(maildir.FlagFlagged, true); err != nil {
+		t.Fatal(err)
+	}
+	cached, err := w.messageInfo(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cached.Flags != models.SeenFlag|models.FlaggedFlag {
+		t.Errorf("unexpected flags %v", cached.Flags)
+	}
+	if cached.Envelope.Subject != info.Envelope.Subject ||
+		cached.RFC822Headers.Get("Message-Id") != "<1@example.com>" ||
+		len(cached.Refs) != 1 || cached.Uid != 1 {
+		t.Errorf("unexpected cached info %#v", cached)
+	}
+
+	path, _ = dir.Filename(key)
+	if err := os.WriteFile(path, []byte(testMessage+"more\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	st, _ = os.Stat(path)
+	if index.get("INBOX", key, st) != nil {
+		t.Error("modified file should not use the index")
+	}
+
+	index.prune("INBOX", nil)
+	if _, err := index.db.Get(indexKey("INBOX", key), nil); err == nil {
+		t.Error("entry should have been pruned")
+	}
+}
@@ -0,0 +1,250 @@
+package maildir
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/emersion/go-maildir"
+)
+
+const keywordsFile = "dovecot-keywords"
+
+// keywords maps the lowercase letters of maildir file names to keyword names,
+// as defined by the dovecot-keywords file of a maildir. The letter 'a'
+// corresponds to the keyword with index 0, 'b' to index 1 and so on.
+type keywords struct {
+	mu      sync.Mutex
+	path    string
+	modTime time.Time
+	names   [26]string
+}
+
+func newKeywords(dir maildir.Dir) *keywords {
+	return &keywords{path: filepath.Join(string(dir), keywordsFile)}
+}
+
+func isKeywordFlag(flag maildir.Flag) bool {
+	return flag >= 'a' && flag <= 'z'
+}
+
+// load reads the keywords file if it was modified since it was last read.
+// Must be called with the lock held.
+func (k *keywords) load() error {
+	st, err := os.Stat(k.path)
+	if errors.Is(err, os.ErrNotExist) {
+		k.names = [26]string{}
+		k.modTime = time.Time{}
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if st.ModTime().Equal(k.modTime) {
+		return nil
+	}
+	f, err := os.Open(k.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var names [26]string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		idx, name, found := strings.Cut(scanner.Text(), " ")
+		if !found {
+			continue
+		}
+		i, err := strconv.Atoi(idx)
+		if err != nil || i < 0 || i >= len(names) {
+			continue
+		}
+		names[i] = strings.TrimSpace(name)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	k.names = names
+	k.modTime = st.ModTime()
+	return nil
+}
+
+// save writes the keywords file atomically. Must be called with the lock
+// held.
+func (k *keywords) save() error {
+	tmp, err := os.CreateTemp(filepath.Dir(k.path), "."+keywordsFile+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	w := bufio.NewWriter(tmp)
+	for i, name := range k.names {
+		if name != "" {
+			fmt.Fprintf(w, "%d %s\n", i, name)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), k.path); err != nil {
+		return err
+	}
+	if st, err := os.Stat(k.path); err == nil {
+		k.modTime = st.ModTime()
+	}
+	return nil
+}
+
+// Labels returns the keyword names of the lowercase letters found in flags.
+func (k *keywords) Labels(flags []maildir.Flag) ([]string, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	var labels []string
+	for _, flag := range flags {
+		if !isKeywordFlag(flag) {
+			continue
+		}
+		if labels == nil {
+			if err := k.load(); err != nil {
+				return nil, err
+			}
+		}
+		if name := k.names[flag-'a']; name != "" {
+			labels = append(labels, name)
+		}
+	}
+	return labels, nil
+}
+
+// Letter returns the letter of a keyword. If the keyword is unknown and create
+// is true, it is assigned the first free letter and the keywords file is
+// updated. Otherwise, ok is false.
+func (k *keywords) Letter(name string, create bool) (maildir.Flag, bool, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if err := k.load(); err != nil {
+		return 0, false, err
+	}
+	free := -1
+	for i, n := range k.names {
+		if n == name {
+			return maildir.Flag('a' + i), true, nil
+		}
+		if n == "" && free < 0 {
+			free = i
+		}
+	}
+	if !create {
+		return 0, false, nil
+	}
+	if strings.ContainsAny(name, " \t\n") || name == "" {
+		return 0, false, fmt.Errorf("invalid keyword %q", name)
+	}
+	if free < 0 {
+		return 0, false, fmt.Errorf("%s: no free keyword slot for %q",
+			k.path, name)
+	}
+	k.names[free] = name
+	if err := k.save(); err != nil {
+		k.names[free] = ""
+		return 0, false, err
+	}
+	return maildir.Flag('a' + free), true, nil
+}
+
+// All returns all defined keywords.
+func (k *keywords) All() ([]string, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if err := k.load(); err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, n := range k.names {
+		if n != "" {
+			names = append(names, n)
+		}
+	}
+	return names, nil
+}
+
+// modifyKeywords returns flags with the letters of the remove keywords
+// removed and those of the add keywords added.
+func modifyKeywords(
+	k *keywords, flags []maildir.Flag, add, remove []string,
+) ([]maildir.Flag, error) {
+	drop := make(map[maildir.Flag]bool)
+	for _, name := range remove {
+		letter, ok, err := k.Letter(name, false)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			drop[letter] = true
+		}
+	}
+	var result []maildir.Flag
+	for _, flag := range flags {
+		if !drop[flag] {
+			result = append(result, flag)
+		}
+	}
+	for _, name := range add {
+		letter, _, err := k.Letter(name, true)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, letter)
+	}
+	return result, nil
+}
+
+// remapKeywords translates the keyword letters of flags from the keywords of
+// one maildir to those of another one.
+func remapKeywords(
+	src, dest *keywords, flags []maildir.Flag,
+) ([]maildir.Flag, error) {
+	labels, err := src.Labels(flags)
+	if err != nil {
+		return nil, err
+	}
+	var result []maildir.Flag
+	for _, flag := range flags {
+		if !isKeywordFlag(flag) {
+			result = append(result, flag)
+		}
+	}
+	for _, name := range labels {
+		letter, _, err := dest.Letter(name, true)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, letter)
+	}
+	return result, nil
+}
+
+// formatInfo returns the info part of a maildir file name with sorted and
+// deduplicated flags.
+func formatInfo(flags []maildir.Flag) string {
+	sorted := append([]maildir.Flag(nil), flags...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	var b strings.Builder
+	b.WriteString("2,")
+	for i, f := range sorted {
+		if i == 0 || f != sorted[i-1] {
+			b.WriteRune(rune(f))
+		}
+	}
+	return b.String()
+}
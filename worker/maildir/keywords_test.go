@@ -0,0 +1,93 @@
+package maildir
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/emersion/go-maildir"
+)
+
+func TestKeywords(t *testing.T) {
+	root := t.TempDir()
+	c, err := NewContainer(root, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inbox := maildir.Dir(filepath.Join(root, "INBOX"))
+	archive := maildir.Dir(filepath.Join(root, "Archive"))
+	for _, d := range []maildir.Dir{inbox, archive} {
+		if err := d.Init(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = os.WriteFile(filepath.Join(string(inbox), keywordsFile),
+		[]byte("0 $Forwarded\n1 work\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(string(archive), keywordsFile),
+		[]byte("0 work\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, wc, err := inbox.Create([]maildir.Flag{maildir.FlagSeen, 'b'})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(wc, testMessage); err != nil {
+		t.Fatal(err)
+	}
+	wc.Close()
+	uids, err := c.UIDs(inbox)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := c.Message(inbox, uids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	labels, err := m.Labels()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(labels, []string{"work"}) {
+		t.Errorf("unexpected labels %v", labels)
+	}
+
+	if err := m.ModifyLabels([]string{"urgent"}, []string{"work"}); err != nil {
+		t.Fatal(err)
+	}
+	flags, _ := m.Flags()
+	if string(flags) != "Sc" {
+		t.Errorf("unexpected flags %q", string(flags))
+	}
+	content, _ := os.ReadFile(filepath.Join(string(inbox), keywordsFile))
+	if !strings.Contains(string(content), "2 urgent\n") {
+		t.Errorf("keyword not saved: %q", content)
+	}
+
+	if err := m.ModifyLabels([]string{"work"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.MoveAll(archive, inbox, uids); err != nil {
+		t.Fatal(err)
+	}
+	moved := &Message{dir: archive, key: key, keywords: c.Keywords(archive)}
+	flags, err = moved.Flags()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(flags) != "Sab" {
+		t.Errorf("unexpected flags after move %q", string(flags))
+	}
+	labels, _ = moved.Labels()
+	if !reflect.DeepEqual(labels, []string{"work", "urgent"}) {
+		t.Errorf("unexpected labels after move %v", labels)
+	}
+}
@@ -12,9 +12,10 @@ import (
 
 // A Message is an individual email inside of a maildir.Dir.
 type Message struct {
-	dir maildir.Dir
-	uid uint32
-	key string
+	dir      maildir.Dir
+	uid      uint32
+	key      string
+	keywords *keywords
 }
 
 // NewReader reads a message into memory and returns an io.Reader for it.
@@ -101,6 +102,30 @@ func (m Message) UID() uint32 {
 	return m.uid
 }
 
+// Labels returns the Dovecot keywords of the message.
 func (m Message) Labels() ([]string, error) {
-	return nil, nil
+	if m.keywords == nil {
+		return nil, nil
+	}
+	flags, err := m.Flags()
+	if err != nil {
+		return nil, err
+	}
+	return m.keywords.Labels(flags)
+}
+
+// ModifyLabels adds and removes Dovecot keywords from the message.
+func (m Message) ModifyLabels(add, remove []string) error {
+	if m.keywords == nil {
+		return fmt.Errorf("keywords not available")
+	}
+	flags, err := m.Flags()
+	if err != nil {
+		return fmt.Errorf("could not read previous flags: %w", err)
+	}
+	flags, err = modifyKeywords(m.keywords, flags, add, remove)
+	if err != nil {
+		return err
+	}
+	return m.SetFlags(flags)
 }
@@ -163,14 +163,14 @@ func (w *Worker) searchKey(key uint32, criteria *searchCriteria,
 		}
 	}
 	if parts&HEADER > 0 || parts&DATE > 0 {
-		header, err = message.MessageInfo()
+		header, err = w.messageInfo(message)
 		if err != nil {
 			return false, err
 		}
 	}
 	if parts&BODY > 0 {
 		// TODO: select which part to search, maybe look for text/plain
-		mi, err := message.MessageInfo()
+		mi, err := w.messageInfo(message)
 		if err != nil {
 			return false, err
 		}
@@ -12,6 +12,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -42,7 +43,8 @@ type Worker struct {
 	worker              *types.Worker
 	watcher             *fsnotify.Watcher
 	currentSortCriteria []*types.SortCriterion
-	maildirpp           bool // whether to use Maildir++ directory layout
+	maildirpp           bool         // whether to use Maildir++ directory layout
+	index               *headerIndex // nil unless cache-headers is enabled
 }
 
 // NewWorker creates a new maildir worker with the provided worker.
@@ -113,6 +115,9 @@ func (w *Worker) handleFSEvent(ev fsnotify.Event) {
 	if w.selected == nil {
 		return
 	}
+	if w.index != nil && filepath.Base(filepath.Dir(ev.Name)) == "cur" {
+		w.updateIndex(ev)
+	}
 	err := w.c.SyncNewMail(*w.selected)
 	if err != nil {
 		log.Errorf("could not move new to cur : %v", err)
@@ -294,6 +299,8 @@ func (w *Worker) handleMessage(msg types.WorkerMessage) error {
 		return w.handleAppendMessage(msg)
 	case *types.SearchDirectory:
 		return w.handleSearchDirectory(msg)
+	case *types.ModifyLabels:
+		return w.handleModifyLabels(msg)
 	}
 	return errUnsupported
 }
@@ -322,6 +329,19 @@ func (w *Worker) handleConfigure(msg *types.Configure) error {
 	}
 	w.c = c
 	log.Debugf("configured base maildir: %s", dir)
+
+	if val, ok := msg.Config.Params["cache-headers"]; ok {
+		enabled, err := strconv.ParseBool(val)
+		if err != nil {
+			return fmt.Errorf("invalid cache-headers value %v: %w", val, err)
+		}
+		if enabled && w.index == nil {
+			w.index, err = openHeaderIndex(msg.Config.Name)
+			if err != nil {
+				log.Errorf("failed opening maildir index: %v", err)
+			}
+		}
+	}
 	return nil
 }
 
@@ -354,6 +374,7 @@ func (w *Worker) handleListDirectories(msg *types.ListDirectories) error {
 			Info: w.getDirectoryInfo(name),
 		}, nil)
 	}
+	w.emitLabelList(dirs)
 	return nil
 }
 
@@ -395,6 +416,14 @@ func (w *Worker) handleOpenDirectory(msg *types.OpenDirectory) error {
 		return fmt.Errorf("could not clean directory: %w", err)
 	}
 
+	if w.index != nil {
+		keys, err := dir.Keys()
+		if err != nil {
+			return err
+		}
+		go w.index.prune(msg.Directory, keys)
+	}
+
 	info := &types.DirectoryInfo{
 		Info: w.getDirectoryInfo(msg.Directory),
 	}
@@ -687,7 +716,7 @@ func (w *Worker) handleAnsweredMessages(msg *types.AnsweredMessages) error {
 			w.err(msg, err)
 			continue
 		}
-		info, err := m.MessageInfo()
+		info, err := w.messageInfo(m)
 		if err != nil {
 			log.Errorf("could not get message info: %v", err)
 			w.err(msg, err)
@@ -720,7 +749,7 @@ func (w *Worker) handleFlagMessages(msg *types.FlagMessages) error {
 			w.err(msg, err)
 			continue
 		}
-		info, err := m.MessageInfo()
+		info, err := w.messageInfo(m)
 		if err != nil {
 			log.Errorf("could not get message info: %v", err)
 			w.err(msg, err)
@@ -791,6 +820,56 @@ func (w *Worker) handleAppendMessage(msg *types.AppendMessage) error {
 	return nil
 }
 
+func (w *Worker) handleModifyLabels(msg *types.ModifyLabels) error {
+	for _, uid := range msg.Uids {
+		m, err := w.c.Message(*w.selected, uid)
+		if err != nil {
+			return fmt.Errorf("could not get message from uid %d: %w", uid, err)
+		}
+		if err := m.ModifyLabels(msg.Add, msg.Remove); err != nil {
+			return fmt.Errorf("could not modify message labels: %w", err)
+		}
+		info, err := w.msgInfoFromUid(uid)
+		if err != nil {
+			return err
+		}
+		w.worker.PostMessage(&types.MessageInfo{
+			Message: types.RespondTo(msg),
+			Info:    info,
+		}, nil)
+	}
+	if len(msg.Add) > 0 {
+		// new keywords may have been defined
+		dirs, err := w.c.Store.FolderMap()
+		if err != nil {
+			return err
+		}
+		w.emitLabelList(dirs)
+	}
+	return nil
+}
+
+// emitLabelList sends the Dovecot keywords defined in all maildirs.
+func (w *Worker) emitLabelList(dirs map[string]maildir.Dir) {
+	seen := make(map[string]bool)
+	var labels []string
+	for _, dir := range dirs {
+		names, err := w.c.Keywords(dir).All()
+		if err != nil {
+			log.Errorf("could not read keywords of %s: %v", dir, err)
+			continue
+		}
+		for _, name := range names {
+			if !seen[name] {
+				seen[name] = true
+				labels = append(labels, name)
+			}
+		}
+	}
+	sort.Strings(labels)
+	w.worker.PostMessage(&types.LabelList{Labels: labels}, nil)
+}
+
 func (w *Worker) handleSearchDirectory(msg *types.SearchDirectory) error {
 	log.Debugf("Searching directory %v with args: %v", *w.selected, msg.Argv)
 	criteria, err := parseSearch(msg.Argv)
@@ -814,7 +893,7 @@ func (w *Worker) msgInfoFromUid(uid uint32) (*models.MessageInfo, error) {
 	if err != nil {
 		return nil, err
 	}
-	info, err := m.MessageInfo()
+	info, err := w.messageInfo(m)
 	if err != nil {
 		return nil, err
 	}
@@ -829,6 +908,10 @@ func (w *Worker) msgHeadersFromUid(uid uint32) (*models.MessageInfo, error) {
 	if err != nil {
 		return nil, err
 	}
+	if w.index != nil {
+		// the full info is indexed, it contains everything needed
+		return w.messageInfo(m)
+	}
 	info, err := m.MessageHeaders()
 	if err != nil {
 		return nil, err
@@ -836,6 +919,76 @@ func (w *Worker) msgHeadersFromUid(uid uint32) (*models.MessageInfo, error) {
 	return info, nil
 }
 
+// messageInfo returns the info of a message of the selected folder. If the
+// header index is enabled, it is used instead of parsing the file when
+// possible.
+func (w *Worker) messageInfo(m *Message) (*models.MessageInfo, error) {
+	if w.index == nil {
+		return m.MessageInfo()
+	}
+	path, err := m.dir.Filename(m.key)
+	if err != nil {
+		return nil, err
+	}
+	st, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info := w.index.get(w.selectedName, m.key, st); info != nil {
+		// the flags are encoded in the file name
+		if _, flags, err := splitMaildirFile(filepath.Base(path)); err == nil {
+			info.Flags = lib.FromMaildirFlags(flags)
+		} else if info.Flags, err = m.ModelFlags(); err != nil {
+			return nil, err
+		}
+		if info.Labels, err = m.Labels(); err != nil || info.Labels == nil {
+			info.Labels = []string{}
+		}
+		info.Uid = m.uid
+		return info, nil
+	}
+	info, err := m.MessageInfo()
+	if err != nil {
+		return nil, err
+	}
+	w.index.put(w.selectedName, m.key, st, info)
+	return info, nil
+}
+
+// updateIndex keeps the header index of the selected folder up to date with
+// the changes made to its cur directory by other programs.
+func (w *Worker) updateIndex(ev fsnotify.Event) {
+	name := filepath.Base(ev.Name)
+	key := name
+	if i := strings.LastIndexByte(name, ':'); i >= 0 {
+		key = name[:i]
+	}
+	switch ev.Op {
+	case fsnotify.Create:
+		st, err := os.Stat(ev.Name)
+		if err != nil || !st.Mode().IsRegular() {
+			return
+		}
+		if w.index.get(w.selectedName, key, st) != nil {
+			// flags were changed, the file was only renamed
+			return
+		}
+		m := &Message{dir: *w.selected, key: key}
+		info, err := m.MessageInfo()
+		if err != nil {
+			log.Errorf("could not index %s: %v", ev.Name, err)
+			return
+		}
+		w.index.put(w.selectedName, key, st, info)
+	case fsnotify.Remove, fsnotify.Rename:
+		if _, err := w.selected.Filename(key); err == nil {
+			// renamed within the directory
+			return
+		}
+		w.index.remove(w.selectedName, key)
+	}
+}
+
 func (w *Worker) handleCheckMail(msg *types.CheckMail) {
 	defer log.PanicHandler()
 	if msg.Command == "" {
@@ -0,0 +1,75 @@
+package mboxer
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"git.sr.ht/~rjarry/aerc/models"
+	"git.sr.ht/~rjarry/aerc/worker/lib"
+)
+
+func TestCompressedContainer(t *testing.T) {
+	for _, c := range lib.Codecs {
+		t.Run(c.Name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "archive.mbox"+c.Extension)
+			w, err := lib.CreateFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := io.WriteString(w, testMbox); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			f := &container{filename: path, codec: lib.CodecFor(path)}
+			if err := f.load(); err != nil {
+				t.Fatal(err)
+			}
+			if len(f.messages) != 2 {
+				t.Fatalf("expected 2 messages, got %d", len(f.messages))
+			}
+
+			msg := "From: Carol <carol@example.com>\nSubject: third\n\nHi\n"
+			if err := f.Append(strings.NewReader(msg), models.SeenFlag); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := f.Delete([]uint32{1}); err != nil {
+				t.Fatal(err)
+			}
+			if err := f.Append(strings.NewReader(msg), 0); err != nil {
+				t.Fatal(err)
+			}
+
+			r, err := lib.OpenFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			messages, err := Read(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(messages) != 3 {
+				t.Fatalf("expected 3 messages, got %d", len(messages))
+			}
+			for i, m := range messages {
+				expected, _ := io.ReadAll(must(f.messages[i].NewReader()))
+				actual, _ := io.ReadAll(must(m.NewReader()))
+				if string(expected) != string(actual) {
+					t.Errorf("%d: expected %q, got %q", i, expected, actual)
+				}
+			}
+		})
+	}
+}
+
+func must(r io.ReadCloser, err error) io.ReadCloser {
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
@@ -1,56 +1,56 @@
 package mboxer
 
 import (
-	"io"
 	"os"
 	"path/filepath"
 	"strings"
+
+	"git.sr.ht/~rjarry/aerc/worker/lib"
 )
 
-func createMailboxContainer(path string) (*mailboxContainer, error) {
-	file, err := os.Open(path)
+func createMailboxContainer(path string, index bool) (*mailboxContainer, error) {
+	fileInfo, err := os.Stat(path)
 	if err != nil {
 		return nil, err
 	}
 
-	defer file.Close()
-
-	fileInfo, err := file.Stat()
-	if err != nil {
-		return nil, err
+	mbdata := &mailboxContainer{
+		mailboxes: make(map[string]*container),
+		index:     index,
 	}
 
-	mbdata := &mailboxContainer{mailboxes: make(map[string]*container)}
-
-	openMboxFile := func(path string, r io.Reader) error {
-		// read mbox file
-		messages, err := Read(r)
-		if err != nil {
+	openMboxFile := func(path string) error {
+		c := &container{filename: path, index: index, codec: lib.CodecFor(path)}
+		if err := c.load(); err != nil {
 			return err
 		}
 		_, name := filepath.Split(path)
-		name = strings.TrimSuffix(name, ".mbox")
-		mbdata.mailboxes[name] = &container{filename: path, messages: messages}
+		name = strings.TrimSuffix(lib.TrimExtension(name), ".mbox")
+		mbdata.mailboxes[name] = c
 		return nil
 	}
 
 	if fileInfo.IsDir() {
+		mbdata.dir = path
 		files, err := filepath.Glob(filepath.Join(path, "*.mbox"))
 		if err != nil {
 			return nil, err
 		}
-		for _, file := range files {
-			f, err := os.Open(file)
+		for _, c := range lib.Codecs {
+			compressed, err := filepath.Glob(
+				filepath.Join(path, "*.mbox"+c.Extension))
 			if err != nil {
-				continue
+				return nil, err
 			}
-			if err := openMboxFile(file, f); err != nil {
+			files = append(files, compressed...)
+		}
+		for _, file := range files {
+			if err := openMboxFile(file); err != nil {
 				return nil, err
 			}
-			f.Close()
 		}
 	} else {
-		if err := openMboxFile(path, file); err != nil {
+		if err := openMboxFile(path); err != nil {
 			return nil, err
 		}
 	}
@@ -0,0 +1,122 @@
+package mboxer
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"git.sr.ht/~rjarry/aerc/log"
+	"github.com/kyoh86/xdg"
+)
+
+// bumped whenever the index format changes
+const indexVersion = 1
+
+// mboxIndex records the location and headers of all messages of an mbox
+// file so that it does not need to be scanned again when it is unchanged.
+type mboxIndex struct {
+	Version int
+	Size    int64
+	ModTime time.Time
+	Entries []indexEntry
+}
+
+type indexEntry struct {
+	From   string
+	Header []byte
+	Offset int64
+	Length int64
+}
+
+// indexPath returns the location of the index of an mbox file in the cache
+// directory.
+func indexPath(filename string) (string, error) {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(abs))
+	return filepath.Join(xdg.CacheHome(), "aerc", "mbox",
+		fmt.Sprintf("%x.idx", sum[:16])), nil
+}
+
+// loadIndex returns the indexed messages, or nil if there is no index or if
+// it does not match the size and modification time of the file.
+func loadIndex(filename string, st os.FileInfo) []*message {
+	path, err := indexPath(filename)
+	if err != nil {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var idx mboxIndex
+	if err := gob.NewDecoder(f).Decode(&idx); err != nil {
+		log.Debugf("mbox: invalid index %s: %v", path, err)
+		return nil
+	}
+	if idx.Version != indexVersion || idx.Size != st.Size() ||
+		!idx.ModTime.Equal(st.ModTime()) {
+		return nil
+	}
+	messages := make([]*message, 0, len(idx.Entries))
+	for i, e := range idx.Entries {
+		messages = append(messages, &message{
+			uid:    uint32(i),
+			flags:  statusFlags(e.Header),
+			from:   e.From,
+			header: e.Header,
+			offset: e.Offset,
+			length: e.Length,
+		})
+	}
+	log.Debugf("mbox: loaded %d messages from index %s", len(messages), path)
+	return messages
+}
+
+// saveIndex writes the index of an mbox file.
+func saveIndex(filename string, st os.FileInfo, messages []*message) error {
+	path, err := indexPath(filename)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	idx := mboxIndex{
+		Version: indexVersion,
+		Size:    st.Size(),
+		ModTime: st.ModTime(),
+		Entries: make([]indexEntry, 0, len(messages)),
+	}
+	for _, m := range messages {
+		if m.body != nil {
+			// not stored in the file
+			return nil
+		}
+		idx.Entries = append(idx.Entries, indexEntry{
+			From:   m.from,
+			Header: m.header,
+			Offset: m.offset,
+			Length: m.length,
+		})
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".idx.*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := gob.NewEncoder(tmp).Encode(&idx); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
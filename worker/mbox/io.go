@@ -1,8 +1,13 @@
 package mboxer
 
 import (
+	"bufio"
+	"bytes"
 	"errors"
+	"fmt"
 	"io"
+	"net/mail"
+	"strings"
 	"time"
 
 	"git.sr.ht/~rjarry/aerc/models"
@@ -10,32 +15,171 @@ import (
 	"github.com/emersion/go-mbox"
 )
 
+var (
+	fromLine        = []byte("From ")
+	escapedFromLine = []byte(">From ")
+)
+
+// Read parses all messages from an mbox stream and keeps them in memory. The
+// "From " separator lines are kept so that they can be written back
+// unchanged. The message flags are read from the Status and X-Status headers.
 func Read(r io.Reader) ([]lib.RawMessage, error) {
-	mbr := mbox.NewReader(r)
-	uid := uint32(0)
-	messages := make([]lib.RawMessage, 0)
-	for {
-		msg, err := mbr.NextMessage()
-		if errors.Is(err, io.EOF) {
-			break
-		} else if err != nil {
-			return nil, err
+	messages, err := scanMbox(r, true)
+	if err != nil {
+		return nil, err
+	}
+	raw := make([]lib.RawMessage, 0, len(messages))
+	for _, m := range messages {
+		raw = append(raw, m)
+	}
+	return raw, nil
+}
+
+// scanMbox reads an mbox stream in a single pass. The message headers are
+// always kept in memory, the bodies only if keepBody is true. Otherwise, only
+// their location in the stream is recorded so that they can be read later.
+func scanMbox(r io.Reader, keepBody bool) ([]*message, error) {
+	br := bufio.NewReader(r)
+	messages := make([]*message, 0)
+	var cur *message
+	var header, body bytes.Buffer
+	inHeader := false
+	// offset of the current line in the stream
+	var offset int64
+	// empty line which may be a message separator
+	var blank []byte
+	var blankOffset int64
+	// false if the previous line was too long to fit in the buffer
+	lineStart := true
+
+	finish := func(end int64) {
+		if cur == nil {
+			return
+		}
+		cur.header = append([]byte(nil), header.Bytes()...)
+		if keepBody {
+			cur.body = append([]byte(nil), body.Bytes()...)
 		}
+		if inHeader {
+			cur.offset = end
+		}
+		cur.length = end - cur.offset
+		cur.flags = statusFlags(cur.header)
+		messages = append(messages, cur)
+		header.Reset()
+		body.Reset()
+	}
 
-		content, err := io.ReadAll(msg)
-		if err != nil {
+	for {
+		line, err := br.ReadSlice('\n')
+		if err != nil && !errors.Is(err, bufio.ErrBufferFull) && !errors.Is(err, io.EOF) {
 			return nil, err
 		}
+		start := offset
+		offset += int64(len(line))
+		atStart := lineStart
+		lineStart = len(line) > 0 && line[len(line)-1] == '\n'
 
-		messages = append(messages, &message{
-			uid: uid, flags: models.SeenFlag, content: content,
-		})
+		switch {
+		case len(line) == 0:
+		case atStart && bytes.HasPrefix(line, fromLine) && (cur == nil || blank != nil):
+			if blank != nil {
+				finish(blankOffset)
+			} else {
+				finish(start)
+			}
+			from := line[len(fromLine):]
+			for errors.Is(err, bufio.ErrBufferFull) {
+				// discard the rest of an overlong separator line
+				line, err = br.ReadSlice('\n')
+				offset += int64(len(line))
+				lineStart = true
+			}
+			cur = &message{
+				uid:  uint32(len(messages)),
+				from: string(bytes.TrimRight(from, "\r\n")),
+			}
+			blank = nil
+			inHeader = true
+		case cur == nil:
+			if len(bytes.TrimSpace(line)) > 0 {
+				return nil, mbox.ErrInvalidFormat
+			}
+		case inHeader:
+			if atStart && bytes.HasPrefix(line, escapedFromLine) {
+				line = line[1:]
+			}
+			header.Write(line)
+			if atStart && isBlank(line) {
+				inHeader = false
+				cur.offset = offset
+				// a message with an empty body may be directly
+				// followed by the next one
+				blank = []byte{}
+				blankOffset = offset
+			}
+		default:
+			if blank != nil && keepBody {
+				body.Write(blank)
+			}
+			blank = nil
+			if atStart && isBlank(line) {
+				blank = append(blank[:0], line...)
+				blankOffset = start
+				break
+			}
+			if keepBody {
+				if atStart && bytes.HasPrefix(line, escapedFromLine) {
+					line = line[1:]
+				}
+				body.Write(line)
+			}
+		}
 
-		uid++
+		if errors.Is(err, io.EOF) {
+			break
+		}
+	}
+	if blank != nil {
+		finish(blankOffset)
+	} else {
+		finish(offset)
 	}
 	return messages, nil
 }
 
+func isBlank(line []byte) bool {
+	return len(line) == 1 || (len(line) == 2 && line[0] == '\r')
+}
+
+// unescapeReader removes the > character from escaped "From " lines.
+type unescapeReader struct {
+	r         *bufio.Reader
+	buf       []byte
+	lineStart bool
+}
+
+func newUnescapeReader(r io.Reader) io.Reader {
+	return &unescapeReader{r: bufio.NewReader(r), lineStart: true}
+}
+
+func (u *unescapeReader) Read(p []byte) (int, error) {
+	if len(u.buf) == 0 {
+		line, err := u.r.ReadSlice('\n')
+		if len(line) == 0 {
+			return 0, err
+		}
+		if u.lineStart && bytes.HasPrefix(line, escapedFromLine) {
+			line = line[1:]
+		}
+		u.lineStart = line[len(line)-1] == '\n'
+		u.buf = line
+	}
+	n := copy(p, u.buf)
+	u.buf = u.buf[n:]
+	return n, nil
+}
+
 func Write(w io.Writer, reader io.Reader, from string, date time.Time) error {
 	wc := mbox.NewWriter(w)
 	mw, err := wc.CreateMessage(from, time.Now())
@@ -48,3 +192,259 @@ func Write(w io.Writer, reader io.Reader, from string, date time.Time) error {
 	}
 	return wc.Close()
 }
+
+// countingWriter keeps track of the number of bytes written.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// writeMessage writes a message in mbox format with up-to-date Status and
+// X-Status headers. Lines starting with "From " are escaped, unless the body
+// is copied from an mbox file in which case it is already escaped. It returns
+// the new header, and the location of the body relative to the start of the
+// message.
+func writeMessage(w io.Writer, m *message) ([]byte, int64, int64, error) {
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	if m.from == "" {
+		m.from = fmt.Sprintf("%s %s", envelopeSender(m.header),
+			time.Now().UTC().Format(time.ANSIC))
+	}
+	if _, err := fmt.Fprintf(bw, "From %s\n", m.from); err != nil {
+		return nil, 0, 0, err
+	}
+	header := setStatus(m.header, m.flags)
+	if !bytes.HasSuffix(header, []byte("\n\n")) &&
+		!bytes.HasSuffix(header, []byte("\r\n\r\n")) {
+		header = append(header, '\n')
+	}
+	if err := writeEscaped(bw, header); err != nil {
+		return nil, 0, 0, err
+	}
+	if err := bw.Flush(); err != nil {
+		return nil, 0, 0, err
+	}
+	offset := cw.n
+
+	var err error
+	if m.body != nil || m.src == nil {
+		err = writeEscaped(bw, m.body)
+	} else {
+		var tail []byte
+		tail, err = copyTail(bw, io.NewSectionReader(m.src, m.offset, m.length))
+		if err == nil && len(tail) > 0 && tail[len(tail)-1] != '\n' {
+			err = bw.WriteByte('\n')
+		}
+	}
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if err := bw.Flush(); err != nil {
+		return nil, 0, 0, err
+	}
+	length := cw.n - offset
+	// messages are separated by an empty line
+	if _, err := cw.Write([]byte("\n")); err != nil {
+		return nil, 0, 0, err
+	}
+	return header, offset, length, nil
+}
+
+// escaped returns content as written with writeEscaped.
+func escaped(content []byte) []byte {
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	_ = writeEscaped(w, content)
+	_ = w.Flush()
+	return buf.Bytes()
+}
+
+// writeEscaped writes content, escaping lines which start with "From ". A
+// final new line is added if missing.
+func writeEscaped(w *bufio.Writer, content []byte) error {
+	for len(content) > 0 {
+		var line []byte
+		if i := bytes.IndexByte(content, '\n'); i >= 0 {
+			line, content = content[:i+1], content[i+1:]
+		} else {
+			line, content = content, nil
+		}
+		if bytes.HasPrefix(line, fromLine) {
+			if err := w.WriteByte('>'); err != nil {
+				return err
+			}
+		}
+		if _, err := w.Write(line); err != nil {
+			return err
+		}
+		if content == nil && line[len(line)-1] != '\n' {
+			if err := w.WriteByte('\n'); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// copyTail copies r to w and returns the last bytes which were copied.
+func copyTail(w io.Writer, r io.Reader) ([]byte, error) {
+	buf := make([]byte, 32*1024)
+	var last []byte
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return nil, werr
+			}
+			last = buf[n-1 : n]
+		}
+		if errors.Is(err, io.EOF) {
+			return last, nil
+		} else if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// envelopeSender returns the address to use in a generated "From " line.
+func envelopeSender(content []byte) string {
+	for _, key := range []string{"Return-Path", "From"} {
+		value := headerValue(content, key)
+		if value == "" {
+			continue
+		}
+		if addr, err := mail.ParseAddress(value); err == nil {
+			return addr.Address
+		}
+	}
+	return "MAILER-DAEMON"
+}
+
+// headerLines splits the message header into lines, keeping the line
+// endings. It stops at the first empty line.
+func headerLines(content []byte) [][]byte {
+	var lines [][]byte
+	for len(content) > 0 {
+		i := bytes.IndexByte(content, '\n')
+		if i < 0 {
+			i = len(content) - 1
+		}
+		line := content[:i+1]
+		if isBlank(line) {
+			break
+		}
+		lines = append(lines, line)
+		content = content[i+1:]
+	}
+	return lines
+}
+
+// headerKey returns the field name of a header line, or an empty string if
+// the line is a continuation line.
+func headerKey(line []byte) string {
+	if len(line) > 0 && (line[0] == ' ' || line[0] == '\t') {
+		return ""
+	}
+	if i := bytes.IndexByte(line, ':'); i > 0 {
+		return string(bytes.TrimSpace(line[:i]))
+	}
+	return ""
+}
+
+func headerValue(content []byte, key string) string {
+	for _, line := range headerLines(content) {
+		if strings.EqualFold(headerKey(line), key) {
+			i := bytes.IndexByte(line, ':')
+			return string(bytes.TrimSpace(line[i+1:]))
+		}
+	}
+	return ""
+}
+
+// statusFlags converts the Status and X-Status headers to message flags.
+// Messages without the O (old) status are recent.
+func statusFlags(content []byte) models.Flags {
+	var flags models.Flags
+	status := headerValue(content, "Status")
+	xstatus := headerValue(content, "X-Status")
+	if strings.ContainsRune(status, 'R') {
+		flags |= models.SeenFlag
+	}
+	if !strings.ContainsRune(status, 'O') {
+		flags |= models.RecentFlag
+	}
+	if strings.ContainsRune(xstatus, 'A') {
+		flags |= models.AnsweredFlag
+	}
+	if strings.ContainsRune(xstatus, 'F') {
+		flags |= models.FlaggedFlag
+	}
+	if strings.ContainsRune(xstatus, 'D') {
+		flags |= models.DeletedFlag
+	}
+	return flags
+}
+
+// Width of the values of the Status and X-Status headers. The values are
+// padded with spaces so that flag changes do not change the size of the
+// header, which can then be updated in place.
+const (
+	statusWidth  = 2
+	xstatusWidth = 3
+)
+
+// setStatus returns the message content with the Status and X-Status headers
+// replaced according to flags.
+func setStatus(content []byte, flags models.Flags) []byte {
+	lines := headerLines(content)
+	eol := "\n"
+	if len(lines) > 0 && bytes.HasSuffix(lines[0], []byte("\r\n")) {
+		eol = "\r\n"
+	}
+	var status, xstatus string
+	if flags.Has(models.SeenFlag) {
+		status += "R"
+	}
+	if !flags.Has(models.RecentFlag) {
+		status += "O"
+	}
+	if flags.Has(models.AnsweredFlag) {
+		xstatus += "A"
+	}
+	if flags.Has(models.FlaggedFlag) {
+		xstatus += "F"
+	}
+	if flags.Has(models.DeletedFlag) {
+		xstatus += "D"
+	}
+
+	var buf bytes.Buffer
+	size := 0
+	skip := false
+	for _, line := range lines {
+		size += len(line)
+		key := headerKey(line)
+		if key != "" {
+			skip = strings.EqualFold(key, "Status") ||
+				strings.EqualFold(key, "X-Status")
+		}
+		if skip {
+			continue
+		}
+		buf.Write(line)
+	}
+	if len(lines) > 0 && !bytes.HasSuffix(lines[len(lines)-1], []byte("\n")) {
+		buf.WriteString(eol)
+	}
+	fmt.Fprintf(&buf, "Status: %-*s%s", statusWidth, status, eol)
+	fmt.Fprintf(&buf, "X-Status: %-*s%s", xstatusWidth, xstatus, eol)
+	buf.Write(content[size:])
+	return buf.Bytes()
+}
@@ -0,0 +1,319 @@
+package mboxer
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"git.sr.ht/~rjarry/aerc/models"
+)
+
+const testMbox = `From alice@example.com Mon Jan  2 15:04:05 2023
+From: Alice <alice@example.com>
+Subject: first
+Status: RO
+X-Status:    
+
+Hello
+>From the other side
+
+From bob@example.com Tue Jan  3 15:04:05 2023
+From: Bob <bob@example.com>
+Subject: second
+Status:   
+X-Status: F  
+
+Bye
+
+`
+
+func TestReadWrite(t *testing.T) {
+	messages, err := Read(strings.NewReader(testMbox))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(messages))
+	}
+	first := messages[0].(*message)
+	if first.flags != models.SeenFlag {
+		t.Errorf("first: unexpected flags %v", first.flags)
+	}
+	if !bytes.Equal(first.body, []byte("Hello\nFrom the other side\n")) {
+		t.Errorf("first: unexpected body: %q", first.body)
+	}
+	second := messages[1].(*message)
+	if second.flags != models.RecentFlag|models.FlaggedFlag {
+		t.Errorf("second: unexpected flags %v", second.flags)
+	}
+
+	var buf bytes.Buffer
+	for _, m := range messages {
+		if _, _, _, err := writeMessage(&buf, m.(*message)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if buf.String() != testMbox {
+		t.Errorf("round trip failed:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	second.flags = models.SeenFlag | models.AnsweredFlag
+	if _, _, _, err := writeMessage(&buf, second); err != nil {
+		t.Fatal(err)
+	}
+	expected := `From bob@example.com Tue Jan  3 15:04:05 2023
+From: Bob <bob@example.com>
+Subject: second
+Status: RO
+X-Status: A  
+
+Bye
+
+`
+	if buf.String() != expected {
+		t.Errorf("unexpected status update:\n%s", buf.String())
+	}
+}
+
+func TestContainerSync(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "INBOX.mbox")
+	if err := os.WriteFile(path, []byte(testMbox), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c := &container{filename: path}
+	if err := c.load(); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := "From: Carol <carol@example.com>\nSubject: third\n\nFrom here\n"
+	if err := c.Append(strings.NewReader(msg), models.SeenFlag); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Delete([]uint32{0}); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded := &container{filename: path}
+	if err := reloaded.load(); err != nil {
+		t.Fatal(err)
+	}
+	if len(reloaded.messages) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(reloaded.messages))
+	}
+	third := reloaded.messages[1]
+	if !strings.HasPrefix(third.from, "carol@example.com ") {
+		t.Errorf("unexpected From line: %q", third.from)
+	}
+	if third.flags != models.SeenFlag {
+		t.Errorf("unexpected flags: %v", third.flags)
+	}
+	r, err := third.NewReader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasSuffix(content, []byte("Status: RO\nX-Status:    \n\nFrom here\n")) {
+		t.Errorf("unexpected content: %q", content)
+	}
+
+	// external modification
+	if err := os.WriteFile(path, []byte(testMbox), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if !c.Modified() {
+		t.Errorf("modification not detected")
+	}
+	if err := c.Sync(); err != errModified {
+		t.Errorf("expected errModified, got %v", err)
+	}
+	if err := c.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.messages) != 2 || c.Modified() {
+		t.Errorf("reload failed")
+	}
+}
+
+func TestContainerFlags(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "INBOX.mbox")
+	if err := os.WriteFile(path, []byte(testMbox), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c := &container{filename: path}
+	if err := c.load(); err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// padded headers are updated in place
+	messages, err := c.SetFlags([]uint32{1}, models.SeenFlag, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flags := models.SeenFlag | models.RecentFlag | models.FlaggedFlag
+	if len(messages) != 1 || messages[0].flags != flags {
+		t.Fatalf("unexpected messages: %v", messages)
+	}
+	after, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !os.SameFile(before, after) {
+		t.Errorf("file was replaced")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := strings.Replace(testMbox,
+		"Status:   \nX-Status: F  \n", "Status: R \nX-Status: F  \n", 1)
+	if string(data) != expected {
+		t.Errorf("unexpected content:\n%s", data)
+	}
+
+	// headers without padding cause the file to be rewritten
+	unpadded := "From a@example.com Mon Jan  2 15:04:05 2023\n" +
+		"Subject: new\n\nBody\n\n"
+	if err := os.WriteFile(path, []byte(unpadded), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	uid := c.messages[0].uid
+	if _, err := c.SetFlags([]uint32{uid}, models.SeenFlag, true); err != nil {
+		t.Fatal(err)
+	}
+	data, err = os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "Status: R \nX-Status:    \n\nBody\n") {
+		t.Errorf("unexpected content:\n%s", data)
+	}
+
+	// nothing is changed in memory if the file cannot be written
+	if err := os.WriteFile(path, []byte(testMbox), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.SetFlags([]uint32{uid}, models.FlaggedFlag, true); err != errModified {
+		t.Errorf("expected errModified, got %v", err)
+	}
+	if c.messages[0].flags.Has(models.FlaggedFlag) {
+		t.Errorf("flags changed in memory")
+	}
+	if _, err := c.Delete([]uint32{uid}); err != errModified {
+		t.Errorf("expected errModified, got %v", err)
+	}
+	if len(c.messages) != 1 {
+		t.Errorf("message deleted from memory")
+	}
+}
+
+func TestContainerLockedClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "INBOX.mbox")
+	if err := os.WriteFile(path, []byte(testMbox), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c := &container{filename: path}
+	if err := c.load(); err != nil {
+		t.Fatal(err)
+	}
+	old := c.file
+	lock, err := c.lock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.setFile(reader)
+	// closing old would release the lock of the mbox file
+	if _, err := old.Stat(); err != nil {
+		t.Errorf("file closed while locked: %v", err)
+	}
+	c.unlock(lock)
+	if _, err := old.Stat(); err == nil {
+		t.Errorf("file not closed after unlock")
+	}
+	c.setFile(nil)
+	if _, err := reader.Stat(); err == nil {
+		t.Errorf("file not closed")
+	}
+}
+
+func TestScanOffsets(t *testing.T) {
+	messages, err := scanMbox(strings.NewReader(testMbox), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(messages))
+	}
+	bodies := []string{"Hello\n>From the other side\n", "Bye\n"}
+	for i, m := range messages {
+		if m.body != nil {
+			t.Errorf("%d: body should not be kept in memory", i)
+		}
+		body := testMbox[m.offset : m.offset+m.length]
+		if body != bodies[i] {
+			t.Errorf("%d: unexpected body %q", i, body)
+		}
+		m.src = strings.NewReader(testMbox)
+		r, _ := m.NewReader()
+		content, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(content, []byte(">From")) {
+			t.Errorf("%d: from line not unescaped: %q", i, content)
+		}
+	}
+}
+
+func TestIndex(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "list.mbox")
+	if err := os.WriteFile(path, []byte(testMbox), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c := &container{filename: path, index: true}
+	if err := c.load(); err != nil {
+		t.Fatal(err)
+	}
+	st, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	indexed := loadIndex(path, st)
+	if len(indexed) != 2 {
+		t.Fatalf("expected 2 indexed messages, got %d", len(indexed))
+	}
+	for i, m := range indexed {
+		if m.offset != c.messages[i].offset || m.length != c.messages[i].length ||
+			m.flags != c.messages[i].flags {
+			t.Errorf("%d: index mismatch", i)
+		}
+	}
+	if err := os.WriteFile(path, []byte(testMbox+testMbox), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	st, err = os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loadIndex(path, st) != nil {
+		t.Errorf("stale index should be ignored")
+	}
+}
@@ -0,0 +1,87 @@
+package mboxer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	// number of attempts to acquire a lock before giving up
+	lockRetries = 20
+	lockDelay   = 250 * time.Millisecond
+	// dot locks older than this are considered stale and removed
+	lockStale = 5 * time.Minute
+)
+
+// mboxLock holds a dot lock and an fcntl lock on an mbox file, as done by
+// most MDAs and MUAs which deliver to or read from spool files.
+type mboxLock struct {
+	dotlock string
+	file    *os.File
+}
+
+// lockMbox locks the given mbox file. The file must exist.
+func lockMbox(path string) (*mboxLock, error) {
+	l := &mboxLock{dotlock: path + ".lock"}
+	if err := l.lockDot(); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		l.Unlock()
+		return nil, err
+	}
+	l.file = f
+	for i := 0; ; i++ {
+		err = fcntlLock(f)
+		if err == nil {
+			break
+		}
+		if i >= lockRetries {
+			l.Unlock()
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		time.Sleep(lockDelay)
+	}
+	return l, nil
+}
+
+func (l *mboxLock) lockDot() error {
+	for i := 0; ; i++ {
+		f, err := os.OpenFile(l.dotlock, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if err == nil {
+			return f.Close()
+		}
+		if !errors.Is(err, os.ErrExist) {
+			// the directory may not be writable, rely on fcntl only
+			if errors.Is(err, os.ErrPermission) {
+				l.dotlock = ""
+				return nil
+			}
+			return err
+		}
+		if st, err := os.Stat(l.dotlock); err == nil &&
+			time.Since(st.ModTime()) > lockStale {
+			_ = os.Remove(l.dotlock)
+			continue
+		}
+		if i >= lockRetries {
+			return fmt.Errorf("%s: already locked", l.dotlock)
+		}
+		time.Sleep(lockDelay)
+	}
+}
+
+func (l *mboxLock) Unlock() {
+	if l.file != nil {
+		_ = fcntlUnlock(l.file)
+		l.file.Close()
+		l.file = nil
+	}
+	if l.dotlock != "" {
+		_ = os.Remove(l.dotlock)
+		l.dotlock = ""
+	}
+}
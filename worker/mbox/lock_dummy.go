@@ -0,0 +1,14 @@
+//go:build !unix
+// +build !unix
+
+package mboxer
+
+import "os"
+
+func fcntlLock(f *os.File) error {
+	return nil
+}
+
+func fcntlUnlock(f *os.File) error {
+	return nil
+}
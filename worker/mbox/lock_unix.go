@@ -0,0 +1,24 @@
+//go:build unix
+// +build unix
+
+package mboxer
+
+import (
+	"io"
+	"os"
+	"syscall"
+)
+
+func fcntlLock(f *os.File) error {
+	return syscall.FcntlFlock(f.Fd(), syscall.F_SETLK, &syscall.Flock_t{
+		Type:   syscall.F_WRLCK,
+		Whence: io.SeekStart,
+	})
+}
+
+func fcntlUnlock(f *os.File) error {
+	return syscall.FcntlFlock(f.Fd(), syscall.F_SETLK, &syscall.Flock_t{
+		Type:   syscall.F_UNLCK,
+		Whence: io.SeekStart,
+	})
+}
@@ -2,15 +2,27 @@ package mboxer
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"time"
 
+	"git.sr.ht/~rjarry/aerc/log"
 	"git.sr.ht/~rjarry/aerc/models"
 	"git.sr.ht/~rjarry/aerc/worker/lib"
 )
 
+var errModified = errors.New("mbox file was modified by another program")
+
 type mailboxContainer struct {
 	mailboxes map[string]*container
+	// directory containing the mbox files, empty if a single file was
+	// configured
+	dir string
+	// persist the message indexes in the cache directory
+	index bool
 }
 
 func (md *mailboxContainer) Names() []string {
@@ -26,29 +38,51 @@ func (md *mailboxContainer) Mailbox(f string) (*container, bool) {
 	return mb, ok
 }
 
+// Create adds a new mailbox. When a directory of mbox files was configured,
+// the file is created on the first write. Otherwise, the mailbox only lives in
+// memory.
 func (md *mailboxContainer) Create(file string) *container {
-	md.mailboxes[file] = &container{filename: file}
+	var filename string
+	if md.dir != "" {
+		filename = filepath.Join(md.dir, file+".mbox")
+	}
+	md.mailboxes[file] = &container{filename: filename, index: md.index}
 	return md.mailboxes[file]
 }
 
 func (md *mailboxContainer) Remove(file string) error {
+	if mb, ok := md.mailboxes[file]; ok && mb.filename != "" {
+		err := os.Remove(mb.filename)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
 	delete(md.mailboxes, file)
 	return nil
 }
 
 func (md *mailboxContainer) DirectoryInfo(file string) *models.DirectoryInfo {
-	var exists int
+	var exists, recent, unseen int
 	if md, ok := md.Mailbox(file); ok {
-		exists = len(md.Uids())
+		exists = len(md.messages)
+		for _, m := range md.messages {
+			flags, _ := m.ModelFlags()
+			if flags.Has(models.RecentFlag) {
+				recent++
+			}
+			if !flags.Has(models.SeenFlag) {
+				unseen++
+			}
+		}
 	}
 	return &models.DirectoryInfo{
 		Name:           file,
 		Flags:          []string{},
 		ReadOnly:       false,
 		Exists:         exists,
-		Recent:         0,
-		Unseen:         0,
-		AccurateCounts: false,
+		Recent:         recent,
+		Unseen:         unseen,
+		AccurateCounts: true,
 		Caps: &models.Capabilities{
 			Sort:   true,
 			Thread: false,
@@ -98,49 +132,616 @@ func (md *mailboxContainer) Copy(dest, src string, uids []uint32) error {
 
 type container struct {
 	filename string
-	messages []lib.RawMessage
+	messages []*message
+	// mbox file opened for reading the message bodies. For compressed
+	// files, this is a temporary file holding the decompressed data.
+	file *os.File
+	// compression format of the file, nil if not compressed
+	codec *lib.Codec
+	// persist the message index in the cache directory
+	index bool
+	// size and modification time of the file when it was last read or
+	// written, used to detect changes made by other programs
+	size  int64
+	mtime time.Time
+	// files replaced while the mbox file is locked. Closing a descriptor
+	// of the mbox file would release the fcntl lock held on it, they are
+	// closed once the lock is released.
+	locked bool
+	stale  []*os.File
+}
+
+// lock locks the mbox file until unlock is called.
+func (f *container) lock() (*mboxLock, error) {
+	lock, err := lockMbox(f.filename)
+	if err != nil {
+		return nil, err
+	}
+	f.locked = true
+	return lock, nil
+}
+
+// unlock releases the lock of the mbox file and closes the files which were
+// replaced meanwhile.
+func (f *container) unlock(lock *mboxLock) {
+	lock.Unlock()
+	f.locked = false
+	for _, file := range f.stale {
+		file.Close()
+	}
+	f.stale = nil
+}
+
+// closeFile closes file, or defers it until the mbox file is unlocked.
+func (f *container) closeFile(file *os.File) {
+	if f.locked {
+		f.stale = append(f.stale, file)
+	} else {
+		file.Close()
+	}
+}
+
+// load scans the mbox file, or loads its index if it is up to date. The
+// message bodies are read from the file when needed.
+func (f *container) load() error {
+	file, err := os.Open(f.filename)
+	if err != nil {
+		return err
+	}
+	st, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	if f.codec != nil {
+		plain, err := f.codec.DecompressToTemp(file)
+		file.Close()
+		if err != nil {
+			return err
+		}
+		file = plain
+	}
+	var messages []*message
+	if f.indexed() {
+		messages = loadIndex(f.filename, st)
+	}
+	if messages == nil {
+		messages, err = scanMbox(file, false)
+		if err != nil {
+			file.Close()
+			return err
+		}
+		if f.indexed() {
+			if err := saveIndex(f.filename, st, messages); err != nil {
+				log.Warnf("mbox: could not save index: %v", err)
+			}
+		}
+	}
+	for _, m := range messages {
+		m.src = file
+	}
+	f.setFile(file)
+	f.messages = messages
+	f.size, f.mtime = st.Size(), st.ModTime()
+	return nil
+}
+
+// indexed returns true if the message index must be persisted. Compressed
+// files are not indexed since the message bodies are not read from them.
+func (f *container) indexed() bool {
+	return f.index && f.codec == nil
+}
+
+// setFile replaces the file from which the message bodies are read.
+func (f *container) setFile(file *os.File) {
+	if f.file != nil && f.file != file {
+		f.closeFile(f.file)
+	}
+	f.file = file
+}
+
+// updateStat records the current size and modification time of the file. The
+// index is saved if enabled.
+func (f *container) updateStat() error {
+	st, err := os.Stat(f.filename)
+	if errors.Is(err, os.ErrNotExist) {
+		f.size, f.mtime = 0, time.Time{}
+		return nil
+	} else if err != nil {
+		return err
+	}
+	f.size, f.mtime = st.Size(), st.ModTime()
+	if f.indexed() {
+		if err := saveIndex(f.filename, st, f.messages); err != nil {
+			log.Warnf("mbox: could not save index: %v", err)
+		}
+	}
+	return nil
+}
+
+// Modified returns true if the mbox file was changed since it was last read
+// or written.
+func (f *container) Modified() bool {
+	if f.filename == "" {
+		return false
+	}
+	st, err := os.Stat(f.filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return !f.mtime.IsZero()
+	} else if err != nil {
+		return false
+	}
+	return st.Size() != f.size || !st.ModTime().Equal(f.mtime)
+}
+
+// Reload reads the mbox file again. Messages which are still present keep
+// their uid, based on their Message-Id header.
+func (f *container) Reload() error {
+	known := make(map[string][]uint32)
+	for _, m := range f.messages {
+		id := headerValue(m.header, "Message-Id")
+		known[id] = append(known[id], m.uid)
+	}
+	next := f.newUid()
+	if err := f.load(); err != nil {
+		return err
+	}
+	for _, m := range f.messages {
+		id := headerValue(m.header, "Message-Id")
+		if uids := known[id]; id != "" && len(uids) > 0 {
+			m.uid = uids[0]
+			known[id] = uids[1:]
+		} else {
+			m.uid = next
+			next++
+		}
+	}
+	return nil
+}
+
+// Sync rewrites the whole mbox file.
+func (f *container) Sync() error {
+	return f.sync(f.messages)
+}
+
+// sync writes messages to the mbox file. They replace the messages of the
+// container only if the file could be written.
+func (f *container) sync(messages []*message) error {
+	if f.filename == "" {
+		f.messages = messages
+		return nil
+	}
+	if _, err := os.Stat(f.filename); errors.Is(err, os.ErrNotExist) {
+		if err := f.create(); err != nil {
+			return err
+		}
+	}
+	lock, err := f.lock()
+	if err != nil {
+		return err
+	}
+	defer f.unlock(lock)
+	if f.Modified() {
+		return errModified
+	}
+	return f.rewrite(lock.file, messages)
+}
+
+// SetFlags changes the flags of the given messages. The Status and X-Status
+// headers are updated in place when their size is unchanged, otherwise the
+// whole file is rewritten. The flags are only changed in memory once they
+// have been written to disk. It returns the messages which were found.
+func (f *container) SetFlags(
+	uids []uint32, flag models.Flags, enable bool,
+) ([]*message, error) {
+	var found, changed []*message
+	var flags []models.Flags
+	for _, m := range f.messages {
+		for _, uid := range uids {
+			if m.uid != uid {
+				continue
+			}
+			found = append(found, m)
+			newFlags := m.flags | flag
+			if !enable {
+				newFlags = m.flags &^ flag
+			}
+			if newFlags != m.flags {
+				changed = append(changed, m)
+				flags = append(flags, newFlags)
+			}
+			break
+		}
+	}
+	if len(changed) == 0 {
+		return found, nil
+	}
+	if err := f.writeFlags(changed, flags); err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+func (f *container) writeFlags(changed []*message, flags []models.Flags) error {
+	setFlags := func() {
+		for i, m := range changed {
+			m.flags = flags[i]
+		}
+	}
+	if f.filename == "" {
+		setFlags()
+		return nil
+	}
+	if _, err := os.Stat(f.filename); errors.Is(err, os.ErrNotExist) {
+		if err := f.create(); err != nil {
+			return err
+		}
+	}
+	lock, err := f.lock()
+	if err != nil {
+		return err
+	}
+	defer f.unlock(lock)
+	if f.Modified() {
+		return errModified
+	}
+
+	type patch struct {
+		offset int64
+		header []byte
+	}
+	var patches []patch
+	inPlace := true
+	for i, m := range changed {
+		offset, ok := f.statusPatch(lock.file, m, flags[i])
+		if !ok {
+			inPlace = false
+			break
+		}
+		patches = append(patches, patch{offset, setStatus(m.header, flags[i])})
+	}
+	if !inPlace {
+		old := make([]models.Flags, len(changed))
+		for i, m := range changed {
+			old[i] = m.flags
+		}
+		setFlags()
+		if err := f.rewrite(lock.file, f.messages); err != nil {
+			for i, m := range changed {
+				m.flags = old[i]
+			}
+			return err
+		}
+		return nil
+	}
+	for _, p := range patches {
+		if _, err := lock.file.WriteAt(escaped(p.header), p.offset); err != nil {
+			return err
+		}
+	}
+	if err := lock.file.Sync(); err != nil {
+		return err
+	}
+	for i, m := range changed {
+		m.header = patches[i].header
+	}
+	setFlags()
+	return f.updateStat()
+}
+
+// statusPatch returns the location of the header of m in the mbox file if it
+// can be replaced in place by the header with the given flags.
+func (f *container) statusPatch(
+	file *os.File, m *message, flags models.Flags,
+) (int64, bool) {
+	// compressed files and messages which were not read from the file
+	// are always rewritten
+	if f.codec != nil || m.body != nil || m.src != io.ReaderAt(f.file) {
+		return 0, false
+	}
+	old := escaped(m.header)
+	if len(escaped(setStatus(m.header, flags))) != len(old) {
+		return 0, false
+	}
+	offset := m.offset - int64(len(old))
+	if offset < 0 {
+		return 0, false
+	}
+	// make sure that the header is where it is expected
+	buf := make([]byte, len(old))
+	if _, err := file.ReadAt(buf, offset); err != nil || !bytes.Equal(buf, old) {
+		return 0, false
+	}
+	return offset, true
+}
+
+// rewrite writes messages to a temporary file which then replaces the mbox
+// file. When the directory is not writable or the owner of the file cannot be
+// preserved, as for spool files, the content is copied back to the locked
+// file instead.
+func (f *container) rewrite(file *os.File, messages []*message) error {
+	st, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	tmp, err := f.createReplacement(st)
+	if err != nil {
+		return err
+	}
+	inPlace := tmp == nil
+	if inPlace {
+		if tmp, err = lib.CreateTemp(); err != nil {
+			return err
+		}
+	} else {
+		defer os.Remove(tmp.Name())
+	}
+	type location struct {
+		header         []byte
+		offset, length int64
+	}
+	locations := make([]location, len(messages))
+	// the message bodies are read from the decompressed data
+	plain := tmp
+	var out io.Writer = tmp
+	var cw io.WriteCloser
+	if f.codec != nil {
+		plain, err = lib.CreateTemp()
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+		cw, err = f.codec.NewWriter(tmp)
+		if err != nil {
+			plain.Close()
+			tmp.Close()
+			return err
+		}
+		out = io.MultiWriter(cw, plain)
+	}
+	fail := func(err error) error {
+		if plain != tmp {
+			f.closeFile(plain)
+		}
+		f.closeFile(tmp)
+		return err
+	}
+	w := &countingWriter{w: out}
+	for i, m := range messages {
+		start := w.n
+		header, offset, length, err := writeMessage(w, m)
+		if err != nil {
+			return fail(err)
+		}
+		locations[i] = location{header, start + offset, length}
+	}
+	if cw != nil {
+		if err := cw.Close(); err != nil {
+			return fail(err)
+		}
+	}
+	if inPlace {
+		if err := copyBack(file, tmp); err != nil {
+			return fail(err)
+		}
+		if plain == tmp {
+			// read the bodies from the mbox file rather than from a
+			// copy of it
+			reader, err := os.Open(f.filename)
+			if err != nil {
+				return fail(err)
+			}
+			plain = reader
+		}
+		f.closeFile(tmp)
+	} else {
+		if err := tmp.Sync(); err != nil {
+			return fail(err)
+		}
+		if err := os.Rename(tmp.Name(), f.filename); err != nil {
+			return fail(err)
+		}
+		if plain != tmp {
+			// tmp is now the mbox file
+			f.closeFile(tmp)
+		}
+	}
+
+	// the bodies are now read from the new file
+	for i, m := range messages {
+		m.header = locations[i].header
+		m.offset = locations[i].offset
+		m.length = locations[i].length
+		m.body = nil
+		m.src = plain
+	}
+	f.messages = messages
+	f.setFile(plain)
+	return f.updateStat()
+}
+
+// createReplacement creates a temporary file next to the mbox file with the
+// same mode, owner and group. It returns nil if this is not possible.
+func (f *container) createReplacement(st os.FileInfo) (*os.File, error) {
+	dir, name := filepath.Split(f.filename)
+	tmp, err := os.CreateTemp(dir, "."+name+".*.tmp")
+	if errors.Is(err, os.ErrPermission) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := tmp.Chmod(st.Mode().Perm()); err == nil {
+		err = copyOwner(tmp, st)
+		if err == nil {
+			return tmp, nil
+		}
+	}
+	tmp.Close()
+	_ = os.Remove(tmp.Name())
+	return nil, nil
+}
+
+// copyBack replaces the content of file with the content of tmp.
+func copyBack(file, tmp *os.File) error {
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	n, err := io.Copy(file, tmp)
+	if err != nil {
+		return err
+	}
+	if err := file.Truncate(n); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+func (f *container) create() error {
+	file, err := os.OpenFile(f.filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return f.updateStat()
+}
+
+// appendToFile writes a message at the end of the mbox file.
+func (f *container) appendToFile(m *message) error {
+	if f.filename == "" {
+		return nil
+	}
+	if _, err := os.Stat(f.filename); errors.Is(err, os.ErrNotExist) {
+		if err := f.create(); err != nil {
+			return err
+		}
+	}
+	lock, err := f.lock()
+	if err != nil {
+		return err
+	}
+	defer f.unlock(lock)
+	if f.Modified() {
+		return errModified
+	}
+
+	file := lock.file
+	if _, err := file.Seek(0, io.SeekEnd); err != nil {
+		return err
+	}
+	// uncompressed data to which the message is appended
+	plain := file
+	var out io.Writer = file
+	var cw io.WriteCloser
+	if f.codec != nil {
+		if f.file == nil {
+			if f.file, err = lib.CreateTemp(); err != nil {
+				return err
+			}
+		}
+		plain = f.file
+		// concatenated streams are decompressed as a single one
+		cw, err = f.codec.NewWriter(file)
+		if err != nil {
+			return err
+		}
+		out = io.MultiWriter(cw, plain)
+	}
+	st, err := plain.Stat()
+	if err != nil {
+		return err
+	}
+	start, err := plain.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if st.Size() > 0 {
+		// make sure the previous message is followed by an empty line
+		tail := make([]byte, 2)
+		if _, err := plain.ReadAt(tail, st.Size()-2); err == nil &&
+			!bytes.Equal(tail, []byte("\n\n")) {
+			if _, err := out.Write([]byte("\n")); err != nil {
+				return err
+			}
+			start++
+		}
+	}
+	header, offset, length, err := writeMessage(out, m)
+	if err != nil {
+		return err
+	}
+	if cw != nil {
+		if err := cw.Close(); err != nil {
+			return err
+		}
+	}
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	if f.file == nil {
+		reader, err := os.Open(f.filename)
+		if err != nil {
+			return err
+		}
+		f.setFile(reader)
+	}
+	m.header = header
+	m.offset = start + offset
+	m.length = length
+	m.body = nil
+	m.src = f.file
+	return f.updateStat()
 }
 
 func (f *container) Uids() []uint32 {
 	uids := make([]uint32, len(f.messages))
 	for i, m := range f.messages {
-		uids[i] = m.UID()
+		uids[i] = m.uid
 	}
 	return uids
 }
 
 func (f *container) Message(uid uint32) (lib.RawMessage, error) {
 	for _, m := range f.messages {
-		if uid == m.UID() {
+		if uid == m.uid {
 			return m, nil
 		}
 	}
 	return &message{}, fmt.Errorf("uid [%d] not found", uid)
 }
 
-func (f *container) Delete(uids []uint32) (deleted []uint32) {
-	newMessages := make([]lib.RawMessage, 0)
+func (f *container) Delete(uids []uint32) (deleted []uint32, err error) {
+	newMessages := make([]*message, 0)
 	for _, m := range f.messages {
 		del := false
 		for _, uid := range uids {
-			if m.UID() == uid {
+			if m.uid == uid {
 				del = true
 				break
 			}
 		}
 		if del {
-			deleted = append(deleted, m.UID())
+			deleted = append(deleted, m.uid)
 		} else {
 			newMessages = append(newMessages, m)
 		}
 	}
-	f.messages = newMessages
-	return
+	if len(deleted) == 0 {
+		return nil, nil
+	}
+	if err := f.sync(newMessages); err != nil {
+		return nil, err
+	}
+	return deleted, nil
 }
 
 func (f *container) newUid() (next uint32) {
 	for _, m := range f.messages {
-		if uid := m.UID(); uid > next {
+		if uid := m.uid; uid > next {
 			next = uid
 		}
 	}
@@ -153,23 +754,69 @@ func (f *container) Append(r io.Reader, flags models.Flags) error {
 	if err != nil {
 		return err
 	}
-	f.messages = append(f.messages, &message{
-		uid:     f.newUid(),
-		flags:   flags,
-		content: data,
-	})
+	m := &message{
+		uid:   f.newUid(),
+		flags: flags,
+	}
+	m.header, m.body = splitMessage(data)
+	if err := f.appendToFile(m); err != nil {
+		return err
+	}
+	f.messages = append(f.messages, m)
 	return nil
 }
 
+// splitMessage separates the header from the body. The header includes the
+// empty line which ends it.
+func splitMessage(data []byte) ([]byte, []byte) {
+	size := 0
+	for _, line := range headerLines(data) {
+		size += len(line)
+	}
+	if rest := data[size:]; len(rest) > 0 && rest[0] == '\n' {
+		size++
+	} else if bytes.HasPrefix(rest, []byte("\r\n")) {
+		size += 2
+	}
+	return data[:size], data[size:]
+}
+
 // message implements the lib.RawMessage interface
 type message struct {
-	uid     uint32
-	flags   models.Flags
-	content []byte
+	uid   uint32
+	flags models.Flags
+	// "From " line without the prefix, empty for new messages
+	from string
+	// raw header, including the empty line which ends it
+	header []byte
+	// body kept in memory, nil if it must be read from src
+	body []byte
+	// location of the body in the mbox file, "From " lines are escaped
+	src            io.ReaderAt
+	offset, length int64
 }
 
 func (m *message) NewReader() (io.ReadCloser, error) {
-	return io.NopCloser(bytes.NewReader(m.content)), nil
+	var body io.Reader
+	if m.body != nil || m.src == nil {
+		body = bytes.NewReader(m.body)
+	} else {
+		body = newUnescapeReader(io.NewSectionReader(m.src, m.offset, m.length))
+	}
+	return io.NopCloser(io.MultiReader(bytes.NewReader(m.header), body)), nil
+}
+
+// Size returns the size of the message in the mbox file.
+func (m *message) Size() uint32 {
+	if m.body != nil || m.src == nil {
+		return uint32(len(m.header) + len(m.body))
+	}
+	return uint32(int64(len(m.header)) + m.length)
+}
+
+// headerOnly returns a copy of the message without its body.
+func (m *message) headerOnly() lib.RawMessage {
+	return &message{uid: m.uid, flags: m.flags, header: m.header, body: []byte{}}
 }
 
 func (m *message) ModelFlags() (models.Flags, error) {
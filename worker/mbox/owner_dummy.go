@@ -0,0 +1,10 @@
+//go:build !unix
+// +build !unix
+
+package mboxer
+
+import "os"
+
+func copyOwner(file *os.File, st os.FileInfo) error {
+	return nil
+}
@@ -0,0 +1,26 @@
+//go:build unix
+// +build unix
+
+package mboxer
+
+import (
+	"os"
+	"syscall"
+)
+
+// copyOwner gives file the owner and group described by st.
+func copyOwner(file *os.File, st os.FileInfo) error {
+	want, ok := st.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	cur, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if have, ok := cur.Sys().(*syscall.Stat_t); ok &&
+		have.Uid == want.Uid && have.Gid == want.Gid {
+		return nil
+	}
+	return file.Chown(int(want.Uid), int(want.Gid))
+}
@@ -9,6 +9,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 
 	"git.sr.ht/~rjarry/aerc/log"
 	"git.sr.ht/~rjarry/aerc/models"
@@ -61,7 +62,15 @@ func (w *mboxWorker) handleMessage(msg types.WorkerMessage) error {
 		} else {
 			dir = filepath.Join(u.Host, u.Path)
 		}
-		w.data, err = createMailboxContainer(dir)
+		index := false
+		if val, ok := msg.Config.Params["mbox-index"]; ok {
+			index, err = strconv.ParseBool(val)
+			if err != nil {
+				reterr = fmt.Errorf("mbox-index: %w", err)
+				break
+			}
+		}
+		w.data, err = createMailboxContainer(dir, index)
 		if err != nil || w.data == nil {
 			w.data = &mailboxContainer{
 				mailboxes: make(map[string]*container),
@@ -102,6 +111,10 @@ func (w *mboxWorker) handleMessage(msg types.WorkerMessage) error {
 				Message: types.RespondTo(&types.CreateDirectory{}),
 			}, nil)
 		}
+		if err := w.refresh(w.folder); err != nil {
+			reterr = err
+			break
+		}
 		w.worker.PostMessage(&types.DirectoryInfo{
 			Info: w.data.DirectoryInfo(msg.Directory),
 		}, nil)
@@ -109,6 +122,10 @@ func (w *mboxWorker) handleMessage(msg types.WorkerMessage) error {
 		log.Debugf("%s opened", msg.Directory)
 
 	case *types.FetchDirectoryContents:
+		if err := w.refresh(w.folder); err != nil {
+			reterr = err
+			break
+		}
 		uids, err := filterUids(w.folder, w.folder.Uids(), msg.FilterCriteria)
 		if err != nil {
 			reterr = err
@@ -155,6 +172,7 @@ func (w *mboxWorker) handleMessage(msg types.WorkerMessage) error {
 				w.worker.PostMessageInfoError(msg, uid, err)
 				break
 			} else {
+				msgInfo.Size = m.(*message).Size()
 				w.worker.PostMessage(&types.MessageInfo{
 					Message: types.RespondTo(msg),
 					Info:    msgInfo,
@@ -232,7 +250,15 @@ func (w *mboxWorker) handleMessage(msg types.WorkerMessage) error {
 		}, nil)
 
 	case *types.DeleteMessages:
-		deleted := w.folder.Delete(msg.Uids)
+		if err := w.refresh(w.folder); err != nil {
+			reterr = err
+			break
+		}
+		deleted, err := w.folder.Delete(msg.Uids)
+		if err != nil {
+			reterr = w.syncError(w.name, err)
+			break
+		}
 		if len(deleted) > 0 {
 			w.worker.PostMessage(&types.MessagesDeleted{
 				Message: types.RespondTo(msg),
@@ -248,17 +274,16 @@ func (w *mboxWorker) handleMessage(msg types.WorkerMessage) error {
 			&types.Done{Message: types.RespondTo(msg)}, nil)
 
 	case *types.FlagMessages:
-		for _, uid := range msg.Uids {
-			m, err := w.folder.Message(uid)
-			if err != nil {
-				log.Errorf("could not get message: %v", err)
-				continue
-			}
-			if err := m.(*message).SetFlag(msg.Flags, msg.Enable); err != nil {
-				log.Errorf("could not change flag %v to %t on message: %v",
-					msg.Flags, msg.Enable, err)
-				continue
-			}
+		if err := w.refresh(w.folder); err != nil {
+			reterr = err
+			break
+		}
+		messages, err := w.folder.SetFlags(msg.Uids, msg.Flags, msg.Enable)
+		if err != nil {
+			reterr = w.syncError(w.name, err)
+			break
+		}
+		for _, m := range messages {
 			info, err := lib.MessageInfo(m)
 			if err != nil {
 				log.Errorf("could not get message info: %v", err)
@@ -279,9 +304,13 @@ func (w *mboxWorker) handleMessage(msg types.WorkerMessage) error {
 			&types.Done{Message: types.RespondTo(msg)}, nil)
 
 	case *types.CopyMessages:
+		if err := w.refresh(w.folder); err != nil {
+			reterr = err
+			break
+		}
 		err := w.data.Copy(msg.Destination, w.name, msg.Uids)
 		if err != nil {
-			reterr = err
+			reterr = w.syncError(msg.Destination, err)
 			break
 		}
 
@@ -296,12 +325,20 @@ func (w *mboxWorker) handleMessage(msg types.WorkerMessage) error {
 		w.worker.PostMessage(
 			&types.Done{Message: types.RespondTo(msg)}, nil)
 	case *types.MoveMessages:
+		if err := w.refresh(w.folder); err != nil {
+			reterr = err
+			break
+		}
 		err := w.data.Copy(msg.Destination, w.name, msg.Uids)
 		if err != nil {
-			reterr = err
+			reterr = w.syncError(msg.Destination, err)
+			break
+		}
+		deleted, err := w.folder.Delete(msg.Uids)
+		if err != nil {
+			reterr = w.syncError(w.name, err)
 			break
 		}
-		deleted := w.folder.Delete(msg.Uids)
 		if len(deleted) > 0 {
 			w.worker.PostMessage(&types.MessagesDeleted{
 				Message: types.RespondTo(msg),
@@ -339,7 +376,7 @@ func (w *mboxWorker) handleMessage(msg types.WorkerMessage) error {
 		}
 
 		if err := folder.Append(msg.Reader, msg.Flags); err != nil {
-			reterr = err
+			reterr = w.syncError(msg.Destination, err)
 			break
 		} else {
 			w.worker.PostMessage(&types.DirectoryInfo{
@@ -348,6 +385,24 @@ func (w *mboxWorker) handleMessage(msg types.WorkerMessage) error {
 			w.worker.PostMessage(&types.Done{Message: types.RespondTo(msg)}, nil)
 		}
 
+	case *types.CheckMail:
+		for _, name := range w.data.Names() {
+			mb, _ := w.data.Mailbox(name)
+			if !mb.Modified() {
+				continue
+			}
+			if err := mb.Reload(); err != nil {
+				reterr = err
+				break
+			}
+			w.worker.PostMessage(&types.DirectoryInfo{
+				Info: w.data.DirectoryInfo(name),
+			}, nil)
+		}
+		if reterr == nil {
+			w.worker.PostMessage(&types.Done{Message: types.RespondTo(msg)}, nil)
+		}
+
 	case *types.AnsweredMessages:
 		reterr = errUnsupported
 	default:
@@ -357,6 +412,32 @@ func (w *mboxWorker) handleMessage(msg types.WorkerMessage) error {
 	return reterr
 }
 
+// refresh reloads a mailbox if its file was modified by another program.
+func (w *mboxWorker) refresh(folder *container) error {
+	if folder == nil || !folder.Modified() {
+		return nil
+	}
+	log.Debugf("%s was modified, reloading", folder.filename)
+	return folder.Reload()
+}
+
+// syncError reloads the given mailbox after a write failed because of an
+// external modification, so that the next attempt works on fresh data.
+func (w *mboxWorker) syncError(name string, err error) error {
+	if !errors.Is(err, errModified) {
+		return err
+	}
+	if mb, ok := w.data.Mailbox(name); ok {
+		if rerr := mb.Reload(); rerr != nil {
+			return rerr
+		}
+	}
+	w.worker.PostMessage(&types.DirectoryInfo{
+		Info: w.data.DirectoryInfo(name),
+	}, nil)
+	return fmt.Errorf("%s: %w, reloaded", name, err)
+}
+
 func (w *mboxWorker) Run() {
 	for msg := range w.worker.Actions {
 		msg = w.worker.ProcessAction(msg)
@@ -401,11 +482,13 @@ func sortUids(folder *container, uids []uint32,
 			log.Errorf("could not get message %v", err)
 			continue
 		}
-		info, err := lib.MessageInfo(m)
+		// sorting only requires the headers, avoid reading the bodies
+		info, err := lib.MessageHeaders(m.(*message).headerOnly())
 		if err != nil {
 			log.Errorf("could not get message info %v", err)
 			continue
 		}
+		info.Size = m.(*message).Size()
 		infos = append(infos, info)
 	}
 	return lib.Sort(infos, criteria)
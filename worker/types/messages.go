@@ -138,12 +138,18 @@ type FetchMessageHeaders struct {
 type FetchFullMessages struct {
 	Message
 	Uids []uint32
+	// Background is set for bulk fetches which are not waited for by the
+	// user. Backends may run them on a secondary connection.
+	Background bool
 }
 
 type FetchMessageBodyPart struct {
 	Message
 	Uid  uint32
 	Part []int
+	// Background is set when the part is prefetched. Backends may run it
+	// on a secondary connection.
+	Background bool
 }
 
 type FetchMessageFlags struct {
@@ -227,6 +233,14 @@ type MessageInfo struct {
 	NeedsFlags bool
 }
 
+// NewMessage reports a message which arrived in a folder other than the
+// selected one.
+type NewMessage struct {
+	Message
+	Directory string
+	Info      *models.MessageInfo
+}
+
 type FullMessage struct {
 	Message
 	Content *models.FullMessage
@@ -2,6 +2,7 @@ package worker
 
 // the following workers are always enabled
 import (
+	_ "git.sr.ht/~rjarry/aerc/worker/archive"
 	_ "git.sr.ht/~rjarry/aerc/worker/imap"
 	_ "git.sr.ht/~rjarry/aerc/worker/maildir"
 